@@ -0,0 +1,131 @@
+package callbacks
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrAliasCycle is returned when registering an alias that would resolve back to itself.
+	ErrAliasCycle = errors.New("alias cycle")
+)
+
+// route identifies a callback by namespace, capability, and operation.
+type route struct {
+	namespace  string
+	capability string
+	operation  string
+}
+
+// key returns the callback map key of the route.
+func (rt route) key() string {
+	return fmt.Sprintf("%s:%s:%s", rt.namespace, rt.capability, rt.operation)
+}
+
+// alias is a registered alias, mapping the from route to the route it targets.
+type alias struct {
+	from route
+	to   route
+}
+
+// RegisterAlias registers the from namespace, capability, and operation as an alias of the to namespace,
+// capability, and operation. Callback requests for the alias transparently execute the target callback, and
+// Lookup returns the target callback, including its metadata. This supports migrating guests between
+// capability versions without the guest knowing.
+//
+// Aliases may target other aliases, but registering an alias that would resolve back to itself returns
+// ErrAliasCycle. If a callback or alias is already registered for the alias, ErrCallbackExists is returned.
+// The target does not need to be registered beforehand; until it is, requests for the alias are not found.
+//
+// Callbacks registered for the alias's exact namespace, capability, and operation take precedence over the
+// alias.
+func (r *Router) RegisterAlias(fromNs, fromCap, fromOp, toNs, toCap, toOp string) error {
+	from := route{namespace: fromNs, capability: fromCap, operation: fromOp}
+	to := route{namespace: toNs, capability: toCap, operation: toOp}
+	for _, rt := range []route{from, to} {
+		switch {
+		case rt.namespace == "":
+			return ErrInvalidNamespace
+		case rt.capability == "":
+			return ErrInvalidCapability
+		case rt.operation == "":
+			return ErrInvalidOperation
+		}
+	}
+
+	// Lock router
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.lookup(from.key()); ok {
+		return fmt.Errorf("%w: %s", ErrCallbackExists, from.key())
+	}
+
+	current := *r.aliases.Load()
+	if _, ok := current[from.key()]; ok {
+		return fmt.Errorf("%w: %s", ErrCallbackExists, from.key())
+	}
+
+	// Follow the target's aliases, rejecting any that lead back to the alias
+	for next, ok := to, true; ok; {
+		if next == from {
+			return fmt.Errorf("%w: %s", ErrAliasCycle, from.key())
+		}
+		var a alias
+		a, ok = current[next.key()]
+		next = a.to
+	}
+
+	// Add alias to map
+	aliases := make(map[string]alias, len(current)+1)
+	for k, v := range current {
+		aliases[k] = v
+	}
+	aliases[from.key()] = alias{from: from, to: to}
+	r.aliases.Store(&aliases)
+
+	return nil
+}
+
+// resolve returns the namespace, capability, and operation targeted by any aliases registered for the
+// provided namespace, capability, and operation. Registered callbacks take precedence over aliases.
+func (r *Router) resolve(namespace, capability, operation string) (string, string, string) {
+	aliases := *r.aliases.Load()
+	if len(aliases) == 0 {
+		return namespace, capability, operation
+	}
+
+	rt := route{namespace: namespace, capability: capability, operation: operation}
+	for {
+		key := rt.key()
+		if _, ok := r.lookup(key); ok {
+			return rt.namespace, rt.capability, rt.operation
+		}
+		a, ok := aliases[key]
+		if !ok {
+			return rt.namespace, rt.capability, rt.operation
+		}
+		rt = a.to
+	}
+}
+
+// removeAliases removes the aliases registered with the provided keys.
+func (r *Router) removeAliases(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	// Lock router
+	r.Lock()
+	defer r.Unlock()
+
+	current := *r.aliases.Load()
+	aliases := make(map[string]alias, len(current))
+	for k, v := range current {
+		aliases[k] = v
+	}
+	for _, key := range keys {
+		delete(aliases, key)
+	}
+	r.aliases.Store(&aliases)
+}
@@ -0,0 +1,137 @@
+package callbacks
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type AliasTestCase struct {
+	Name string
+	From [3]string
+	To   [3]string
+	Err  error
+}
+
+func TestRouterRegisterAlias(t *testing.T) {
+	router, err := New(RouterConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	for _, op := range []string{"get", "set"} {
+		err = router.RegisterCallback(CallbackConfig{
+			Namespace:  "default",
+			Capability: "kv.v2",
+			Operation:  op,
+			Func: func(input []byte) ([]byte, error) {
+				return append([]byte("v2 "), input...), nil
+			},
+			Metadata: map[string]string{"version": "2"},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error registering callback: %s", err)
+		}
+	}
+
+	tt := []AliasTestCase{
+		{
+			Name: "Alias",
+			From: [3]string{"default", "kv", "get"},
+			To:   [3]string{"default", "kv.v2", "get"},
+		},
+		{
+			Name: "Alias of alias",
+			From: [3]string{"default", "kv.v0", "get"},
+			To:   [3]string{"default", "kv", "get"},
+		},
+		{
+			Name: "Unregistered target",
+			From: [3]string{"default", "kv", "delete"},
+			To:   [3]string{"default", "kv.v2", "delete"},
+		},
+		{
+			Name: "Existing alias",
+			From: [3]string{"default", "kv", "get"},
+			To:   [3]string{"default", "kv.v2", "set"},
+			Err:  ErrCallbackExists,
+		},
+		{
+			Name: "Existing callback",
+			From: [3]string{"default", "kv.v2", "set"},
+			To:   [3]string{"default", "kv.v2", "get"},
+			Err:  ErrCallbackExists,
+		},
+		{
+			Name: "Self cycle",
+			From: [3]string{"default", "kv", "list"},
+			To:   [3]string{"default", "kv", "list"},
+			Err:  ErrAliasCycle,
+		},
+		{
+			Name: "Indirect cycle",
+			From: [3]string{"default", "kv.v2", "delete"},
+			To:   [3]string{"default", "kv", "delete"},
+			Err:  ErrAliasCycle,
+		},
+		{
+			Name: "Missing operation",
+			From: [3]string{"default", "kv", ""},
+			To:   [3]string{"default", "kv.v2", "get"},
+			Err:  ErrInvalidOperation,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := router.RegisterAlias(tc.From[0], tc.From[1], tc.From[2], tc.To[0], tc.To[1], tc.To[2])
+			if !errors.Is(err, tc.Err) {
+				t.Errorf("Unexpected error registering alias: %s, expected: %s", err, tc.Err)
+			}
+		})
+	}
+
+	t.Run("Callback via alias", func(t *testing.T) {
+		for _, capability := range []string{"kv", "kv.v0"} {
+			rsp, info, err := router.CallbackWithMatch(context.Background(), "default", capability, "get", []byte("hi"))
+			if err != nil {
+				t.Fatalf("Unexpected error calling callback: %s", err)
+			}
+			if string(rsp) != "v2 hi" {
+				t.Errorf("Unexpected callback response: %s", rsp)
+			}
+			if info.Capability != "kv.v2" || info.Metadata["version"] != "2" {
+				t.Errorf("Expected target callback info, got: %+v", info)
+			}
+		}
+	})
+
+	t.Run("Lookup via alias", func(t *testing.T) {
+		cb, err := router.Lookup("default", "kv", "get")
+		if err != nil || cb.Capability != "kv.v2" {
+			t.Errorf("Unexpected lookup result: %+v, %v", cb, err)
+		}
+	})
+
+	t.Run("Unregistered target", func(t *testing.T) {
+		_, err := router.Callback(context.Background(), "default", "kv", "delete", []byte("hi"))
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected not found error, got: %s", err)
+		}
+	})
+	t.Run("Close", func(t *testing.T) {
+		err := router.UseNamespace("default", nil, func(CallbackResult) {})
+		if err != nil {
+			t.Fatalf("Unexpected error registering namespace middleware: %s", err)
+		}
+
+		router.Close()
+		if len(*router.aliases.Load()) != 0 {
+			t.Errorf("Expected aliases to be cleared on close")
+		}
+		if len(*router.middleware.Load()) != 0 {
+			t.Errorf("Expected namespace middleware to be cleared on close")
+		}
+	})
+}
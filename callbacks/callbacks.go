@@ -1,7 +1,10 @@
 package callbacks
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,6 +20,15 @@ var (
 
 	// ErrInvalidFunc is returned when the callback function is invalid.
 	ErrInvalidFunc = errors.New("invalid func: cannot be nil")
+
+	// ErrCallbackPanic is returned when the callback function panics.
+	//
+	// The router recovers panics within callback functions. Unless the callback defines an OnPanic
+	// function, the panic is returned as this error, and flows to any PostFunc and OnError functions.
+	ErrCallbackPanic = errors.New("callback panicked")
+
+	// ErrCallbackTimeout is returned when the callback function exceeds the callback's Timeout.
+	ErrCallbackTimeout = errors.New("callback timed out")
 )
 
 // CallbackConfig is the user-provided configuration for a callback.
@@ -35,6 +47,77 @@ type CallbackConfig struct {
 
 	// Func is the callback function that will be called when a callback is triggered.
 	Func func(input []byte) ([]byte, error)
+
+	// CtxFunc is a context-aware callback function that will be called when a callback is triggered.
+	// CtxFunc receives the context provided to the router, or the context returned by any
+	// PreFuncWithContext function defined. If both Func and CtxFunc are provided, CtxFunc is used.
+	CtxFunc func(ctx context.Context, input []byte) ([]byte, error)
+
+	// Priority is the admission priority of the callback. When the router's MaxConcurrency is reached,
+	// callbacks with a Priority greater than zero are admitted using the router's ReservedConcurrency
+	// while other callbacks are rejected with ErrOverloaded. See RouterConfig for details.
+	Priority int
+
+	// SkipPreFunc, when enabled, exempts the callback from the router's PreFunc and PreFuncWithContext
+	// functions. This is useful for routes such as health checks that do not need global middleware.
+	SkipPreFunc bool
+
+	// SkipPostFunc, when enabled, exempts the callback from the router's PostFunc function.
+	SkipPostFunc bool
+
+	// OnPanic is an optional function called with the recovered value when the callback function
+	// panics. The returned error becomes the callback's error, allowing specific panics to be mapped to
+	// domain errors. If OnPanic is not provided, or returns nil, ErrCallbackPanic is returned.
+	OnPanic func(recovered any) error
+
+	// ServeStaleOnError, when enabled, serves the most recent successful response for the same input
+	// when the callback function returns an error, rather than returning the error. This improves
+	// availability for idempotent, read-style callbacks when their backing service is temporarily failing.
+	//
+	// The router retains up to DefaultStaleCacheSize responses per callback. If no prior response exists
+	// for the input, the error is returned. Stale responses are reported to PostFunc via
+	// CallbackResult.Stale, along with the underlying error.
+	ServeStaleOnError bool
+
+	// IdempotencyKeyFunc is an optional function returning the idempotency key for a callback input. When
+	// defined, executions of the callback function are deduplicated by key: concurrent requests with the
+	// same key share a single execution, and the result of a successful execution is returned for requests
+	// with the same key until IdempotencyTTL elapses. This protects non-idempotent operations from guests
+	// that retry host calls. An empty key disables deduplication for the request.
+	//
+	// Failed executions are not retained, allowing requests to be retried. The router retains up to
+	// DefaultIdempotencyCacheSize completed results per callback, evicting expired results first. Router
+	// PreFunc and PostFunc functions are called for every request, including duplicates, which are reported
+	// to PostFunc via CallbackResult.Duplicate.
+	IdempotencyKeyFunc func(input []byte) string
+
+	// IdempotencyTTL is the duration successful results are retained for IdempotencyKeyFunc. If
+	// IdempotencyTTL is not provided, DefaultIdempotencyTTL will be used.
+	IdempotencyTTL time.Duration
+
+	// Timeout is the maximum duration the callback function may run. When defined, the callback function is
+	// provided a context with the corresponding deadline, and the router returns ErrCallbackTimeout once the
+	// timeout is exceeded, even if the callback function has not returned. This prevents callbacks that
+	// block, such as on I/O, from blocking the guest invocation indefinitely.
+	//
+	// Callback functions that ignore the context, including any Func, continue to run in the background
+	// after the timeout; their result is discarded. The timeout error is provided to PostFunc via
+	// CallbackResult.Err. If Timeout is not provided, the callback function is not limited.
+	Timeout time.Duration
+
+	// OnRegisterCtx is an optional function called when the callback is registered, with a context tied to
+	// the lifetime of the callback. The context is canceled when the callback is unregistered or the router
+	// is closed, allowing capabilities to start background work, such as subscriptions, without leaking
+	// goroutines once the callback is removed.
+	//
+	// OnRegisterCtx is called synchronously by RegisterCallback, without holding the router lock, and should
+	// start any long-lived work in a goroutine and return. If OnRegisterCtx returns an error, the context is
+	// canceled and the callback is not registered.
+	OnRegisterCtx func(ctx context.Context) error
+
+	// Metadata is optional user-defined information describing the callback, such as tags used for
+	// auditing or ownership. Metadata is not used by the router to route callback requests.
+	Metadata map[string]string
 }
 
 // Validate validates the callback configuration. It returns an error if the configuration
@@ -56,7 +139,7 @@ func (c CallbackConfig) Validate() error {
 	}
 
 	// Verify Func
-	if c.Func == nil {
+	if c.Func == nil && c.CtxFunc == nil {
 		return ErrInvalidFunc
 	}
 
@@ -76,6 +159,176 @@ type Callback struct {
 
 	// Func is the callback function that will be called when a callback is triggered.
 	Func func(input []byte) ([]byte, error)
+
+	// CtxFunc is the context-aware callback function that will be called when a callback is triggered.
+	CtxFunc func(ctx context.Context, input []byte) ([]byte, error)
+
+	// Priority is the admission priority of the callback.
+	Priority int
+
+	// SkipPreFunc exempts the callback from the router's PreFunc and PreFuncWithContext functions.
+	SkipPreFunc bool
+
+	// SkipPostFunc exempts the callback from the router's PostFunc function.
+	SkipPostFunc bool
+
+	// OnPanic is called with the recovered value when the callback function panics.
+	OnPanic func(recovered any) error
+
+	// ServeStaleOnError serves the most recent successful response when the callback function errors.
+	ServeStaleOnError bool
+
+	// IdempotencyKeyFunc returns the idempotency key used to deduplicate executions of the callback function.
+	IdempotencyKeyFunc func(input []byte) string
+
+	// IdempotencyTTL is the duration successful results are retained for IdempotencyKeyFunc.
+	IdempotencyTTL time.Duration
+
+	// Timeout is the maximum duration the callback function may run.
+	Timeout time.Duration
+
+	// OnRegisterCtx is called with a context tied to the lifetime of the callback when it is registered.
+	OnRegisterCtx func(ctx context.Context) error
+
+	// Metadata is the user-defined information provided when the callback was registered.
+	Metadata map[string]string
+
+	// bytes tracks the cumulative bytes processed by a registered callback.
+	bytes *callbackBytes
+
+	// latency records the latency of the callback function.
+	latency *latencyHistogram
+
+	// stale retains successful responses when ServeStaleOnError is enabled.
+	stale *staleCache
+
+	// idempotency deduplicates executions when IdempotencyKeyFunc is defined.
+	idempotency *idempotencyCache
+
+	// cancel cancels the context provided to OnRegisterCtx.
+	cancel context.CancelFunc
+}
+
+// callbackBytes tracks the cumulative input and output bytes processed by a callback.
+type callbackBytes struct {
+	// in is the total number of input bytes provided to the callback function.
+	in atomic.Uint64
+
+	// out is the total number of output bytes returned by the callback function.
+	out atomic.Uint64
+}
+
+// copy returns a copy of the callback, including a copy of its metadata, so that callers cannot
+// modify the registered callback.
+func (cb *Callback) copy() Callback {
+	return Callback{
+		Namespace:          cb.Namespace,
+		Capability:         cb.Capability,
+		Operation:          cb.Operation,
+		Func:               cb.Func,
+		CtxFunc:            cb.CtxFunc,
+		Priority:           cb.Priority,
+		SkipPreFunc:        cb.SkipPreFunc,
+		SkipPostFunc:       cb.SkipPostFunc,
+		OnPanic:            cb.OnPanic,
+		ServeStaleOnError:  cb.ServeStaleOnError,
+		IdempotencyKeyFunc: cb.IdempotencyKeyFunc,
+		IdempotencyTTL:     cb.IdempotencyTTL,
+		Timeout:            cb.Timeout,
+		OnRegisterCtx:      cb.OnRegisterCtx,
+		Metadata:           copyMetadata(cb.Metadata),
+	}
+}
+
+// moved returns a copy of the callback that shares its state, such as byte counters, latency histogram,
+// caches, and lifetime, for replacing the registered callback without resetting it.
+func (cb *Callback) moved() *Callback {
+	m := cb.copy()
+	m.bytes = cb.bytes
+	m.latency = cb.latency
+	m.stale = cb.stale
+	m.idempotency = cb.idempotency
+	m.cancel = cb.cancel
+	return &m
+}
+
+// run executes the callback function, enforcing the callback Timeout if defined.
+func (cb *Callback) run(ctx context.Context, input []byte) ([]byte, error) {
+	if cb.Timeout <= 0 {
+		return cb.call(ctx, input)
+	}
+
+	ctx, cancel := context.WithTimeoutCause(ctx, cb.Timeout, ErrCallbackTimeout)
+	defer cancel()
+
+	type result struct {
+		rsp []byte
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		rsp, err := cb.call(ctx, input)
+		done <- result{rsp: rsp, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		// Callbacks returning the context error upon timeout report the timeout
+		if r.err != nil && errors.Is(context.Cause(ctx), ErrCallbackTimeout) {
+			return nil, fmt.Errorf("%w: exceeded %s", ErrCallbackTimeout, cb.Timeout)
+		}
+		return r.rsp, r.err
+	case <-ctx.Done():
+		if errors.Is(context.Cause(ctx), ErrCallbackTimeout) {
+			return nil, fmt.Errorf("%w: exceeded %s", ErrCallbackTimeout, cb.Timeout)
+		}
+		return nil, ErrCanceled
+	}
+}
+
+// call executes the callback function, preferring CtxFunc if defined. Panics within the callback function
+// are recovered and returned as an error.
+func (cb *Callback) call(ctx context.Context, input []byte) (rsp []byte, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			rsp, err = nil, cb.panicError(recovered)
+		}
+	}()
+
+	if cb.CtxFunc != nil {
+		return cb.CtxFunc(ctx, input)
+	}
+	return cb.Func(input)
+}
+
+// stop cancels the context provided to OnRegisterCtx, if any.
+func (cb *Callback) stop() {
+	if cb.cancel != nil {
+		cb.cancel()
+	}
+}
+
+// copyMetadata returns a copy of the provided metadata.
+func copyMetadata(md map[string]string) map[string]string {
+	if md == nil {
+		return nil
+	}
+
+	cp := make(map[string]string, len(md))
+	for k, v := range md {
+		cp[k] = v
+	}
+	return cp
+}
+
+// panicError returns the error for a recovered callback panic, using OnPanic if defined.
+func (cb *Callback) panicError(recovered any) error {
+	if cb.OnPanic != nil {
+		if err := cb.OnPanic(recovered); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: %v", ErrCallbackPanic, recovered)
 }
 
 // CallbackRequest represents a callback request made to the callback router.
@@ -92,6 +345,13 @@ type CallbackRequest struct {
 	// Input is the user-provided input for the callback request.
 	Input []byte
 
+	// RequestID is the request ID carried by the callback context, if any. See RequestIDFromContext.
+	RequestID string
+
+	// Context is the context provided to Callback, carrying its deadline, cancellation, and values such
+	// as trace spans. This allows PreFunc to perform request-scoped work, such as authorization checks.
+	Context context.Context
+
 	// StartTime is the time the callback router receives the callback request.
 	// The callback router sets this time before calling any pre-function hooks.
 	// This time may differ from when the WASM module made the callback request.
@@ -119,6 +379,24 @@ type CallbackResult struct {
 	// Err is the error returned by the callback function provided to the WASM module.
 	Err error
 
+	// Stale is true if Err was not returned to the WASM module because a stale response was served
+	// in its place. See CallbackConfig.ServeStaleOnError.
+	Stale bool
+
+	// Duplicate is true if the callback function was not executed because the result of an execution with
+	// the same idempotency key was returned. See CallbackConfig.IdempotencyKeyFunc.
+	Duplicate bool
+
+	// RequestID is the request ID carried by the callback context, if any. See RequestIDFromContext.
+	RequestID string
+
+	// Context is detached from the context the callback function was executed with, including any context
+	// returned by PreFuncWithContext. It carries the same values, such as request IDs and trace spans, but
+	// not the deadline or cancellation, so it is not done just because the request finished. This allows
+	// PostFunc, which may run asynchronously via the PostFunc queue, to still perform work such as emitting
+	// metrics. PostFunc should apply its own timeouts to any such work.
+	Context context.Context
+
 	// StartTime is the time the callback router receives the callback request.
 	// The callback router sets this time before calling any pre-function hooks.
 	// This time may differ from when the WASM module made the callback request.
@@ -1,6 +1,7 @@
 package callbacks
 
 import (
+	"context"
 	"errors"
 	"testing"
 )
@@ -25,6 +26,18 @@ func TestCallbackConfigValidation(t *testing.T) {
 			},
 			Err: nil,
 		},
+		{
+			Name: "Valid CtxFunc",
+			CallbackCfg: CallbackConfig{
+				Namespace:  "default",
+				Capability: "counter",
+				Operation:  "increment",
+				CtxFunc: func(_ context.Context, input []byte) ([]byte, error) {
+					return input, nil
+				},
+			},
+			Err: nil,
+		},
 		{
 			Name: "Invalid Namespace",
 			CallbackCfg: CallbackConfig{
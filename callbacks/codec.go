@@ -0,0 +1,73 @@
+package callbacks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrDecodeFailed is returned by callbacks registered with RegisterTyped when the callback input cannot
+	// be decoded with the router's Codec.
+	ErrDecodeFailed = errors.New("unable to decode callback input")
+
+	// ErrEncodeFailed is returned by callbacks registered with RegisterTyped when the callback output cannot
+	// be encoded with the router's Codec.
+	ErrEncodeFailed = errors.New("unable to encode callback output")
+)
+
+// Codec is the serialization format used by callbacks registered with RegisterTyped to decode callback
+// inputs and encode callback outputs. Implementations must be safe for concurrent use.
+type Codec interface {
+	// Marshal encodes v.
+	Marshal(v any) ([]byte, error)
+
+	// Unmarshal decodes data into v.
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec is a Codec using the encoding/json package. It is the default Codec.
+type JSONCodec struct{}
+
+// Marshal encodes v as JSON.
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes JSON data into v.
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// RegisterTyped adds a typed callback to the router. The callback input is decoded into In, and the Out
+// returned by fn is encoded as the callback output, using the router's Codec. This provides the same
+// ergonomics for every serialization format without per-callback wiring.
+//
+// Any Func or CtxFunc provided within the CallbackConfig is replaced by fn. If the input cannot be decoded,
+// the callback returns ErrDecodeFailed without calling fn; if the output cannot be encoded, the callback
+// returns ErrEncodeFailed. Errors returned by fn are returned as is, along with the encoded output.
+func RegisterTyped[In, Out any](r *Router, cfg CallbackConfig, fn func(ctx context.Context, in In) (Out, error)) error {
+	if fn == nil {
+		return ErrInvalidFunc
+	}
+
+	codec := r.codec
+	cfg.Func = nil
+	cfg.CtxFunc = func(ctx context.Context, input []byte) ([]byte, error) {
+		var in In
+		if err := codec.Unmarshal(input, &in); err != nil {
+			return nil, fmt.Errorf("%w - %w", ErrDecodeFailed, err)
+		}
+
+		out, err := fn(ctx, in)
+
+		rsp, mErr := codec.Marshal(out)
+		if mErr != nil {
+			return nil, fmt.Errorf("%w - %w", ErrEncodeFailed, mErr)
+		}
+		return rsp, err
+	}
+
+	return r.RegisterCallback(cfg)
+}
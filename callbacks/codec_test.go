@@ -0,0 +1,152 @@
+package callbacks
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+type reply struct {
+	Message string `json:"message"`
+}
+
+// upperCodec is a test Codec which encodes and decodes greeting and reply values as upper case strings.
+type upperCodec struct{}
+
+func (upperCodec) Marshal(v any) ([]byte, error) {
+	r, ok := v.(reply)
+	if !ok {
+		return nil, ErrTestError
+	}
+	return []byte(strings.ToUpper(r.Message)), nil
+}
+
+func (upperCodec) Unmarshal(data []byte, v any) error {
+	g, ok := v.(*greeting)
+	if !ok {
+		return ErrTestError
+	}
+	g.Name = strings.ToUpper(string(data))
+	return nil
+}
+
+type RegisterTypedTestCase struct {
+	Name     string
+	Codec    Codec
+	Input    string
+	Output   string
+	FuncErr  error
+	Err      error
+	Executed bool
+}
+
+func TestRegisterTyped(t *testing.T) {
+	tt := []RegisterTypedTestCase{
+		{
+			Name:     "Default JSON codec",
+			Input:    `{"name":"wapc"}`,
+			Output:   `{"message":"Hello wapc"}`,
+			Executed: true,
+		},
+		{
+			Name:     "Custom codec",
+			Codec:    upperCodec{},
+			Input:    "wapc",
+			Output:   "HELLO WAPC",
+			Executed: true,
+		},
+		{
+			Name:  "Decode failure",
+			Input: `not json`,
+			Err:   ErrDecodeFailed,
+		},
+		{
+			Name:     "Function error",
+			Input:    `{"name":"wapc"}`,
+			Output:   `{"message":"Hello wapc"}`,
+			FuncErr:  ErrTestError,
+			Err:      ErrTestError,
+			Executed: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			router, err := New(RouterConfig{Codec: tc.Codec})
+			if err != nil {
+				t.Fatalf("Unexpected error creating router: %s", err)
+			}
+			defer router.Close()
+
+			var executed bool
+			err = RegisterTyped(router, CallbackConfig{
+				Namespace:  "default",
+				Capability: "greeting",
+				Operation:  "hello",
+			}, func(_ context.Context, in greeting) (reply, error) {
+				executed = true
+				return reply{Message: "Hello " + in.Name}, tc.FuncErr
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error registering callback: %s", err)
+			}
+
+			rsp, err := router.Callback(context.Background(), "default", "greeting", "hello", []byte(tc.Input))
+			if !errors.Is(err, tc.Err) {
+				t.Fatalf("Unexpected error calling callback: %s, expected: %s", err, tc.Err)
+			}
+			if string(rsp) != tc.Output {
+				t.Errorf("Unexpected output: %s, expected: %s", rsp, tc.Output)
+			}
+			if executed != tc.Executed {
+				t.Errorf("Unexpected execution: %t, expected: %t", executed, tc.Executed)
+			}
+		})
+	}
+
+	t.Run("Encode failure", func(t *testing.T) {
+		router, err := New(RouterConfig{Codec: upperCodec{}})
+		if err != nil {
+			t.Fatalf("Unexpected error creating router: %s", err)
+		}
+		defer router.Close()
+
+		err = RegisterTyped(router, CallbackConfig{
+			Namespace:  "default",
+			Capability: "greeting",
+			Operation:  "hello",
+		}, func(_ context.Context, in greeting) (string, error) {
+			return in.Name, nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error registering callback: %s", err)
+		}
+
+		_, err = router.Callback(context.Background(), "default", "greeting", "hello", []byte("wapc"))
+		if !errors.Is(err, ErrEncodeFailed) {
+			t.Errorf("Expected encode error, got: %s", err)
+		}
+	})
+
+	t.Run("Nil function", func(t *testing.T) {
+		router, err := New(RouterConfig{})
+		if err != nil {
+			t.Fatalf("Unexpected error creating router: %s", err)
+		}
+		defer router.Close()
+
+		err = RegisterTyped[greeting, reply](router, CallbackConfig{
+			Namespace:  "default",
+			Capability: "greeting",
+			Operation:  "hello",
+		}, nil)
+		if !errors.Is(err, ErrInvalidFunc) {
+			t.Errorf("Expected invalid func error, got: %s", err)
+		}
+	})
+}
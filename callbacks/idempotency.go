@@ -0,0 +1,132 @@
+package callbacks
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultIdempotencyTTL is the default duration completed results are retained for IdempotencyKeyFunc.
+	DefaultIdempotencyTTL = time.Minute
+
+	// DefaultIdempotencyCacheSize is the maximum number of completed results retained per callback for
+	// IdempotencyKeyFunc.
+	DefaultIdempotencyCacheSize = 1000
+)
+
+// idempotencyCache deduplicates callback executions by idempotency key. Concurrent executions with the same
+// key share a single execution, and successful results are retained until they expire.
+type idempotencyCache struct {
+	sync.Mutex
+
+	// calls maps idempotency keys to their in-flight or completed execution.
+	calls map[string]*idempotentCall
+
+	// ttl is the duration completed results are retained.
+	ttl time.Duration
+
+	// size is the maximum number of completed results retained.
+	size int
+}
+
+// idempotentCall is a single in-flight or completed callback execution.
+type idempotentCall struct {
+	// done is closed once the execution completes.
+	done chan struct{}
+
+	// rsp is the response of the execution.
+	rsp []byte
+
+	// err is the error of the execution.
+	err error
+
+	// expires is the time the completed result expires, or zero while the execution is in flight.
+	expires time.Time
+}
+
+// newIdempotencyCache creates a new idempotency cache.
+func newIdempotencyCache(ttl time.Duration, size int) *idempotencyCache {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &idempotencyCache{
+		calls: make(map[string]*idempotentCall),
+		ttl:   ttl,
+		size:  size,
+	}
+}
+
+// do executes fn for the idempotency key, unless an execution for the key is in flight or has completed
+// successfully within the TTL, in which case its result is returned. The returned bool is true if the
+// result is from a duplicate execution.
+//
+// Waiting for an in-flight execution returns ErrCanceled if the context is canceled.
+func (c *idempotencyCache) do(ctx context.Context, key string, fn func() ([]byte, error)) ([]byte, bool, error) {
+	c.Lock()
+	if call, ok := c.calls[key]; ok {
+		if call.expires.IsZero() || time.Now().Before(call.expires) {
+			c.Unlock()
+			select {
+			case <-call.done:
+				return bytes.Clone(call.rsp), true, call.err
+			case <-ctx.Done():
+				return nil, true, ErrCanceled
+			}
+		}
+		delete(c.calls, key)
+	}
+	c.evict()
+	call := &idempotentCall{done: make(chan struct{})}
+	c.calls[key] = call
+	c.Unlock()
+
+	call.rsp, call.err = fn()
+
+	// Retain successful results, allowing failed executions to be retried
+	c.Lock()
+	if call.err != nil {
+		delete(c.calls, key)
+	} else {
+		call.expires = time.Now().Add(c.ttl)
+	}
+	c.Unlock()
+	close(call.done)
+
+	return call.rsp, false, call.err
+}
+
+// evict removes expired results. If the cache remains full, an arbitrary completed result is evicted.
+// In-flight executions are never evicted. The caller must hold the lock.
+func (c *idempotencyCache) evict() {
+	if len(c.calls) < c.size {
+		return
+	}
+
+	now := time.Now()
+	for k, call := range c.calls {
+		if !call.expires.IsZero() && !now.Before(call.expires) {
+			delete(c.calls, k)
+		}
+	}
+
+	if len(c.calls) < c.size {
+		return
+	}
+	for k, call := range c.calls {
+		if !call.expires.IsZero() {
+			delete(c.calls, k)
+			return
+		}
+	}
+}
+
+// idempotencyKey returns the idempotency key for the callback input, or an empty key if executions of the
+// callback are not deduplicated.
+func (cb *Callback) idempotencyKey(input []byte) string {
+	if cb.idempotency == nil {
+		return ""
+	}
+	return cb.IdempotencyKeyFunc(input)
+}
@@ -0,0 +1,194 @@
+package callbacks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type IdempotencyTestCase struct {
+	Name       string
+	TTL        time.Duration
+	Sleep      time.Duration
+	Inputs     []string
+	Err        error
+	Executions int
+}
+
+func TestIdempotency(t *testing.T) {
+	tt := []IdempotencyTestCase{
+		{
+			Name:       "Duplicate key",
+			Inputs:     []string{"key-1", "key-1", "key-1"},
+			Executions: 1,
+		},
+		{
+			Name:       "Unique keys",
+			Inputs:     []string{"key-1", "key-2", "key-3"},
+			Executions: 3,
+		},
+		{
+			Name:       "Empty key",
+			Inputs:     []string{"", "", ""},
+			Executions: 3,
+		},
+		{
+			Name:       "Expired",
+			TTL:        10 * time.Millisecond,
+			Sleep:      20 * time.Millisecond,
+			Inputs:     []string{"key-1", "key-1"},
+			Executions: 2,
+		},
+		{
+			Name:       "Failed executions retried",
+			Inputs:     []string{"key-1", "key-1"},
+			Err:        ErrTestError,
+			Executions: 2,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			counter := &Counter{}
+			duplicates := &Counter{}
+			router, err := New(RouterConfig{
+				PostFunc: func(r CallbackResult) {
+					if r.Duplicate {
+						duplicates.Increment()
+					}
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error creating router: %s", err)
+			}
+
+			err = router.RegisterCallback(CallbackConfig{
+				Namespace:  "default",
+				Capability: "counter",
+				Operation:  "increment",
+				Func: func(input []byte) ([]byte, error) {
+					counter.Increment()
+					return []byte("executed"), tc.Err
+				},
+				IdempotencyKeyFunc: func(input []byte) string { return string(input) },
+				IdempotencyTTL:     tc.TTL,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error registering callback: %s", err)
+			}
+
+			for _, input := range tc.Inputs {
+				rsp, err := router.Callback(context.Background(), "default", "counter", "increment", []byte(input))
+				if !errors.Is(err, tc.Err) {
+					t.Fatalf("Unexpected error calling callback: %s, expected: %s", err, tc.Err)
+				}
+				if string(rsp) != "executed" {
+					t.Errorf("Unexpected response: %s", rsp)
+				}
+				time.Sleep(tc.Sleep)
+			}
+			router.Close()
+
+			if counter.Value() != tc.Executions {
+				t.Errorf("Unexpected executions: %d, expected: %d", counter.Value(), tc.Executions)
+			}
+			if duplicates.Value() != len(tc.Inputs)-tc.Executions {
+				t.Errorf("Unexpected duplicates: %d, expected: %d", duplicates.Value(), len(tc.Inputs)-tc.Executions)
+			}
+		})
+	}
+}
+
+func TestIdempotencyConcurrent(t *testing.T) {
+	counter := &Counter{}
+	release := make(chan struct{})
+	router, err := New(RouterConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "counter",
+		Operation:  "increment",
+		Func: func(input []byte) ([]byte, error) {
+			counter.Increment()
+			<-release
+			return input, nil
+		},
+		IdempotencyKeyFunc: func(input []byte) string { return string(input) },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rsp, err := router.Callback(context.Background(), "default", "counter", "increment", []byte("key"))
+			if err != nil {
+				t.Errorf("Unexpected error calling callback: %s", err)
+			}
+			if string(rsp) != "key" {
+				t.Errorf("Unexpected response: %s", rsp)
+			}
+		}()
+	}
+
+	// Allow the duplicates to wait on the in-flight execution
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if counter.Value() != 1 {
+		t.Errorf("Unexpected executions: %d, expected: 1", counter.Value())
+	}
+
+	t.Run("Canceled while waiting", func(t *testing.T) {
+		block := make(chan struct{})
+		defer close(block)
+		err := router.RegisterCallback(CallbackConfig{
+			Namespace:          "default",
+			Capability:         "counter",
+			Operation:          "block",
+			Func:               func(input []byte) ([]byte, error) { <-block; return input, nil },
+			IdempotencyKeyFunc: func(input []byte) string { return string(input) },
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error registering callback: %s", err)
+		}
+
+		go func() {
+			_, _ = router.Callback(context.Background(), "default", "counter", "block", []byte("key"))
+		}()
+		time.Sleep(50 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = router.Callback(ctx, "default", "counter", "block", []byte("key"))
+		if !errors.Is(err, ErrCanceled) {
+			t.Errorf("Expected canceled error, got: %s", err)
+		}
+	})
+}
+
+func TestIdempotencyCacheEviction(t *testing.T) {
+	c := newIdempotencyCache(time.Minute, 2)
+	for _, key := range []string{"a", "b", "c"} {
+		_, _, _ = c.do(context.Background(), key, func() ([]byte, error) { return []byte(key), nil })
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if len(c.calls) != 2 {
+		t.Errorf("Unexpected cached results: %d, expected: 2", len(c.calls))
+	}
+	if _, ok := c.calls["c"]; !ok {
+		t.Errorf("Expected most recent result to be cached")
+	}
+}
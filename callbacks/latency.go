@@ -0,0 +1,103 @@
+package callbacks
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the buckets used to record callback latency. Latencies greater
+// than the largest bound are recorded in an overflow bucket.
+var latencyBuckets = [...]time.Duration{
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	1 * time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// latencyHistogram is a fixed-bucket histogram of callback function latency.
+type latencyHistogram struct {
+	// counts is the number of latencies recorded within each bucket, followed by the overflow bucket.
+	counts [len(latencyBuckets) + 1]atomic.Uint64
+
+	// max is the largest latency recorded, in nanoseconds.
+	max atomic.Int64
+}
+
+// observe records a latency within the histogram.
+func (h *latencyHistogram) observe(d time.Duration) {
+	i := 0
+	for i < len(latencyBuckets) && d > latencyBuckets[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+
+	for {
+		n := h.max.Load()
+		if int64(d) <= n || h.max.CompareAndSwap(n, int64(d)) {
+			return
+		}
+	}
+}
+
+// percentile returns the upper bound of the bucket containing the q quantile, where q is between 0 and 1.
+// Latencies within the overflow bucket are reported as the largest latency recorded. If no latencies have
+// been recorded, zero is returned.
+func (h *latencyHistogram) percentile(q float64) time.Duration {
+	var counts [len(latencyBuckets) + 1]uint64
+	var total uint64
+	for i := range h.counts {
+		counts[i] = h.counts[i].Load()
+		total += counts[i]
+	}
+	if total == 0 {
+		return 0
+	}
+
+	// Find the bucket containing the rank of the quantile
+	rank := uint64(q*float64(total) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var seen uint64
+	for i, n := range counts {
+		seen += n
+		if seen >= rank && i < len(latencyBuckets) {
+			return latencyBuckets[i]
+		}
+	}
+	return time.Duration(h.max.Load())
+}
+
+// Percentiles returns the 50th, 95th, and 99th percentile latency of the callback function registered with
+// the provided namespace, capability, and operation. Latency is measured from the start to the end of the
+// callback function; router PreFunc and PostFunc functions are not included.
+//
+// Percentiles are computed from a fixed-bucket histogram, with bucket boundaries of 100µs, 250µs, 500µs,
+// 1ms, 2.5ms, 5ms, 10ms, 25ms, 50ms, 100ms, 250ms, 500ms, 1s, 2.5s, 5s, and 10s. The returned value is the
+// upper bound of the bucket containing the percentile, so it may overstate the true percentile by up to
+// the bucket width. Percentiles above 10s are reported as the largest latency recorded. This trades
+// accuracy for constant memory and lock-free recording.
+//
+// Latencies are kept from the time the callback is registered, including across RenameNamespace; they are
+// discarded when the callback is unregistered. If the callback is not found, or has not been called, zero
+// is returned for each.
+func (r *Router) Percentiles(namespace, capability, operation string) (p50, p95, p99 time.Duration) {
+	cb, ok := r.lookup(fmt.Sprintf("%s:%s:%s", namespace, capability, operation))
+	if !ok {
+		return 0, 0, 0
+	}
+	return cb.latency.percentile(0.50), cb.latency.percentile(0.95), cb.latency.percentile(0.99)
+}
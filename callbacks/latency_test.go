@@ -0,0 +1,121 @@
+package callbacks
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type PercentileTestCase struct {
+	Name      string
+	Latencies []time.Duration
+	P50       time.Duration
+	P95       time.Duration
+	P99       time.Duration
+}
+
+func TestLatencyHistogramPercentile(t *testing.T) {
+	tt := []PercentileTestCase{
+		{
+			Name: "Empty",
+		},
+		{
+			Name:      "Single",
+			Latencies: []time.Duration{3 * time.Millisecond},
+			P50:       5 * time.Millisecond,
+			P95:       5 * time.Millisecond,
+			P99:       5 * time.Millisecond,
+		},
+		{
+			Name:      "Exact bucket bound",
+			Latencies: []time.Duration{time.Millisecond},
+			P50:       time.Millisecond,
+			P95:       time.Millisecond,
+			P99:       time.Millisecond,
+		},
+		{
+			Name: "Tail",
+			Latencies: append(
+				repeatLatency(90, 50*time.Microsecond),
+				append(repeatLatency(8, 20*time.Millisecond), repeatLatency(2, 400*time.Millisecond)...)...,
+			),
+			P50: 100 * time.Microsecond,
+			P95: 25 * time.Millisecond,
+			P99: 500 * time.Millisecond,
+		},
+		{
+			Name:      "Overflow",
+			Latencies: []time.Duration{time.Millisecond, 30 * time.Second},
+			P50:       time.Millisecond,
+			P95:       30 * time.Second,
+			P99:       30 * time.Second,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			h := &latencyHistogram{}
+			for _, d := range tc.Latencies {
+				h.observe(d)
+			}
+
+			if p := h.percentile(0.50); p != tc.P50 {
+				t.Errorf("Unexpected p50: %s, expected: %s", p, tc.P50)
+			}
+			if p := h.percentile(0.95); p != tc.P95 {
+				t.Errorf("Unexpected p95: %s, expected: %s", p, tc.P95)
+			}
+			if p := h.percentile(0.99); p != tc.P99 {
+				t.Errorf("Unexpected p99: %s, expected: %s", p, tc.P99)
+			}
+		})
+	}
+}
+
+func repeatLatency(n int, d time.Duration) []time.Duration {
+	l := make([]time.Duration, n)
+	for i := range l {
+		l[i] = d
+	}
+	return l
+}
+
+func TestRouterPercentiles(t *testing.T) {
+	router, err := New(RouterConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "latency",
+		Operation:  "sleep",
+		Func: func(input []byte) ([]byte, error) {
+			time.Sleep(2 * time.Millisecond)
+			return input, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		_, err := router.Callback(context.Background(), "default", "latency", "sleep", []byte(""))
+		if err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+	}
+
+	p50, p95, p99 := router.Percentiles("default", "latency", "sleep")
+	if p50 < 2*time.Millisecond || p50 > p95 || p95 > p99 {
+		t.Errorf("Unexpected percentiles: p50 %s, p95 %s, p99 %s", p50, p95, p99)
+	}
+
+	t.Run("Not found", func(t *testing.T) {
+		p50, p95, p99 := router.Percentiles("default", "latency", "missing")
+		if p50 != 0 || p95 != 0 || p99 != 0 {
+			t.Errorf("Unexpected percentiles: p50 %s, p95 %s, p99 %s", p50, p95, p99)
+		}
+	})
+}
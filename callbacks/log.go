@@ -0,0 +1,65 @@
+package callbacks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+const (
+	// LogNamespace is the namespace LogHandler registers the logging capability to.
+	LogNamespace = "log"
+
+	// LogCapability is the capability LogHandler registers the logging capability to.
+	LogCapability = "logger"
+)
+
+// LogHandler returns a callback configuration providing guests with a logging capability routed through the
+// host logger. Guests log by performing a host call to the LogNamespace namespace and LogCapability
+// capability, with the log level as the operation and the message as the input:
+//
+//	wapc.HostCall("log", "logger", "info", []byte("Hello World!"))
+//
+// The supported levels are "debug", "info", "warn", and "error"; other levels return ErrInvalidOperation.
+// Records carry any request ID found within the callback context, see RequestIDFromContext. If logger is
+// nil, slog.Default is used.
+//
+// The returned configuration is registered using WildcardOperation and may be modified before it is
+// registered, such as to change the namespace.
+func LogHandler(logger *slog.Logger) CallbackConfig {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return CallbackConfig{
+		Namespace:  LogNamespace,
+		Capability: LogCapability,
+		Operation:  WildcardOperation,
+		CtxFunc: func(ctx context.Context, input []byte) ([]byte, error) {
+			op, _ := OperationFromContext(ctx)
+
+			var level slog.Level
+			switch strings.ToLower(op) {
+			case "debug":
+				level = slog.LevelDebug
+			case "info":
+				level = slog.LevelInfo
+			case "warn":
+				level = slog.LevelWarn
+			case "error":
+				level = slog.LevelError
+			default:
+				return nil, fmt.Errorf("%w: unknown log level %s", ErrInvalidOperation, op)
+			}
+
+			var attrs []slog.Attr
+			if id, ok := RequestIDFromContext(ctx); ok {
+				attrs = append(attrs, slog.String("request_id", id))
+			}
+			logger.LogAttrs(ctx, level, string(input), attrs...)
+
+			return []byte(""), nil
+		},
+	}
+}
@@ -0,0 +1,57 @@
+package callbacks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type LogHandlerTestCase struct {
+	Name   string
+	Level  string
+	Output string
+	Err    error
+}
+
+func TestLogHandler(t *testing.T) {
+	tt := []LogHandlerTestCase{
+		{Name: "Debug", Level: "debug", Output: "level=DEBUG msg=\"guest message\""},
+		{Name: "Info", Level: "info", Output: "level=INFO msg=\"guest message\""},
+		{Name: "Warn", Level: "warn", Output: "level=WARN msg=\"guest message\""},
+		{Name: "Error", Level: "ERROR", Output: "level=ERROR msg=\"guest message\""},
+		{Name: "Unknown level", Level: "trace", Err: ErrInvalidOperation},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+			router, err := New(RouterConfig{})
+			if err != nil {
+				t.Fatalf("Unexpected error creating router: %s", err)
+			}
+			defer router.Close()
+
+			if err := router.RegisterCallback(LogHandler(logger)); err != nil {
+				t.Fatalf("Unexpected error registering callback: %s", err)
+			}
+
+			ctx := WithRequestID(context.Background(), "req-1")
+			_, err = router.Callback(ctx, LogNamespace, LogCapability, tc.Level, []byte("guest message"))
+			if !errors.Is(err, tc.Err) {
+				t.Fatalf("Unexpected error calling callback: %s, expected: %s", err, tc.Err)
+			}
+
+			if !strings.Contains(buf.String(), tc.Output) {
+				t.Errorf("Unexpected log output: %q, expected: %q", buf.String(), tc.Output)
+			}
+			if tc.Err == nil && !strings.Contains(buf.String(), "request_id=req-1") {
+				t.Errorf("Expected log output to carry the request ID: %q", buf.String())
+			}
+		})
+	}
+}
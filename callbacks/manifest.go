@@ -0,0 +1,186 @@
+package callbacks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RouterManifest is a serializable description of the callbacks registered to a router, produced by
+// Router.Export. Together with a FunctionRegistry, a manifest can be loaded with Router.LoadManifest to
+// reconstruct the router's routing configuration elsewhere, such as in a staging environment.
+type RouterManifest struct {
+	// Callbacks describes each registered callback, sorted by namespace, capability, and operation.
+	Callbacks []CallbackManifest `json:"callbacks"`
+
+	// Aliases describes each registered alias, sorted by namespace, capability, and operation.
+	Aliases []AliasManifest `json:"aliases,omitempty"`
+}
+
+// CallbackManifest is a serializable description of a registered callback. Functions, such as the
+// callback function itself, OnPanic, and IdempotencyKeyFunc, cannot be serialized and are omitted.
+type CallbackManifest struct {
+	// Namespace represents the namespace a callback is registered to.
+	Namespace string `json:"namespace"`
+
+	// Capability represents the capability a callback is registered to.
+	Capability string `json:"capability"`
+
+	// Operation represents the operation a callback performs, or WildcardOperation.
+	Operation string `json:"operation"`
+
+	// Priority is the admission priority of the callback.
+	Priority int `json:"priority,omitempty"`
+
+	// SkipPreFunc exempts the callback from the router's PreFunc and PreFuncWithContext functions.
+	SkipPreFunc bool `json:"skipPreFunc,omitempty"`
+
+	// SkipPostFunc exempts the callback from the router's PostFunc function.
+	SkipPostFunc bool `json:"skipPostFunc,omitempty"`
+
+	// ServeStaleOnError serves the most recent successful response when the callback function errors.
+	ServeStaleOnError bool `json:"serveStaleOnError,omitempty"`
+
+	// IdempotencyTTL is the duration successful results are retained for IdempotencyKeyFunc.
+	IdempotencyTTL time.Duration `json:"idempotencyTTL,omitempty"`
+
+	// Timeout is the maximum duration the callback function may run.
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// Metadata is the user-defined information provided when the callback was registered.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// AliasManifest is a serializable description of an alias registered via RegisterAlias.
+type AliasManifest struct {
+	// Namespace is the namespace of the alias.
+	Namespace string `json:"namespace"`
+
+	// Capability is the capability of the alias.
+	Capability string `json:"capability"`
+
+	// Operation is the operation of the alias.
+	Operation string `json:"operation"`
+
+	// TargetNamespace is the namespace the alias targets.
+	TargetNamespace string `json:"targetNamespace"`
+
+	// TargetCapability is the capability the alias targets.
+	TargetCapability string `json:"targetCapability"`
+
+	// TargetOperation is the operation the alias targets.
+	TargetOperation string `json:"targetOperation"`
+}
+
+// FunctionRegistry provides the callback functions used to load a RouterManifest, keyed by
+// "namespace:capability:operation".
+type FunctionRegistry map[string]func(ctx context.Context, input []byte) ([]byte, error)
+
+// Export returns a manifest describing every callback and alias registered to the router.
+func (r *Router) Export() RouterManifest {
+	callbacks := *r.callbacks.Load()
+
+	keys := make([]string, 0, len(callbacks))
+	for key := range callbacks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	m := RouterManifest{Callbacks: make([]CallbackManifest, 0, len(keys))}
+	for _, key := range keys {
+		cb := callbacks[key]
+		m.Callbacks = append(m.Callbacks, CallbackManifest{
+			Namespace:         cb.Namespace,
+			Capability:        cb.Capability,
+			Operation:         cb.Operation,
+			Priority:          cb.Priority,
+			SkipPreFunc:       cb.SkipPreFunc,
+			SkipPostFunc:      cb.SkipPostFunc,
+			ServeStaleOnError: cb.ServeStaleOnError,
+			IdempotencyTTL:    cb.IdempotencyTTL,
+			Timeout:           cb.Timeout,
+			Metadata:          copyMetadata(cb.Metadata),
+		})
+	}
+
+	aliases := *r.aliases.Load()
+	keys = make([]string, 0, len(aliases))
+	for key := range aliases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		a := aliases[key]
+		m.Aliases = append(m.Aliases, AliasManifest{
+			Namespace:        a.from.namespace,
+			Capability:       a.from.capability,
+			Operation:        a.from.operation,
+			TargetNamespace:  a.to.namespace,
+			TargetCapability: a.to.capability,
+			TargetOperation:  a.to.operation,
+		})
+	}
+	return m
+}
+
+// LoadManifest registers every callback and alias described by the manifest, using the function registered
+// within the registry for each callback as its CtxFunc.
+//
+// Either every callback and alias is registered or none are. If the registry is missing a function for any
+// callback, ErrInvalidFunc is returned; if any callback fails to register, such as because it already exists,
+// the error is returned as with RegisterCallbacks, and if any alias fails to register, the error is returned
+// as with RegisterAlias.
+func (r *Router) LoadManifest(m RouterManifest, registry FunctionRegistry) error {
+	configs := make([]CallbackConfig, 0, len(m.Callbacks))
+	for _, cm := range m.Callbacks {
+		key := fmt.Sprintf("%s:%s:%s", cm.Namespace, cm.Capability, cm.Operation)
+		fn, ok := registry[key]
+		if !ok || fn == nil {
+			return fmt.Errorf("%w: no function registered for %s", ErrInvalidFunc, key)
+		}
+
+		configs = append(configs, CallbackConfig{
+			Namespace:         cm.Namespace,
+			Capability:        cm.Capability,
+			Operation:         cm.Operation,
+			CtxFunc:           fn,
+			Priority:          cm.Priority,
+			SkipPreFunc:       cm.SkipPreFunc,
+			SkipPostFunc:      cm.SkipPostFunc,
+			ServeStaleOnError: cm.ServeStaleOnError,
+			IdempotencyTTL:    cm.IdempotencyTTL,
+			Timeout:           cm.Timeout,
+			Metadata:          cm.Metadata,
+		})
+	}
+
+	if err := r.RegisterCallbacks(configs); err != nil {
+		return err
+	}
+
+	// Register aliases, rolling back the callbacks and aliases already registered on failure
+	registered := make([]string, 0, len(m.Aliases))
+	for n, am := range m.Aliases {
+		err := r.RegisterAlias(
+			am.Namespace, am.Capability, am.Operation,
+			am.TargetNamespace, am.TargetCapability, am.TargetOperation,
+		)
+		if err != nil {
+			r.removeAliases(registered)
+			for _, cfg := range configs {
+				_ = r.UnregisterCallback(cfg)
+			}
+			return fmt.Errorf("unable to register alias %d (%s:%s:%s) - %w",
+				n, am.Namespace, am.Capability, am.Operation, err)
+		}
+		registered = append(registered, route{
+			namespace:  am.Namespace,
+			capability: am.Capability,
+			operation:  am.Operation,
+		}.key())
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package callbacks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRouterManifest(t *testing.T) {
+	router, err := New(RouterConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	for _, cfg := range []CallbackConfig{
+		{Namespace: "default", Capability: "kv", Operation: "get", Priority: 1, Metadata: map[string]string{"owner": "kv"}},
+		{Namespace: "default", Capability: "metrics", Operation: WildcardOperation, SkipPostFunc: true},
+		{Namespace: "default", Capability: "health", Operation: "check", SkipPreFunc: true, ServeStaleOnError: true},
+		{Namespace: "default", Capability: "kv", Operation: "set", IdempotencyTTL: time.Hour},
+	} {
+		cfg.Func = func(input []byte) ([]byte, error) { return input, nil }
+		if err := router.RegisterCallback(cfg); err != nil {
+			t.Fatalf("Unexpected error registering callback: %s", err)
+		}
+	}
+	if err := router.RegisterAlias("legacy", "cache", "get", "default", "kv", "get"); err != nil {
+		t.Fatalf("Unexpected error registering alias: %s", err)
+	}
+
+	// Export the manifest and round trip it through JSON
+	b, err := json.Marshal(router.Export())
+	if err != nil {
+		t.Fatalf("Unexpected error marshaling manifest: %s", err)
+	}
+	var manifest RouterManifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		t.Fatalf("Unexpected error unmarshaling manifest: %s", err)
+	}
+
+	if len(manifest.Callbacks) != 4 {
+		t.Fatalf("Unexpected number of callbacks in manifest: %d, expected: 4", len(manifest.Callbacks))
+	}
+	if manifest.Callbacks[0].Capability != "health" || manifest.Callbacks[3].Operation != WildcardOperation {
+		t.Errorf("Unexpected manifest order: %+v", manifest.Callbacks)
+	}
+	expectedAlias := AliasManifest{
+		Namespace: "legacy", Capability: "cache", Operation: "get",
+		TargetNamespace: "default", TargetCapability: "kv", TargetOperation: "get",
+	}
+	if len(manifest.Aliases) != 1 || manifest.Aliases[0] != expectedAlias {
+		t.Errorf("Unexpected manifest aliases: %+v", manifest.Aliases)
+	}
+
+	registry := FunctionRegistry{
+		"default:kv:get": func(_ context.Context, _ []byte) ([]byte, error) { return []byte("kv"), nil },
+		"default:kv:set": func(_ context.Context, _ []byte) ([]byte, error) { return []byte("set"), nil },
+		"default:metrics:*": func(ctx context.Context, _ []byte) ([]byte, error) {
+			op, _ := OperationFromContext(ctx)
+			return []byte(op), nil
+		},
+		"default:health:check": func(_ context.Context, _ []byte) ([]byte, error) { return []byte("ok"), nil },
+	}
+
+	t.Run("Load", func(t *testing.T) {
+		staging, err := New(RouterConfig{})
+		if err != nil {
+			t.Fatalf("Unexpected error creating router: %s", err)
+		}
+		defer staging.Close()
+
+		if err := staging.LoadManifest(manifest, registry); err != nil {
+			t.Fatalf("Unexpected error loading manifest: %s", err)
+		}
+
+		for n, cb := range staging.List() {
+			expected := router.List()[n]
+			if cb.Namespace != expected.Namespace || cb.Capability != expected.Capability ||
+				cb.Operation != expected.Operation || cb.Priority != expected.Priority ||
+				cb.SkipPreFunc != expected.SkipPreFunc || cb.SkipPostFunc != expected.SkipPostFunc ||
+				cb.ServeStaleOnError != expected.ServeStaleOnError || cb.IdempotencyTTL != expected.IdempotencyTTL ||
+				len(cb.Metadata) != len(expected.Metadata) {
+				t.Errorf("Unexpected loaded callback: %+v, expected: %+v", cb, expected)
+			}
+		}
+
+		rsp, err := staging.Callback(context.Background(), "default", "metrics", "counter.inc", []byte(""))
+		if err != nil || string(rsp) != "counter.inc" {
+			t.Errorf("Unexpected wildcard callback result: %s, %s", rsp, err)
+		}
+
+		rsp, err = staging.Callback(context.Background(), "legacy", "cache", "get", []byte(""))
+		if err != nil || string(rsp) != "kv" {
+			t.Errorf("Unexpected aliased callback result: %s, %s", rsp, err)
+		}
+	})
+
+	t.Run("Alias registration failure", func(t *testing.T) {
+		staging, err := New(RouterConfig{})
+		if err != nil {
+			t.Fatalf("Unexpected error creating router: %s", err)
+		}
+		defer staging.Close()
+
+		if err := staging.RegisterAlias("legacy", "cache", "get", "default", "kv", "set"); err != nil {
+			t.Fatalf("Unexpected error registering alias: %s", err)
+		}
+
+		err = staging.LoadManifest(manifest, registry)
+		if !errors.Is(err, ErrCallbackExists) {
+			t.Errorf("Expected callback exists error, got: %s", err)
+		}
+		if len(staging.List()) != 0 {
+			t.Errorf("Unexpected callbacks registered: %d, expected: 0", len(staging.List()))
+		}
+		if aliases := staging.Export().Aliases; len(aliases) != 1 || aliases[0].TargetOperation != "set" {
+			t.Errorf("Unexpected aliases after failed load: %+v", aliases)
+		}
+	})
+
+	t.Run("Missing function", func(t *testing.T) {
+		staging, err := New(RouterConfig{})
+		if err != nil {
+			t.Fatalf("Unexpected error creating router: %s", err)
+		}
+		defer staging.Close()
+
+		err = staging.LoadManifest(manifest, FunctionRegistry{"default:kv:get": registry["default:kv:get"]})
+		if !errors.Is(err, ErrInvalidFunc) {
+			t.Errorf("Expected invalid func error, got: %s", err)
+		}
+		if len(staging.List()) != 0 {
+			t.Errorf("Unexpected callbacks registered: %d", len(staging.List()))
+		}
+	})
+
+	t.Run("Registration failure", func(t *testing.T) {
+		staging, err := New(RouterConfig{})
+		if err != nil {
+			t.Fatalf("Unexpected error creating router: %s", err)
+		}
+		defer staging.Close()
+
+		err = staging.RegisterCallback(CallbackConfig{
+			Namespace:  "default",
+			Capability: "metrics",
+			Operation:  WildcardOperation,
+			Func:       func(input []byte) ([]byte, error) { return input, nil },
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error registering callback: %s", err)
+		}
+
+		err = staging.LoadManifest(manifest, registry)
+		if !errors.Is(err, ErrCallbackExists) {
+			t.Errorf("Expected callback exists error, got: %s", err)
+		}
+		if len(staging.List()) != 1 {
+			t.Errorf("Unexpected callbacks registered: %d, expected: 1", len(staging.List()))
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package callbacks
+
+import (
+	"fmt"
+)
+
+// namespaceMiddleware is a pair of PreFunc and PostFunc style functions registered for a namespace via
+// UseNamespace. Either function may be nil.
+type namespaceMiddleware struct {
+	// pre is called before callbacks within the namespace are executed.
+	pre func(CallbackRequest) ([]byte, error)
+
+	// post is called after callbacks within the namespace are executed.
+	post func(CallbackResult)
+}
+
+// UseNamespace registers middleware for the callbacks registered within the provided namespace, such as
+// authorization logic specific to a namespace. Either function may be nil, but not both. UseNamespace may be
+// called multiple times for a namespace; the middleware of each call is run in the order registered.
+//
+// Middleware applies to the namespace of the executed callback, after any alias is followed, rather than the
+// requested namespace. Callbacks that set SkipPreFunc or SkipPostFunc also skip namespace pre or post
+// functions respectively.
+//
+// Hooks run in the following order:
+//
+//  1. The router PreFunc, then PreFuncWithContext.
+//  2. Each namespace pre function. The CallbackRequest carries any context returned by
+//     PreFuncWithContext. As with PreFunc, a non-nil payload returned without an error replaces the
+//     input provided to later pre functions, the callback function, and PostFunc. If a pre function
+//     returns an error, later pre functions and the callback function are not called, and the response
+//     and error are returned to the caller.
+//  3. The callback function.
+//  4. Each namespace post function, called synchronously with the same CallbackResult as PostFunc.
+//  5. The router PostFunc, which is queued unless SyncPostFunc is enabled.
+//
+// When PostFuncOnPreError is enabled, namespace post functions are also called for requests rejected by a
+// pre function, as with PostFunc.
+func (r *Router) UseNamespace(
+	namespace string,
+	pre func(CallbackRequest) ([]byte, error),
+	post func(CallbackResult),
+) error {
+	if namespace == "" {
+		return ErrInvalidNamespace
+	}
+	if pre == nil && post == nil {
+		return fmt.Errorf("%w: pre and post functions for namespace %s", ErrInvalidFunc, namespace)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	current := *r.middleware.Load()
+	middleware := make(map[string][]namespaceMiddleware, len(current)+1)
+	for k, v := range current {
+		middleware[k] = v
+	}
+	mw := make([]namespaceMiddleware, len(current[namespace]), len(current[namespace])+1)
+	copy(mw, current[namespace])
+	middleware[namespace] = append(mw, namespaceMiddleware{pre: pre, post: post})
+	r.middleware.Store(&middleware)
+
+	return nil
+}
+
+// namespacePre calls the pre functions registered for the namespace in order, stopping at the first error. The
+// request is returned with its input replaced by any transformed input.
+func (r *Router) namespacePre(namespace string, req CallbackRequest) (CallbackRequest, []byte, error) {
+	for _, mw := range (*r.middleware.Load())[namespace] {
+		if mw.pre == nil {
+			continue
+		}
+		rsp, err := mw.pre(req)
+		if err != nil {
+			return req, rsp, err
+		}
+		if rsp != nil {
+			req.Input = rsp
+		}
+	}
+	return req, nil, nil
+}
+
+// namespacePost calls the post functions registered for the namespace in order.
+func (r *Router) namespacePost(namespace string, res CallbackResult) {
+	for _, mw := range (*r.middleware.Load())[namespace] {
+		if mw.post != nil {
+			mw.post(res)
+		}
+	}
+}
+
+// hasNamespacePost returns true if any post function is registered for the namespace.
+func (r *Router) hasNamespacePost(namespace string) bool {
+	for _, mw := range (*r.middleware.Load())[namespace] {
+		if mw.post != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package callbacks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRouterUseNamespace(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	record := func(hook string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, hook)
+	}
+
+	router, err := New(RouterConfig{
+		PreFunc: func(CallbackRequest) ([]byte, error) {
+			record("pre")
+			return nil, nil
+		},
+		PostFunc: func(CallbackResult) {
+			record("post")
+		},
+		SyncPostFunc:       true,
+		PostFuncOnPreError: true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	for _, ns := range []string{"db", "http"} {
+		err := router.RegisterCallback(CallbackConfig{
+			Namespace:  ns,
+			Capability: "client",
+			Operation:  "call",
+			Func: func([]byte) ([]byte, error) {
+				record("callback")
+				return []byte("ok"), nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error registering callback: %s", err)
+		}
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		if err := router.UseNamespace("", nil, func(CallbackResult) {}); !errors.Is(err, ErrInvalidNamespace) {
+			t.Errorf("Expected invalid namespace error, got: %s", err)
+		}
+		if err := router.UseNamespace("db", nil, nil); !errors.Is(err, ErrInvalidFunc) {
+			t.Errorf("Expected invalid func error, got: %s", err)
+		}
+	})
+
+	for _, name := range []string{"db1", "db2"} {
+		name := name
+		err := router.UseNamespace("db",
+			func(req CallbackRequest) ([]byte, error) {
+				record(name + " pre")
+				if string(req.Input) == "deny" {
+					return []byte("denied"), ErrTestError
+				}
+				return nil, nil
+			},
+			func(CallbackResult) {
+				record(name + " post")
+			},
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error registering namespace middleware: %s", err)
+		}
+	}
+
+	call := func(ns, input string) ([]string, []byte, error) {
+		mu.Lock()
+		order = nil
+		mu.Unlock()
+
+		rsp, err := router.Callback(context.Background(), ns, "client", "call", []byte(input))
+
+		mu.Lock()
+		defer mu.Unlock()
+		return order, rsp, err
+	}
+
+	t.Run("Ordering", func(t *testing.T) {
+		got, _, err := call("db", "")
+		if err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+		expected := []string{"pre", "db1 pre", "db2 pre", "callback", "db1 post", "db2 post", "post"}
+		if strings.Join(got, ",") != strings.Join(expected, ",") {
+			t.Errorf("Unexpected hook order: %v, expected: %v", got, expected)
+		}
+	})
+
+	t.Run("Other namespace", func(t *testing.T) {
+		got, _, err := call("http", "")
+		if err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+		expected := []string{"pre", "callback", "post"}
+		if strings.Join(got, ",") != strings.Join(expected, ",") {
+			t.Errorf("Unexpected hook order: %v, expected: %v", got, expected)
+		}
+	})
+
+	t.Run("Pre error", func(t *testing.T) {
+		got, rsp, err := call("db", "deny")
+		if !errors.Is(err, ErrTestError) || string(rsp) != "denied" {
+			t.Fatalf("Expected namespace pre error, got: %q - %v", rsp, err)
+		}
+		expected := []string{"pre", "db1 pre", "db1 post", "db2 post", "post"}
+		if strings.Join(got, ",") != strings.Join(expected, ",") {
+			t.Errorf("Unexpected hook order: %v, expected: %v", got, expected)
+		}
+	})
+}
+
+func TestRouterUseNamespaceTransform(t *testing.T) {
+	var results []CallbackResult
+	router, err := New(RouterConfig{
+		PostFunc: func(res CallbackResult) {
+			results = append(results, res)
+		},
+		SyncPostFunc: true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	err = router.RegisterCallbackFunc("default", "echo", "call", func(input []byte) ([]byte, error) {
+		return input, nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	// The first pre function transforms the input, the second leaves it unchanged
+	err = router.UseNamespace("default", func(req CallbackRequest) ([]byte, error) {
+		if string(req.Input) == "keep" {
+			return nil, nil
+		}
+		return bytes.ToUpper(req.Input), nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error registering namespace middleware: %s", err)
+	}
+	var seen []string
+	err = router.UseNamespace("default", func(req CallbackRequest) ([]byte, error) {
+		seen = append(seen, string(req.Input))
+		return nil, nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error registering namespace middleware: %s", err)
+	}
+
+	for _, tc := range []struct{ input, expected string }{
+		{input: "hello", expected: "HELLO"},
+		{input: "keep", expected: "keep"},
+	} {
+		rsp, err := router.Callback(context.Background(), "default", "echo", "call", []byte(tc.input))
+		if err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+		if string(rsp) != tc.expected {
+			t.Errorf("Unexpected callback response: %q, expected: %q", rsp, tc.expected)
+		}
+	}
+
+	if strings.Join(seen, ",") != "HELLO,keep" {
+		t.Errorf("Unexpected input seen by later pre function: %v", seen)
+	}
+	if len(results) != 2 || string(results[0].Input) != "HELLO" {
+		t.Errorf("Expected transformed input within PostFunc result, got: %+v", results)
+	}
+}
@@ -0,0 +1,60 @@
+package callbacks
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNilRouter is returned when a nil Router is provided to a Mux.
+	ErrNilRouter = errors.New("router cannot be nil")
+)
+
+// Mux composes multiple routers, presenting them to the waPC engine as a single callback handler.
+//
+// This enables separate subsystems to own and manage their routers independently while sharing a single
+// waPC engine. Each router keeps its own callbacks, PreFunc, PostFunc, and other configuration.
+type Mux struct {
+	// routers is the ordered list of routers callbacks are dispatched to.
+	routers []*Router
+}
+
+// NewMux creates a new Mux dispatching callbacks to the provided routers. Routers are consulted in the
+// order provided.
+func NewMux(routers ...*Router) (*Mux, error) {
+	for _, r := range routers {
+		if r == nil {
+			return nil, ErrNilRouter
+		}
+	}
+
+	m := &Mux{
+		routers: make([]*Router, len(routers)),
+	}
+	copy(m.routers, routers)
+
+	return m, nil
+}
+
+// Callback executes the callback registered with the first router that has a callback registered for the
+// user-provided Namespace, Capability, and Operation. The callback is executed by that router, including
+// any PreFunc, PostFunc, and OnError functions defined for it.
+//
+// Routers are matched using Lookup, so aliases and WildcardOperation callbacks are honored exactly as
+// Router.Callback serves them; OnMiss functions are not consulted. If no router has a matching callback,
+// ErrNotFound is returned.
+func (m *Mux) Callback(ctx context.Context, namespace, capability, operation string, input []byte) ([]byte, error) {
+	for _, r := range m.routers {
+		if _, err := r.Lookup(namespace, capability, operation); err == nil {
+			return r.Callback(ctx, namespace, capability, operation, input)
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// HostCallHandler returns the Mux's callback handler, which can be registered with the waPC engine as the
+// host call function.
+func (m *Mux) HostCallHandler() func(context.Context, string, string, string, []byte) ([]byte, error) {
+	return m.Callback
+}
@@ -0,0 +1,87 @@
+package callbacks
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type MuxTestCase struct {
+	Name       string
+	Namespace  string
+	Capability string
+	Operation  string
+	Output     string
+	Err        error
+}
+
+func TestMux(t *testing.T) {
+	_, err := NewMux(nil)
+	if !errors.Is(err, ErrNilRouter) {
+		t.Errorf("Expected nil router error creating mux, got: %s", err)
+	}
+
+	newRouter := func(name string, callbacks ...CallbackConfig) *Router {
+		router, err := New(RouterConfig{})
+		if err != nil {
+			t.Fatalf("Unexpected error creating router: %s", err)
+		}
+		for _, cfg := range callbacks {
+			cfg.Func = func([]byte) ([]byte, error) {
+				return []byte(name), nil
+			}
+			if err := router.RegisterCallback(cfg); err != nil {
+				t.Fatalf("Unexpected error registering callback: %s", err)
+			}
+		}
+		return router
+	}
+
+	kv := newRouter("kv",
+		CallbackConfig{Namespace: "default", Capability: "kv", Operation: "get"},
+		CallbackConfig{Namespace: "default", Capability: "shared", Operation: "get"},
+	)
+	defer kv.Close()
+
+	sql := newRouter("sql",
+		CallbackConfig{Namespace: "default", Capability: "sql", Operation: "query"},
+		CallbackConfig{Namespace: "default", Capability: "shared", Operation: "get"},
+	)
+	defer sql.Close()
+
+	// The cache router serves cache operations via a wildcard and alias only
+	cache := newRouter("cache",
+		CallbackConfig{Namespace: "default", Capability: "cache", Operation: WildcardOperation},
+	)
+	defer cache.Close()
+	if err := cache.RegisterAlias("legacy", "memcache", "get", "default", "cache", "get"); err != nil {
+		t.Fatalf("Unexpected error registering alias: %s", err)
+	}
+
+	mux, err := NewMux(kv, sql, cache)
+	if err != nil {
+		t.Fatalf("Unexpected error creating mux: %s", err)
+	}
+
+	tt := []MuxTestCase{
+		{Name: "First router", Namespace: "default", Capability: "kv", Operation: "get", Output: "kv"},
+		{Name: "Second router", Namespace: "default", Capability: "sql", Operation: "query", Output: "sql"},
+		{Name: "Both routers", Namespace: "default", Capability: "shared", Operation: "get", Output: "kv"},
+		{Name: "Wildcard route", Namespace: "default", Capability: "cache", Operation: "set", Output: "cache"},
+		{Name: "Alias route", Namespace: "legacy", Capability: "memcache", Operation: "get", Output: "cache"},
+		{Name: "No router", Namespace: "default", Capability: "missing", Operation: "get", Err: ErrNotFound},
+	}
+
+	handler := mux.HostCallHandler()
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			rsp, err := handler(context.Background(), tc.Namespace, tc.Capability, tc.Operation, []byte(""))
+			if !errors.Is(err, tc.Err) {
+				t.Fatalf("Unexpected error calling callback: %s", err)
+			}
+			if string(rsp) != tc.Output {
+				t.Errorf("Unexpected callback response: %s, expected: %s", rsp, tc.Output)
+			}
+		})
+	}
+}
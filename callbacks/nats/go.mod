@@ -0,0 +1,22 @@
+module github.com/tarmac-project/wapc-toolkit/callbacks/nats
+
+go 1.21.4
+
+require (
+	github.com/nats-io/nats-server/v2 v2.10.14
+	github.com/nats-io/nats.go v1.34.1
+	github.com/tarmac-project/wapc-toolkit/callbacks v0.0.0
+)
+
+require (
+	github.com/klauspost/compress v1.17.7 // indirect
+	github.com/minio/highwayhash v1.0.2 // indirect
+	github.com/nats-io/jwt/v2 v2.5.5 // indirect
+	github.com/nats-io/nkeys v0.4.7 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
+	golang.org/x/crypto v0.22.0 // indirect
+	golang.org/x/sys v0.19.0 // indirect
+	golang.org/x/time v0.5.0 // indirect
+)
+
+replace github.com/tarmac-project/wapc-toolkit/callbacks => ../
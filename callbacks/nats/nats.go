@@ -0,0 +1,64 @@
+// Package nats bridges waPC host calls to NATS request/reply, exposing remote capabilities to guests through
+// the callbacks router.
+//
+// The package is a separate module so that importers of the callbacks package do not depend on NATS.
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/tarmac-project/wapc-toolkit/callbacks"
+)
+
+const (
+	// DefaultTimeout is the request timeout used by Proxy when the callback context has no deadline.
+	DefaultTimeout = 5 * time.Second
+)
+
+// Proxy returns a callback function that bridges host calls to NATS request/reply. The input is published
+// as a request on the subject returned by subjectFunc for the requested namespace, capability, and
+// operation, and the reply payload is returned to the guest. This exposes remote capabilities to guests
+// without bespoke glue.
+//
+// The returned function is suited to callbacks.RouterConfig.DefaultFunc, proxying every unregistered host
+// call, or to wrapping within a callback function. Requests are bounded by the callback context, or
+// DefaultTimeout if the context has no deadline. Timeouts return callbacks.ErrCallbackTimeout, and requests
+// with no responders, or for which subjectFunc returns an empty subject, return callbacks.ErrNotFound.
+func Proxy(
+	conn *natsgo.Conn,
+	subjectFunc func(namespace, capability, operation string) string,
+) func(callbacks.CallbackRequest) ([]byte, error) {
+	return func(req callbacks.CallbackRequest) ([]byte, error) {
+		subject := subjectFunc(req.Namespace, req.Capability, req.Operation)
+		if subject == "" {
+			return nil, fmt.Errorf("%w: no subject for %s:%s:%s",
+				callbacks.ErrNotFound, req.Namespace, req.Capability, req.Operation)
+		}
+
+		ctx := req.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+			defer cancel()
+		}
+
+		msg, err := conn.RequestWithContext(ctx, subject, req.Input)
+		switch {
+		case err == nil:
+			return msg.Data, nil
+		case errors.Is(err, natsgo.ErrNoResponders):
+			return nil, fmt.Errorf("%w: no responders on %s - %w", callbacks.ErrNotFound, subject, err)
+		case errors.Is(err, natsgo.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+			return nil, fmt.Errorf("%w: request on %s - %w", callbacks.ErrCallbackTimeout, subject, err)
+		default:
+			return nil, fmt.Errorf("unable to request %s - %w", subject, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package nats
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/tarmac-project/wapc-toolkit/callbacks"
+)
+
+type ProxyTestCase struct {
+	Name      string
+	Subject   string
+	Input     []byte
+	Timeout   time.Duration
+	Expected  []byte
+	ExpectErr error
+}
+
+func TestProxy(t *testing.T) {
+	opts := natsserver.DefaultTestOptions
+	opts.Port = server.RANDOM_PORT
+	srv := natsserver.RunServer(&opts)
+	defer srv.Shutdown()
+
+	conn, err := natsgo.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error connecting to NATS - %s", err)
+	}
+	defer conn.Close()
+
+	_, err = conn.Subscribe("echo", func(msg *natsgo.Msg) {
+		_ = msg.Respond(msg.Data)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error subscribing - %s", err)
+	}
+
+	// The slow subscriber never replies, forcing the request to time out.
+	_, err = conn.Subscribe("slow", func(_ *natsgo.Msg) {})
+	if err != nil {
+		t.Fatalf("Unexpected error subscribing - %s", err)
+	}
+
+	if err := conn.Flush(); err != nil {
+		t.Fatalf("Unexpected error flushing connection - %s", err)
+	}
+
+	tc := []ProxyTestCase{
+		{
+			Name:     "Reply",
+			Subject:  "echo",
+			Input:    []byte("Hello World"),
+			Expected: []byte("Hello World"),
+		},
+		{
+			Name:      "Timeout",
+			Subject:   "slow",
+			Input:     []byte("Hello World"),
+			Timeout:   50 * time.Millisecond,
+			ExpectErr: callbacks.ErrCallbackTimeout,
+		},
+		{
+			Name:      "No Responders",
+			Subject:   "nobody",
+			Input:     []byte("Hello World"),
+			ExpectErr: callbacks.ErrNotFound,
+		},
+		{
+			Name:      "Empty Subject",
+			Input:     []byte("Hello World"),
+			ExpectErr: callbacks.ErrNotFound,
+		},
+	}
+
+	for _, c := range tc {
+		t.Run(c.Name, func(t *testing.T) {
+			proxy := Proxy(conn, func(_, _, _ string) string {
+				return c.Subject
+			})
+
+			ctx := context.Background()
+			if c.Timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+				defer cancel()
+			}
+
+			rsp, err := proxy(callbacks.CallbackRequest{
+				Namespace:  "default",
+				Capability: "remote",
+				Operation:  "call",
+				Input:      c.Input,
+				Context:    ctx,
+			})
+			if !errors.Is(err, c.ExpectErr) {
+				t.Fatalf("Unexpected error - %s, expected %v", err, c.ExpectErr)
+			}
+
+			if !bytes.Equal(rsp, c.Expected) {
+				t.Errorf("Unexpected response - %s, expected %s", rsp, c.Expected)
+			}
+		})
+	}
+}
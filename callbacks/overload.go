@@ -0,0 +1,42 @@
+package callbacks
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultRetryAfter is the retry-after hint used for overloaded responses when RouterConfig.RetryAfter is
+// not provided.
+const DefaultRetryAfter = 100 * time.Millisecond
+
+// OverloadedError is returned when the router rejects a callback because the router's concurrency limit has
+// been reached. It wraps ErrOverloaded, so errors.Is(err, ErrOverloaded) continues to match, while
+// errors.As provides the retry-after hint.
+type OverloadedError struct {
+	// RetryAfter is how long the caller should wait before retrying the callback.
+	//
+	// The hint is the median latency of the rejected callback, the time an executing callback can be
+	// expected to free its slot, but never less than RouterConfig.RetryAfter. It is a hint only; the
+	// callback may be rejected again after waiting.
+	RetryAfter time.Duration
+}
+
+// Error returns the error message, including the retry-after hint.
+func (e *OverloadedError) Error() string {
+	return fmt.Sprintf("%s - retry after %s", ErrOverloaded, e.RetryAfter)
+}
+
+// Unwrap returns ErrOverloaded.
+func (e *OverloadedError) Unwrap() error {
+	return ErrOverloaded
+}
+
+// overloaded returns the error for a callback rejected by admission, with a retry-after hint derived from the
+// callback's latency.
+func (r *Router) overloaded(cb *Callback) error {
+	retryAfter := cb.latency.percentile(0.50)
+	if retryAfter < r.retryAfter {
+		retryAfter = r.retryAfter
+	}
+	return &OverloadedError{RetryAfter: retryAfter}
+}
@@ -0,0 +1,91 @@
+package callbacks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRouterRetryAfter(t *testing.T) {
+	router, err := New(RouterConfig{
+		MaxConcurrency: 1,
+		RetryAfter:     time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "overload",
+		Operation:  "block",
+		Func: func(input []byte) ([]byte, error) {
+			if string(input) == "block" {
+				close(started)
+				<-release
+			}
+			return input, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = router.Callback(context.Background(), "default", "overload", "block", []byte("block"))
+	}()
+	<-started
+
+	t.Run("Overloaded", func(t *testing.T) {
+		_, err := router.Callback(context.Background(), "default", "overload", "block", []byte(""))
+		if !errors.Is(err, ErrOverloaded) {
+			t.Fatalf("Expected overloaded error calling callback, got: %s", err)
+		}
+
+		var oerr *OverloadedError
+		if !errors.As(err, &oerr) {
+			t.Fatalf("Expected OverloadedError, got: %T", err)
+		}
+		if oerr.RetryAfter != time.Second {
+			t.Errorf("Unexpected retry-after hint: %s", oerr.RetryAfter)
+		}
+	})
+
+	close(release)
+	<-done
+
+	t.Run("Default", func(t *testing.T) {
+		r, err := New(RouterConfig{MaxConcurrency: 1})
+		if err != nil {
+			t.Fatalf("Unexpected error creating router: %s", err)
+		}
+		defer r.Close()
+
+		var oerr *OverloadedError
+		if !errors.As(r.overloaded(&Callback{latency: &latencyHistogram{}}), &oerr) {
+			t.Fatalf("Expected OverloadedError")
+		}
+		if oerr.RetryAfter != DefaultRetryAfter {
+			t.Errorf("Unexpected retry-after hint: %s", oerr.RetryAfter)
+		}
+	})
+
+	t.Run("Derived from latency", func(t *testing.T) {
+		cb := &Callback{latency: &latencyHistogram{}}
+		cb.latency.observe(2 * time.Second)
+
+		var oerr *OverloadedError
+		if !errors.As(router.overloaded(cb), &oerr) {
+			t.Fatalf("Expected OverloadedError")
+		}
+		if oerr.RetryAfter != 2500*time.Millisecond {
+			t.Errorf("Unexpected retry-after hint: %s", oerr.RetryAfter)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package callbacks
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// DefaultPostFuncQueueSize is the default number of CallbackResults that can be queued for PostFunc.
+	DefaultPostFuncQueueSize = 1000
+
+	// DefaultPostFuncWorkers is the default number of worker goroutines processing queued PostFunc calls.
+	DefaultPostFuncWorkers = 10
+)
+
+// QueuePolicy defines how the router behaves when the PostFunc queue is full.
+type QueuePolicy int
+
+const (
+	// QueueDropNewest discards the new result, leaving the queue unchanged. Dropped results are counted; see
+	// Router.PostFuncDropped. This is the default policy, so a slow PostFunc never slows down callback
+	// execution.
+	QueueDropNewest QueuePolicy = iota
+
+	// QueueDropOldest discards the oldest queued result to make room for the new result.
+	QueueDropOldest
+
+	// QueueBlock blocks the callback until there is room in the queue. No results are dropped, but a slow
+	// PostFunc will slow down callback execution.
+	QueueBlock
+)
+
+// postFuncQueue is a bounded queue of CallbackResults processed by a fixed pool of worker goroutines
+// calling the user-defined PostFunc.
+type postFuncQueue struct {
+	// RWMutex guards the queue channel against being closed while results are being sent.
+	sync.RWMutex
+
+	// fn is the user-defined PostFunc.
+	fn func(CallbackResult)
+
+	// ch is the bounded queue of results waiting to be processed.
+	ch chan CallbackResult
+
+	// policy determines how the queue behaves when full.
+	policy QueuePolicy
+
+	// closed is set once the queue is closed, any results dispatched afterward are dropped.
+	closed bool
+
+	// dropped counts the number of results dropped.
+	dropped atomic.Uint64
+
+	// wg tracks running workers.
+	wg sync.WaitGroup
+}
+
+// newPostFuncQueue creates a new queue and starts its workers.
+func newPostFuncQueue(fn func(CallbackResult), size, workers int, policy QueuePolicy) *postFuncQueue {
+	if size <= 0 {
+		size = DefaultPostFuncQueueSize
+	}
+	if workers <= 0 {
+		workers = DefaultPostFuncWorkers
+	}
+
+	q := &postFuncQueue{
+		fn:     fn,
+		ch:     make(chan CallbackResult, size),
+		policy: policy,
+	}
+
+	q.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+
+	return q
+}
+
+// work processes queued results until the queue is closed and drained.
+func (q *postFuncQueue) work() {
+	defer q.wg.Done()
+	for res := range q.ch {
+		q.fn(res)
+	}
+}
+
+// dispatch adds a result to the queue, applying the queue policy if the queue is full.
+func (q *postFuncQueue) dispatch(res CallbackResult) {
+	q.RLock()
+	defer q.RUnlock()
+
+	if q.closed {
+		q.dropped.Add(1)
+		return
+	}
+
+	switch q.policy {
+	case QueueDropOldest:
+		for {
+			select {
+			case q.ch <- res:
+				return
+			default:
+			}
+
+			// Discard the oldest result to make room
+			select {
+			case <-q.ch:
+				q.dropped.Add(1)
+			default:
+			}
+		}
+	case QueueBlock:
+		q.ch <- res
+	default:
+		select {
+		case q.ch <- res:
+		default:
+			q.dropped.Add(1)
+		}
+	}
+}
+
+// close stops accepting results and waits for the workers to process any queued results.
+func (q *postFuncQueue) close() {
+	q.Lock()
+	if q.closed {
+		q.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.ch)
+	q.Unlock()
+
+	q.wg.Wait()
+}
@@ -0,0 +1,212 @@
+package callbacks
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type QueuePolicyTestCase struct {
+	Name      string
+	Policy    QueuePolicy
+	Processed []string
+	Dropped   uint64
+}
+
+func TestPostFuncQueuePolicy(t *testing.T) {
+	tt := []QueuePolicyTestCase{
+		{
+			Name:      "Block",
+			Policy:    QueueBlock,
+			Processed: []string{"1", "2", "3"},
+			Dropped:   0,
+		},
+		{
+			Name:      "Drop Oldest",
+			Policy:    QueueDropOldest,
+			Processed: []string{"1", "3"},
+			Dropped:   1,
+		},
+		{
+			Name:      "Default",
+			Processed: []string{"1", "2"},
+			Dropped:   1,
+		},
+		{
+			Name:      "Drop Newest",
+			Policy:    QueueDropNewest,
+			Processed: []string{"1", "2"},
+			Dropped:   1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var mu sync.Mutex
+			var processed []string
+			started := make(chan struct{}, 3)
+			release := make(chan struct{})
+
+			router, err := New(RouterConfig{
+				PostFunc: func(res CallbackResult) {
+					started <- struct{}{}
+					<-release
+					mu.Lock()
+					defer mu.Unlock()
+					processed = append(processed, string(res.Input))
+				},
+				PostFuncQueueSize:   1,
+				PostFuncWorkers:     1,
+				PostFuncQueuePolicy: tc.Policy,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error creating router: %s", err)
+			}
+
+			err = router.RegisterCallback(CallbackConfig{
+				Namespace:  "default",
+				Capability: "counter",
+				Operation:  "increment",
+				Func: func(input []byte) ([]byte, error) {
+					return input, nil
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error registering callback: %s", err)
+			}
+
+			call := func(input string) {
+				_, err := router.Callback(context.Background(), "default", "counter", "increment", []byte(input))
+				if err != nil {
+					t.Errorf("Unexpected error calling callback: %s", err)
+				}
+			}
+
+			// First result is taken by the worker, which blocks until released
+			call("1")
+			<-started
+
+			// Second result fills the queue
+			call("2")
+
+			// Third result exceeds the queue
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				call("3")
+			}()
+
+			if tc.Policy == QueueBlock {
+				select {
+				case <-done:
+					t.Fatalf("Expected callback to block on a full queue")
+				case <-time.After(100 * time.Millisecond):
+				}
+			} else {
+				<-done
+			}
+
+			// Release the worker
+			close(release)
+			<-done
+			router.Close()
+
+			if router.PostFuncDropped() != tc.Dropped {
+				t.Errorf("Unexpected dropped count: %d, expected: %d", router.PostFuncDropped(), tc.Dropped)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(processed) != len(tc.Processed) {
+				t.Fatalf("Unexpected processed results: %v, expected: %v", processed, tc.Processed)
+			}
+			for i := range processed {
+				if processed[i] != tc.Processed[i] {
+					t.Errorf("Unexpected processed results: %v, expected: %v", processed, tc.Processed)
+				}
+			}
+		})
+	}
+}
+
+func TestPostFuncAfterClose(t *testing.T) {
+	counter := &Counter{}
+	router, err := New(RouterConfig{
+		PostFunc: func(CallbackResult) {
+			counter.Increment()
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	router.Close()
+
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "counter",
+		Operation:  "increment",
+		Func: func(input []byte) ([]byte, error) {
+			return input, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	_, err = router.Callback(context.Background(), "default", "counter", "increment", []byte(""))
+	if err != nil {
+		t.Fatalf("Unexpected error calling callback: %s", err)
+	}
+
+	if counter.Value() != 0 {
+		t.Errorf("Unexpected PostFunc count after close: %d, expected: 0", counter.Value())
+	}
+	if router.PostFuncDropped() != 1 {
+		t.Errorf("Unexpected dropped count: %d, expected: 1", router.PostFuncDropped())
+	}
+}
+
+func TestSyncPostFunc(t *testing.T) {
+	var results []CallbackResult
+	router, err := New(RouterConfig{
+		PreFunc: func(req CallbackRequest) ([]byte, error) {
+			if string(req.Input) == "deny" {
+				return nil, ErrTestError
+			}
+			return nil, nil
+		},
+		PostFunc: func(res CallbackResult) {
+			results = append(results, res)
+		},
+		PostFuncOnPreError: true,
+		SyncPostFunc:       true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "counter",
+		Operation:  "increment",
+		Func: func(input []byte) ([]byte, error) {
+			return input, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	// PostFunc has completed once Callback returns, without waiting for the router to close
+	for n, input := range []string{"1", "deny", "2"} {
+		_, _ = router.Callback(context.Background(), "default", "counter", "increment", []byte(input))
+		if len(results) != n+1 {
+			t.Fatalf("Unexpected PostFunc calls after callback %d: %d, expected: %d", n, len(results), n+1)
+		}
+		if string(results[n].Input) != input {
+			t.Errorf("Unexpected PostFunc result input: %s, expected: %s", results[n].Input, input)
+		}
+	}
+}
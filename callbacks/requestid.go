@@ -0,0 +1,27 @@
+package callbacks
+
+import (
+	"context"
+)
+
+// requestIDKey is the context key for request IDs.
+//
+// The key is an unnamed struct type with an exported field, rather than a named type, so that it is identical
+// across packages. This allows request IDs set with the engine package's WithRequestID to be read by this
+// package without either package importing the other.
+type requestIDKey = struct{ WapcToolkitRequestID struct{} }
+
+// WithRequestID returns a copy of the context carrying the provided request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by the context, if any.
+//
+// Request IDs set on the context provided to the engine's RunWithContext are propagated to the host
+// callbacks performed by the guest, and so are available to context-aware callbacks. The router also
+// provides the request ID to PreFunc and PostFunc via CallbackRequest and CallbackResult.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
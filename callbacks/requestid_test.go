@@ -0,0 +1,64 @@
+package callbacks
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var mu sync.Mutex
+	var preFuncID, postFuncID, callbackID string
+	router, err := New(RouterConfig{
+		PreFunc: func(rq CallbackRequest) ([]byte, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			preFuncID = rq.RequestID
+			return nil, nil
+		},
+		PostFunc: func(res CallbackResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			postFuncID = res.RequestID
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "request",
+		Operation:  "id",
+		CtxFunc: func(ctx context.Context, input []byte) ([]byte, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			callbackID, _ = RequestIDFromContext(ctx)
+			return input, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	// Set the request ID using the key convention shared with the engine package
+	ctx := context.WithValue(context.Background(), struct{ WapcToolkitRequestID struct{} }{}, "request-1")
+	if _, err := router.Callback(ctx, "default", "request", "id", []byte("")); err != nil {
+		t.Fatalf("Unexpected error calling callback: %s", err)
+	}
+
+	// Wait for PostFunc to complete
+	router.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for name, id := range map[string]string{"PreFunc": preFuncID, "PostFunc": postFuncID, "Callback": callbackID} {
+		if id != "request-1" {
+			t.Errorf("Unexpected %s request ID: %q, expected: request-1", name, id)
+		}
+	}
+
+	if id, ok := RequestIDFromContext(WithRequestID(context.Background(), "request-2")); !ok || id != "request-2" {
+		t.Errorf("Unexpected request ID: %q, expected: request-2", id)
+	}
+}
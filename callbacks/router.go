@@ -47,10 +47,13 @@ Usage:
 package callbacks
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -69,6 +72,16 @@ var (
 
 	// ErrCallbackExists is returned when the callback already exists.
 	ErrCallbackExists = errors.New("callback already exists")
+
+	// ErrInvalidRouterConfig is returned when the router configuration is invalid.
+	ErrInvalidRouterConfig = errors.New("invalid router config")
+
+	// ErrOverloaded is returned when the router rejects a callback because the router's concurrency
+	// limit has been reached.
+	//
+	// The router will not execute any PreFunc or PostFunc functions if the callback is rejected. The
+	// returned error is an *OverloadedError, providing a retry-after hint.
+	ErrOverloaded = errors.New("router overloaded")
 )
 
 // RouterConfig is a configuration struct used to create a new Router instance.
@@ -84,10 +97,29 @@ type RouterConfig struct {
 	// response payload to the caller and abandon any attempt to call the registered
 	// callback function.
 	//
+	// If the PreFunc function returns a non-nil payload without an error, the payload replaces the
+	// callback input. This allows PreFunc to transform the guest-supplied input, such as by decrypting or
+	// decompressing it. The transformed input is provided to PreFuncWithContext, the callback function,
+	// and PostFunc via CallbackResult Input; it is also used for IdempotencyKeyFunc and ServeStaleOnError.
+	// Return a nil payload to leave the input unchanged.
+	//
 	// If a callback execution is for an unknown function, the router will return
-	// a not found error and not execute the PreFunc function.
+	// a not found error and not execute the PreFunc function. Callbacks registered with
+	// SkipPreFunc are also exempt from the PreFunc function.
 	PreFunc func(CallbackRequest) ([]byte, error)
 
+	// PreFuncWithContext is a user-defined function registered to a router instance and called
+	// before callback function execution, after any PreFunc function defined.
+	//
+	// Unlike PreFunc, PreFuncWithContext receives the context provided to the router and returns
+	// a context that is passed to the callback's CtxFunc. This enables PreFuncWithContext to attach
+	// request-scoped values, such as an authenticated user, for the callback to use. If the returned
+	// context is nil, the original context is passed to the callback.
+	//
+	// If the PreFuncWithContext function returns an error, the router will return the error and
+	// response payload to the caller and abandon any attempt to call the registered callback function.
+	PreFuncWithContext func(context.Context, CallbackRequest) (context.Context, []byte, error)
+
 	// PostFunc is a user-defined function registered to a router instance and called after
 	// callback function execution.
 	//
@@ -96,76 +128,521 @@ type RouterConfig struct {
 	// Users can use PostFunc for logging, metrics, or any post-callback validations.
 	//
 	// If a callback execution is for an unknown function, the router will return a not found
-	// error and not execute the PostFunc function.
+	// error and not execute the PostFunc function. Callbacks registered with SkipPostFunc are also
+	// exempt from the PostFunc function.
+	//
+	// PostFunc is executed asynchronously by a fixed pool of worker goroutines reading from a
+	// bounded queue; see PostFuncQueueSize, PostFuncWorkers, and PostFuncQueuePolicy. To execute
+	// PostFunc before Callback returns, see SyncPostFunc.
 	PostFunc func(CallbackResult)
+
+	// SyncPostFunc, when enabled, executes PostFunc inline before Callback returns rather than via the
+	// PostFunc queue. This guarantees PostFunc has completed, such as for metrics recorded before the
+	// response is returned, or for deterministic test assertions.
+	//
+	// The time taken by PostFunc is added to the latency of every callback, and so to the guest
+	// invocation that triggered it. SyncPostFunc cannot be used with the PostFunc queue settings.
+	SyncPostFunc bool
+
+	// PostFuncQueueSize is the number of CallbackResults that can be queued waiting for PostFunc
+	// execution. If PostFuncQueueSize is not provided, DefaultPostFuncQueueSize will be used.
+	PostFuncQueueSize int
+
+	// PostFuncWorkers is the number of worker goroutines executing PostFunc. If PostFuncWorkers is
+	// not provided, DefaultPostFuncWorkers will be used.
+	PostFuncWorkers int
+
+	// PostFuncQueuePolicy determines how the router behaves when the PostFunc queue is full. By
+	// default, QueueDropNewest is used, and new results are discarded and counted; see PostFuncDropped.
+	// QueueDropOldest discards queued results instead. With QueueBlock, callbacks wait for room in the
+	// queue, so a slow PostFunc slows down callback execution.
+	PostFuncQueuePolicy QueuePolicy
+
+	// OnError is a user-defined function registered to a router instance and called whenever
+	// Callback is about to return a non-nil error.
+	//
+	// Unlike PostFunc, which only runs for registered callbacks, OnError observes every error
+	// returned by the router, including not found errors, canceled contexts, PreFunc errors,
+	// and errors returned by the callback function. This provides a single place for error
+	// telemetry.
+	//
+	// OnError is called synchronously before the error is returned to the caller.
+	OnError func(CallbackRequest, error)
+
+	// OnMiss is a user-defined function registered to a router instance and called when Callback
+	// is executed for an unregistered namespace, capability, and operation.
+	//
+	// This function enables lazy registration of callbacks the host can resolve dynamically, such
+	// as from a service registry. If OnMiss returns true, the returned CallbackConfig is registered
+	// with the router and the callback request proceeds as if the callback had been registered
+	// beforehand. If OnMiss returns false, the router returns a not found error.
+	OnMiss func(namespace, capability, operation string) (CallbackConfig, bool)
+
+	// OnResolve is a user-defined function registered to a router instance and called when Callback
+	// resolves a request to a WildcardOperation callback rather than an exact match. It is provided the
+	// requested key and the matched key, both formatted as namespace:capability:operation, giving
+	// operators visibility into how catch-all routes are used.
+	//
+	// OnResolve is called synchronously before the callback is executed; it should not block.
+	OnResolve func(requested, matched string)
+
+	// DefaultFunc is a user-defined function registered to a router instance and called for callback
+	// requests that match no registered callback, including after any OnMiss resolution, rather than
+	// returning a not found error. The CallbackRequest carries the requested namespace, capability, and
+	// operation, allowing the host to log or dynamically dispatch unknown callbacks.
+	//
+	// DefaultFunc is executed as a callback would be, with PreFunc and PostFunc being called as normal.
+	DefaultFunc func(CallbackRequest) ([]byte, error)
+
+	// SpanFunc is a user-defined function registered to a router instance and called to start a tracing
+	// span, such as an OpenTelemetry span, for every callback request, including requests that are not found
+	// or rejected as overloaded. It is called before the callback is looked up with the CallbackRequest,
+	// whose namespace, capability, and operation suit span attributes. The returned context, if not nil,
+	// carries the span context and is provided to PreFuncWithContext, the callback function, and PostFunc.
+	// The returned function, if not nil, is called to end the span with the error returned to the caller,
+	// just before the router returns.
+	//
+	// Unless SyncPostFunc is enabled, PostFunc runs asynchronously and may complete after the span has ended.
+	//
+	// Defining tracing as a function keeps the router free of any tracing dependency. If SpanFunc is not
+	// provided, no spans are started.
+	SpanFunc func(ctx context.Context, req CallbackRequest) (context.Context, func(error))
+
+	// MaxConcurrency is the maximum number of callbacks the router will execute concurrently. Callbacks
+	// exceeding the limit are not queued; they are rejected immediately with ErrOverloaded.
+	//
+	// If MaxConcurrency is zero, the number of concurrent callbacks is unlimited.
+	MaxConcurrency int
+
+	// ReservedConcurrency is the portion of MaxConcurrency reserved for high-priority callbacks, those
+	// registered with a Priority greater than zero.
+	//
+	// Admission works as follows: a callback with a Priority of zero or less is admitted only while fewer
+	// than MaxConcurrency minus ReservedConcurrency callbacks are executing. A high-priority callback is
+	// admitted while fewer than MaxConcurrency callbacks are executing. As load increases, low-priority
+	// callbacks are therefore shed first, leaving reserved capacity for high-priority callbacks such as
+	// control-plane operations.
+	//
+	// ReservedConcurrency must be less than MaxConcurrency.
+	ReservedConcurrency int
+
+	// RetryAfter is the minimum retry-after hint provided with ErrOverloaded, see OverloadedError for how
+	// the hint is derived. If RetryAfter is zero, DefaultRetryAfter is used.
+	RetryAfter time.Duration
+
+	// CopyInput, when enabled, copies the callback input before it is provided to PreFunc, the callback
+	// function, and PostFunc. The input provided by the waPC engine references guest memory, which may be
+	// modified or reused once the callback returns; callbacks that retain the input, such as by
+	// processing it asynchronously, should enable CopyInput.
+	//
+	// By default, the input is not copied.
+	CopyInput bool
+
+	// CopyOutput, when enabled, copies the output returned by the callback function before it is provided
+	// to PostFunc and returned to the caller. This protects callbacks that return slices they continue
+	// to modify, such as shared buffers.
+	//
+	// By default, the output is not copied.
+	CopyOutput bool
+
+	// PostFuncOnPreError, when enabled, calls PostFunc for requests rejected by PreFunc or
+	// PreFuncWithContext. The CallbackResult carries the PreFunc error and no output. This allows
+	// middleware, such as audit logs, to observe requests that never reach the callback function.
+	//
+	// By default, PostFunc is not called when PreFunc returns an error.
+	PostFuncOnPreError bool
+
+	// Codec is the serialization format used to decode inputs and encode outputs of callbacks registered
+	// with RegisterTyped. This allows teams standardizing on formats such as msgpack or protobuf to provide
+	// their own implementation.
+	//
+	// If Codec is not provided, JSONCodec will be used.
+	Codec Codec
+}
+
+// Validate validates the router configuration as a whole. It returns an error describing the first
+// invalid or inconsistent configuration value found.
+func (cfg RouterConfig) Validate() error {
+	// Verify PostFunc queue settings
+	if cfg.PostFuncQueueSize < 0 {
+		return fmt.Errorf("%w: PostFuncQueueSize cannot be negative", ErrInvalidRouterConfig)
+	}
+	if cfg.PostFuncWorkers < 0 {
+		return fmt.Errorf("%w: PostFuncWorkers cannot be negative", ErrInvalidRouterConfig)
+	}
+
+	switch cfg.PostFuncQueuePolicy {
+	case QueueDropNewest, QueueDropOldest, QueueBlock:
+	default:
+		return fmt.Errorf("%w: unknown PostFuncQueuePolicy %d", ErrInvalidRouterConfig, cfg.PostFuncQueuePolicy)
+	}
+
+	// Verify concurrency settings
+	if cfg.MaxConcurrency < 0 {
+		return fmt.Errorf("%w: MaxConcurrency cannot be negative", ErrInvalidRouterConfig)
+	}
+	if cfg.ReservedConcurrency < 0 {
+		return fmt.Errorf("%w: ReservedConcurrency cannot be negative", ErrInvalidRouterConfig)
+	}
+	if cfg.ReservedConcurrency > 0 && cfg.ReservedConcurrency >= cfg.MaxConcurrency {
+		return fmt.Errorf("%w: ReservedConcurrency must be less than MaxConcurrency", ErrInvalidRouterConfig)
+	}
+	if cfg.RetryAfter < 0 {
+		return fmt.Errorf("%w: RetryAfter cannot be negative", ErrInvalidRouterConfig)
+	}
+
+	// Verify PostFunc queue settings are not provided without a PostFunc
+	queueConfigured := cfg.PostFuncQueueSize != 0 || cfg.PostFuncWorkers != 0 || cfg.PostFuncQueuePolicy != QueueDropNewest
+	if cfg.PostFunc == nil && queueConfigured {
+		return fmt.Errorf("%w: PostFunc queue settings provided without a PostFunc", ErrInvalidRouterConfig)
+	}
+	if cfg.SyncPostFunc && queueConfigured {
+		return fmt.Errorf("%w: PostFunc queue settings cannot be used with SyncPostFunc", ErrInvalidRouterConfig)
+	}
+
+	return nil
 }
 
 // Router is a callback router that enables users to register callback functions and execute
 // them by providing a namespace, capability, and operation.
 type Router struct {
+	// RWMutex serializes changes to the registered callbacks. Callback lookups do not take the lock.
 	sync.RWMutex
 
-	// callbacks is a map of registered callbacks. The key is a string of the form
+	// callbacks is an immutable map of registered callbacks. The key is a string of the form
 	// namespace:capability:operation.
-	callbacks map[string]*Callback
+	//
+	// The map is copy-on-write: changes create a new map which replaces the current map, allowing
+	// lookups to read the map without locking.
+	callbacks atomic.Pointer[map[string]*Callback]
+
+	// aliases maps alias keys to the routes they target. As with callbacks, the map is copy-on-write.
+	aliases atomic.Pointer[map[string]alias]
+
+	// middleware maps namespaces to the middleware registered via UseNamespace. As with callbacks, the map
+	// is copy-on-write.
+	middleware atomic.Pointer[map[string][]namespaceMiddleware]
 
 	// preFunc is a user-defined function registered to a router instance and called before
 	// callback function execution. See RouterConfig for more details.
 	preFunc func(CallbackRequest) ([]byte, error)
 
+	// preFuncWithContext is a user-defined function registered to a router instance and called before
+	// callback function execution. See RouterConfig for more details.
+	preFuncWithContext func(context.Context, CallbackRequest) (context.Context, []byte, error)
+
 	// postFunc is a user-defined function registered to a router instance and called after
 	// callback function execution. See RouterConfig for more details.
 	postFunc func(CallbackResult)
+
+	// postQueue is the bounded queue used to execute postFunc asynchronously. It is nil when postFunc is
+	// executed synchronously.
+	postQueue *postFuncQueue
+
+	// onError is a user-defined function registered to a router instance and called whenever
+	// Callback returns an error. See RouterConfig for more details.
+	onError func(CallbackRequest, error)
+
+	// onMiss is a user-defined function registered to a router instance and called when a callback
+	// is not found. See RouterConfig for more details.
+	onMiss func(namespace, capability, operation string) (CallbackConfig, bool)
+
+	// onResolve is a user-defined function called when a request resolves to a wildcard callback.
+	onResolve func(requested, matched string)
+
+	// defaultFunc is a user-defined function called for callback requests that match no registered
+	// callback. See RouterConfig for more details.
+	defaultFunc func(CallbackRequest) ([]byte, error)
+
+	// spanFunc is a user-defined function called to start a tracing span for each admitted callback. See
+	// RouterConfig for more details.
+	spanFunc func(ctx context.Context, req CallbackRequest) (context.Context, func(error))
+
+	// maxConcurrency is the maximum number of concurrently executing callbacks, zero means unlimited.
+	maxConcurrency int64
+
+	// reservedConcurrency is the portion of maxConcurrency reserved for high-priority callbacks.
+	reservedConcurrency int64
+
+	// inFlight is the number of callbacks currently admitted for execution.
+	inFlight atomic.Int64
+
+	// retryAfter is the minimum retry-after hint for overloaded responses. See RouterConfig for more details.
+	retryAfter time.Duration
+
+	// copyInput enables copying of callback inputs. See RouterConfig for more details.
+	copyInput bool
+
+	// copyOutput enables copying of callback outputs. See RouterConfig for more details.
+	copyOutput bool
+
+	// postFuncOnPreError enables calling postFunc for requests rejected by preFunc. See RouterConfig for
+	// more details.
+	postFuncOnPreError bool
+
+	// codec is the serialization format used by callbacks registered with RegisterTyped.
+	codec Codec
 }
 
 // New creates a new Router instance.
+//
+// The provided configuration is validated with RouterConfig.Validate; if it is invalid, the returned
+// error describes the problem.
 func New(cfg RouterConfig) (*Router, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
 	r := &Router{
-		callbacks: make(map[string]*Callback),
-		preFunc:   cfg.PreFunc,
-		postFunc:  cfg.PostFunc,
+		preFunc:             cfg.PreFunc,
+		preFuncWithContext:  cfg.PreFuncWithContext,
+		postFunc:            cfg.PostFunc,
+		onError:             cfg.OnError,
+		onMiss:              cfg.OnMiss,
+		onResolve:           cfg.OnResolve,
+		defaultFunc:         cfg.DefaultFunc,
+		spanFunc:            cfg.SpanFunc,
+		maxConcurrency:      int64(cfg.MaxConcurrency),
+		reservedConcurrency: int64(cfg.ReservedConcurrency),
+		retryAfter:          cfg.RetryAfter,
+		copyInput:           cfg.CopyInput,
+		copyOutput:          cfg.CopyOutput,
+		postFuncOnPreError:  cfg.PostFuncOnPreError,
+		codec:               cfg.Codec,
+	}
+	if r.codec == nil {
+		r.codec = JSONCodec{}
 	}
+	if r.retryAfter == 0 {
+		r.retryAfter = DefaultRetryAfter
+	}
+	r.callbacks.Store(&map[string]*Callback{})
+	r.aliases.Store(&map[string]alias{})
+	r.middleware.Store(&map[string][]namespaceMiddleware{})
+
+	if r.postFunc != nil && !cfg.SyncPostFunc {
+		r.postQueue = newPostFuncQueue(r.postFunc, cfg.PostFuncQueueSize, cfg.PostFuncWorkers, cfg.PostFuncQueuePolicy)
+	}
+
 	return r, nil
 }
 
-// Close clears the router's callback map and shuts down the router.
+// Close clears the router's callbacks, aliases, and namespace middleware, and shuts down the router.
+//
+// Close waits for any queued PostFunc calls to complete. Results of callbacks executed after
+// Close are not provided to PostFunc.
 func (r *Router) Close() {
 	// Lock router
 	r.Lock()
-	defer r.Unlock()
 
-	// Clear callbacks map
-	r.callbacks = make(map[string]*Callback)
+	// Clear callbacks, aliases, and namespace middleware
+	callbacks := r.callbacks.Swap(&map[string]*Callback{})
+	r.aliases.Store(&map[string]alias{})
+	r.middleware.Store(&map[string][]namespaceMiddleware{})
+	r.Unlock()
+
+	// Cancel callback lifetimes
+	for _, cb := range *callbacks {
+		cb.stop()
+	}
+
+	// Stop postFunc workers
+	if r.postQueue != nil {
+		r.postQueue.close()
+	}
+}
+
+// PostFuncDropped returns the number of callback results discarded without calling PostFunc,
+// either because the PostFunc queue was full or because the router was closed.
+func (r *Router) PostFuncDropped() uint64 {
+	if r.postQueue == nil {
+		return 0
+	}
+	return r.postQueue.dropped.Load()
 }
 
 // RegisterCallback adds a callback to the router. If the callback already exists, an error
 // is returned.
+//
+// Registering a callback with the WildcardOperation operation handles every operation under the namespace
+// and capability that has no callback registered for the exact operation.
+//
+// Registration copies the router's callback map so that callback lookups remain lock-free; the
+// cost of registering a callback grows with the number of callbacks registered.
 func (r *Router) RegisterCallback(cfg CallbackConfig) error {
 	// Validate Config
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
 
+	key := fmt.Sprintf("%s:%s:%s", cfg.Namespace, cfg.Capability, cfg.Operation)
+
 	// Check if callback already exists
-	if _, err := r.Lookup(cfg.Namespace, cfg.Capability, cfg.Operation); err == nil {
+	if _, ok := r.lookup(key); ok {
 		return ErrCallbackExists
 	}
 
+	// Start the callback lifetime
+	var cancel context.CancelFunc
+	if cfg.OnRegisterCtx != nil {
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		if err := cfg.OnRegisterCtx(ctx); err != nil {
+			cancel()
+			return err
+		}
+	}
+
 	// Lock router
 	r.Lock()
 	defer r.Unlock()
 
+	// Re-check as the callback may have been registered concurrently
+	if _, ok := r.lookup(key); ok {
+		if cancel != nil {
+			cancel()
+		}
+		return ErrCallbackExists
+	}
+
 	// Add callback to map
-	r.callbacks[fmt.Sprintf("%s:%s:%s", cfg.Namespace, cfg.Capability, cfg.Operation)] = &Callback{
-		Namespace:  cfg.Namespace,
-		Capability: cfg.Capability,
-		Operation:  cfg.Operation,
-		Func:       cfg.Func,
+	callbacks := r.clone()
+	callbacks[key] = newCallback(cfg, cancel)
+	r.callbacks.Store(&callbacks)
+
+	return nil
+}
+
+// RegisterCallbackFunc adds a callback with the provided namespace, capability, operation, and function to
+// the router, in the same way as RegisterCallback with a CallbackConfig providing only those fields. The
+// same validation is performed and the same errors are returned.
+func (r *Router) RegisterCallbackFunc(
+	namespace, capability, operation string,
+	fn func([]byte) ([]byte, error),
+) error {
+	return r.RegisterCallback(CallbackConfig{
+		Namespace:  namespace,
+		Capability: capability,
+		Operation:  operation,
+		Func:       fn,
+	})
+}
+
+// RegisterIfAbsent adds a callback to the router only if no callback is registered for its namespace,
+// capability, and operation, returning whether the callback was registered. Unlike RegisterCallback, an
+// existing callback is not an error, which suits idempotent wiring such as registering fallbacks.
+//
+// The existence check and registration are performed atomically under the router lock.
+func (r *Router) RegisterIfAbsent(cfg CallbackConfig) (bool, error) {
+	err := r.RegisterCallback(cfg)
+	if errors.Is(err, ErrCallbackExists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RegisterCallbacks adds multiple callbacks to the router with all-or-nothing semantics. Every config is
+// validated, and only if all are valid and none are already registered are the callbacks added, together
+// under a single lock. Otherwise no callback is registered, and the returned error wraps the underlying
+// error, such as ErrInvalidFunc or ErrCallbackExists, identifying the index and key of the offending config.
+//
+// OnRegisterCtx functions are called for each config before registration; if any returns an error, the
+// lifetimes already started are canceled.
+func (r *Router) RegisterCallbacks(cfgs []CallbackConfig) error {
+	keys := make([]string, len(cfgs))
+	seen := make(map[string]struct{}, len(cfgs))
+	for n, cfg := range cfgs {
+		keys[n] = fmt.Sprintf("%s:%s:%s", cfg.Namespace, cfg.Capability, cfg.Operation)
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("unable to register callback %d (%s) - %w", n, keys[n], err)
+		}
+		if _, ok := seen[keys[n]]; ok {
+			return fmt.Errorf("unable to register callback %d (%s) - %w", n, keys[n], ErrCallbackExists)
+		}
+		if _, ok := r.lookup(keys[n]); ok {
+			return fmt.Errorf("unable to register callback %d (%s) - %w", n, keys[n], ErrCallbackExists)
+		}
+		seen[keys[n]] = struct{}{}
 	}
 
+	// Start the callback lifetimes
+	cancels := make([]context.CancelFunc, len(cfgs))
+	stop := func() {
+		for _, cancel := range cancels {
+			if cancel != nil {
+				cancel()
+			}
+		}
+	}
+	for n, cfg := range cfgs {
+		if cfg.OnRegisterCtx == nil {
+			continue
+		}
+		var ctx context.Context
+		ctx, cancels[n] = context.WithCancel(context.Background())
+		if err := cfg.OnRegisterCtx(ctx); err != nil {
+			stop()
+			return fmt.Errorf("unable to register callback %d (%s) - %w", n, keys[n], err)
+		}
+	}
+
+	// Lock router
+	r.Lock()
+	defer r.Unlock()
+
+	// Re-check as callbacks may have been registered concurrently
+	for n, key := range keys {
+		if _, ok := r.lookup(key); ok {
+			stop()
+			return fmt.Errorf("unable to register callback %d (%s) - %w", n, key, ErrCallbackExists)
+		}
+	}
+
+	// Add callbacks to map
+	callbacks := r.clone()
+	for n, cfg := range cfgs {
+		callbacks[keys[n]] = newCallback(cfg, cancels[n])
+	}
+	r.callbacks.Store(&callbacks)
+
 	return nil
 }
 
+// newCallback creates the registered callback for the provided config. The cancel function ends the callback
+// lifetime started by OnRegisterCtx, if any.
+func newCallback(cfg CallbackConfig, cancel context.CancelFunc) *Callback {
+	cb := &Callback{
+		Namespace:          cfg.Namespace,
+		Capability:         cfg.Capability,
+		Operation:          cfg.Operation,
+		Func:               cfg.Func,
+		CtxFunc:            cfg.CtxFunc,
+		Priority:           cfg.Priority,
+		SkipPreFunc:        cfg.SkipPreFunc,
+		SkipPostFunc:       cfg.SkipPostFunc,
+		OnPanic:            cfg.OnPanic,
+		ServeStaleOnError:  cfg.ServeStaleOnError,
+		IdempotencyKeyFunc: cfg.IdempotencyKeyFunc,
+		IdempotencyTTL:     cfg.IdempotencyTTL,
+		Timeout:            cfg.Timeout,
+		OnRegisterCtx:      cfg.OnRegisterCtx,
+		Metadata:           copyMetadata(cfg.Metadata),
+		bytes:              &callbackBytes{},
+		latency:            &latencyHistogram{},
+		cancel:             cancel,
+	}
+	if cfg.ServeStaleOnError {
+		cb.stale = newStaleCache(DefaultStaleCacheSize)
+	}
+	if cfg.IdempotencyKeyFunc != nil {
+		cb.idempotency = newIdempotencyCache(cfg.IdempotencyTTL, DefaultIdempotencyCacheSize)
+	}
+	return cb
+}
+
 // UnregisterCallback removes a callback from the router. If the callback does not exist,
 // no error is returned.
 func (r *Router) UnregisterCallback(cfg CallbackConfig) error {
@@ -179,22 +656,141 @@ func (r *Router) UnregisterCallback(cfg CallbackConfig) error {
 	defer r.Unlock()
 
 	// Remove callback from map
-	delete(r.callbacks, fmt.Sprintf("%s:%s:%s", cfg.Namespace, cfg.Capability, cfg.Operation))
+	key := fmt.Sprintf("%s:%s:%s", cfg.Namespace, cfg.Capability, cfg.Operation)
+	cb, ok := r.lookup(key)
+	if !ok {
+		return nil
+	}
+
+	callbacks := r.clone()
+	delete(callbacks, key)
+	r.callbacks.Store(&callbacks)
+
+	// Cancel callback lifetime
+	cb.stop()
 
 	return nil
 }
 
+// UpdateCallback atomically replaces the function of a registered callback with the Func and CtxFunc of
+// the provided config, enabling callback implementations to be hot-swapped without a window where the
+// callback is missing. If the callback is not registered, ErrNotFound is returned.
+//
+// Other settings of the registered callback are retained, as are its statistics, caches, and lifetime.
+// Callback executions already in progress complete with the previous function.
+func (r *Router) UpdateCallback(cfg CallbackConfig) error {
+	// Validate Config
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s:%s:%s", cfg.Namespace, cfg.Capability, cfg.Operation)
+
+	// Lock router
+	r.Lock()
+	defer r.Unlock()
+
+	cb, ok := r.lookup(key)
+	if !ok {
+		return ErrNotFound
+	}
+
+	// Replace callback within map
+	callbacks := r.clone()
+	updated := cb.moved()
+	updated.Func = cfg.Func
+	updated.CtxFunc = cfg.CtxFunc
+	callbacks[key] = updated
+	r.callbacks.Store(&callbacks)
+
+	return nil
+}
+
+// RenameNamespace atomically moves every callback registered to the old namespace to the new namespace,
+// returning the number of callbacks moved. Callbacks are never missing from the router during the
+// rename; lookups see either the old or the new namespace.
+//
+// If any moved callback would collide with a callback already registered to the new namespace, no
+// callbacks are moved and ErrCallbackExists is returned.
+func (r *Router) RenameNamespace(oldNamespace, newNamespace string) (int, error) {
+	if oldNamespace == "" || newNamespace == "" {
+		return 0, ErrInvalidNamespace
+	}
+	if oldNamespace == newNamespace {
+		return 0, nil
+	}
+
+	// Lock router
+	r.Lock()
+	defer r.Unlock()
+
+	callbacks := r.clone()
+
+	// Find callbacks to move, checking for collisions
+	moved := make([]*Callback, 0)
+	for _, cb := range callbacks {
+		if cb.Namespace != oldNamespace {
+			continue
+		}
+		key := fmt.Sprintf("%s:%s:%s", newNamespace, cb.Capability, cb.Operation)
+		if _, ok := callbacks[key]; ok {
+			return 0, fmt.Errorf("%w: %s", ErrCallbackExists, key)
+		}
+		moved = append(moved, cb)
+	}
+
+	// Move callbacks
+	for _, cb := range moved {
+		delete(callbacks, fmt.Sprintf("%s:%s:%s", cb.Namespace, cb.Capability, cb.Operation))
+		renamed := cb.moved()
+		renamed.Namespace = newNamespace
+		callbacks[fmt.Sprintf("%s:%s:%s", renamed.Namespace, renamed.Capability, renamed.Operation)] = renamed
+	}
+	r.callbacks.Store(&callbacks)
+
+	return len(moved), nil
+}
+
 // Callback executes callbacks registered to the router. It will identify the Callback by
 // the user-provided Namespace, Capability, and Operation and execute the associated function,
 // passing the provided input to the callback function.
 //
 // If any PreFunc functions are defined, Callback will execute them before executing the identified Callback.
 //
-// After execution, the router will call any PostFunc functions defined.
+// After execution, the router will call any PostFunc functions defined. If an error is returned,
+// the router will call any OnError function defined.
 func (r *Router) Callback(ctx context.Context, namespace, capability, operation string, input []byte) ([]byte, error) {
-	// Validate Context
-	if ctx.Err() != nil {
-		return nil, ErrCanceled
+	rsp, _, err := r.execute(ctx, namespace, capability, operation, input)
+	return rsp, err
+}
+
+// CallbackWithMatch executes callbacks registered to the router in the same way as Callback but also returns
+// a copy of the matched Callback, including its metadata, as resolved by the router.
+//
+// This enables audit logging of exactly which registered callback handled a request. If no callback
+// was matched, an empty Callback is returned.
+func (r *Router) CallbackWithMatch(
+	ctx context.Context,
+	namespace, capability, operation string,
+	input []byte,
+) ([]byte, Callback, error) {
+	rsp, cb, err := r.execute(ctx, namespace, capability, operation, input)
+	if cb == nil {
+		return rsp, Callback{}, err
+	}
+	return rsp, cb.copy(), err
+}
+
+// execute creates a callback request, executes it, and calls any OnError function defined if an
+// error is returned.
+func (r *Router) execute(
+	ctx context.Context,
+	namespace, capability, operation string,
+	input []byte,
+) ([]byte, *Callback, error) {
+	// Copy input
+	if r.copyInput {
+		input = bytes.Clone(input)
 	}
 
 	// Create callback request
@@ -203,74 +799,361 @@ func (r *Router) Callback(ctx context.Context, namespace, capability, operation
 		Capability: capability,
 		Operation:  operation,
 		Input:      input,
+		Context:    ctx,
 		StartTime:  time.Now(),
 	}
+	req.RequestID, _ = RequestIDFromContext(ctx)
+
+	// Execute callback
+	rsp, cb, err := r.callback(ctx, req)
+	if err != nil && r.onError != nil {
+		r.onError(req, err)
+	}
+
+	return rsp, cb, err
+}
+
+// callback dispatches the provided request within any span started by SpanFunc. The matched callback is
+// returned, or nil if no callback was matched.
+func (r *Router) callback(ctx context.Context, req CallbackRequest) ([]byte, *Callback, error) {
+	if r.spanFunc == nil {
+		return r.dispatch(ctx, req)
+	}
 
-	// Create lookup key
-	key := fmt.Sprintf("%s:%s:%s", namespace, capability, operation)
+	// Start a span covering the lookup, admission, and execution of the callback
+	spanCtx, end := r.spanFunc(ctx, req)
+	if spanCtx != nil {
+		ctx = spanCtx
+		req.Context = ctx
+	}
+	rsp, cb, err := r.dispatch(ctx, req)
+	if end != nil {
+		end(err)
+	}
+	return rsp, cb, err
+}
 
-	// Read lock router
-	r.RLock()
-	defer r.RUnlock()
+// dispatch looks up, admits, and executes the callback for the provided request. The matched callback is
+// returned, or nil if no callback was matched.
+func (r *Router) dispatch(ctx context.Context, req CallbackRequest) ([]byte, *Callback, error) {
+	// Validate Context
+	if ctx.Err() != nil {
+		return nil, nil, ErrCanceled
+	}
 
-	// Lookup callback
-	if cb, ok := r.callbacks[key]; ok {
-		// Call preFunc
-		if r.preFunc != nil {
-			rsp, err := r.preFunc(req)
-			if err != nil {
-				// return error to caller
-				return rsp, err
+	// Lookup callback, following any aliases and falling back to any wildcard callback
+	namespace, capability, operation := r.resolve(req.Namespace, req.Capability, req.Operation)
+	cb, ok := r.match(namespace, capability, operation)
+	if !ok && r.onMiss != nil {
+		// Resolve and register callback on the fly
+		if cfg, found := r.onMiss(req.Namespace, req.Capability, req.Operation); found {
+			err := r.RegisterCallback(cfg)
+			if err != nil && !errors.Is(err, ErrCallbackExists) {
+				return nil, nil, err
 			}
+			namespace, capability, operation = req.Namespace, req.Capability, req.Operation
+			cb, ok = r.match(namespace, capability, operation)
+		}
+	}
+	if !ok && r.defaultFunc != nil {
+		// Fall back to the default function
+		cb, ok = r.fallback(req), true
+	}
+	if !ok {
+		// Return not found error
+		return nil, nil, ErrNotFound
+	}
+
+	// Provide the requested operation to wildcard callbacks
+	if cb.Operation == WildcardOperation {
+		ctx = context.WithValue(ctx, operationKey{}, operation)
+		if r.onResolve != nil && operation != WildcardOperation {
+			r.onResolve(
+				fmt.Sprintf("%s:%s:%s", req.Namespace, req.Capability, req.Operation),
+				fmt.Sprintf("%s:%s:%s", cb.Namespace, cb.Capability, cb.Operation),
+			)
+		}
+	}
+
+	// Admit callback for execution
+	if !r.admit(cb.Priority) {
+		return nil, cb, r.overloaded(cb)
+	}
+	defer r.inFlight.Add(-1)
+
+	rsp, err := r.invoke(ctx, req, cb)
+	return rsp, cb, err
+}
+
+// invoke executes the admitted callback for the provided request, calling any PreFunc and PostFunc functions
+// defined.
+func (r *Router) invoke(ctx context.Context, req CallbackRequest, cb *Callback) ([]byte, error) {
+	// Call preFunc
+	if r.preFunc != nil && !cb.SkipPreFunc {
+		rsp, err := r.preFunc(req)
+		if err != nil {
+			r.preError(req, cb, err)
+			// return error to caller
+			return rsp, err
+		}
+		// Replace the input with any transformed input
+		if rsp != nil {
+			req.Input = rsp
+		}
+	}
+
+	// Call preFuncWithContext
+	if r.preFuncWithContext != nil && !cb.SkipPreFunc {
+		preCtx, rsp, err := r.preFuncWithContext(ctx, req)
+		if err != nil {
+			r.preError(req, cb, err)
+			// return error to caller
+			return rsp, err
+		}
+		if preCtx != nil {
+			ctx = preCtx
+			req.Context = ctx
 		}
+	}
 
-		// Call callback func
-		cbRsp, err := cb.Func(input)
+	// Call namespace pre functions, replacing the input with any transformed input
+	if !cb.SkipPreFunc {
+		var rsp []byte
+		var err error
+		req, rsp, err = r.namespacePre(cb.Namespace, req)
+		if err != nil {
+			r.preError(req, cb, err)
+			// return error to caller
+			return rsp, err
+		}
+	}
 
-		// Call postFunc
+	// Call callback func, deduplicating executions by idempotency key
+	call := func() ([]byte, error) {
+		start := time.Now()
+		rsp, err := cb.run(ctx, req.Input)
+		cb.latency.observe(time.Since(start))
+		cb.bytes.in.Add(uint64(len(req.Input)))
+		cb.bytes.out.Add(uint64(len(rsp)))
+		return rsp, err
+	}
+	var cbRsp []byte
+	var err error
+	var duplicate bool
+	if key := cb.idempotencyKey(req.Input); key != "" {
+		cbRsp, duplicate, err = cb.idempotency.do(ctx, key, call)
+	} else {
+		cbRsp, err = call()
+	}
+
+	// Copy output
+	if r.copyOutput {
+		cbRsp = bytes.Clone(cbRsp)
+	}
+
+	// Serve stale response on error
+	var stale bool
+	if cb.stale != nil {
+		if err == nil {
+			cb.stale.put(req.Input, cbRsp)
+		} else if rsp, ok := cb.stale.get(req.Input); ok {
+			cbRsp, stale = rsp, true
+		}
+	}
+
+	// Call namespace post functions and postFunc
+	if (r.postFunc != nil || r.hasNamespacePost(cb.Namespace)) && !cb.SkipPostFunc {
+		res := CallbackResult{
+			Namespace:  req.Namespace,
+			Capability: req.Capability,
+			Operation:  req.Operation,
+			Input:      req.Input,
+			Output:     cbRsp,
+			Err:        err,
+			Stale:      stale,
+			Duplicate:  duplicate,
+			RequestID:  req.RequestID,
+			Context:    context.WithoutCancel(ctx),
+			StartTime:  req.StartTime,
+			EndTime:    time.Now(),
+		}
+		r.namespacePost(cb.Namespace, res)
 		if r.postFunc != nil {
-			go r.postFunc(CallbackResult{
-				Namespace:  namespace,
-				Capability: capability,
-				Operation:  operation,
-				Input:      input,
-				Output:     cbRsp,
-				Err:        err,
-				StartTime:  req.StartTime,
-				EndTime:    time.Now(),
-			})
+			r.post(res)
 		}
+	}
 
-		// Return output and error
-		return cbRsp, err
+	// Return output and error
+	if stale {
+		return cbRsp, nil
 	}
+	return cbRsp, err
+}
 
-	// Return not found error
-	return nil, ErrNotFound
+// fallback returns an unregistered callback for the provided request that executes defaultFunc.
+func (r *Router) fallback(req CallbackRequest) *Callback {
+	return &Callback{
+		Namespace:  req.Namespace,
+		Capability: req.Capability,
+		Operation:  req.Operation,
+		CtxFunc: func(_ context.Context, input []byte) ([]byte, error) {
+			req.Input = input
+			return r.defaultFunc(req)
+		},
+		bytes:   &callbackBytes{},
+		latency: &latencyHistogram{},
+	}
 }
 
-// Lookup returns a copy of the callback function registered to the router.
-// If the callback function is not found, the function returns ErrNotFound.
-func (r *Router) Lookup(namespace, capability, operation string) (Callback, error) {
-	// Create lookup key
-	key := fmt.Sprintf("%s:%s:%s", namespace, capability, operation)
+// post executes postFunc with the callback result, either inline or via the postFunc queue.
+func (r *Router) post(res CallbackResult) {
+	if r.postQueue == nil {
+		r.postFunc(res)
+		return
+	}
+	r.postQueue.dispatch(res)
+}
+
+// preError calls any namespace post functions and postFunc for a request rejected by a pre function, if
+// enabled.
+func (r *Router) preError(req CallbackRequest, cb *Callback, err error) {
+	if !r.postFuncOnPreError || cb.SkipPostFunc {
+		return
+	}
 
-	// Read lock router
-	r.RLock()
-	defer r.RUnlock()
+	res := CallbackResult{
+		Namespace:  req.Namespace,
+		Capability: req.Capability,
+		Operation:  req.Operation,
+		Input:      req.Input,
+		Err:        err,
+		RequestID:  req.RequestID,
+		Context:    context.WithoutCancel(req.Context),
+		StartTime:  req.StartTime,
+		EndTime:    time.Now(),
+	}
+	r.namespacePost(cb.Namespace, res)
+	if r.postFunc != nil {
+		r.post(res)
+	}
+}
 
-	// Lookup callback
-	if cb, ok := r.callbacks[key]; ok {
+// admit reserves an execution slot for a callback with the provided priority. It returns false if the
+// router's concurrency limit for the priority has been reached. Admitted callbacks must release their
+// slot by decrementing inFlight.
+func (r *Router) admit(priority int) bool {
+	n := r.inFlight.Add(1)
+	if r.maxConcurrency == 0 {
+		return true
+	}
+
+	limit := r.maxConcurrency
+	if priority <= 0 {
+		limit -= r.reservedConcurrency
+	}
+
+	if n > limit {
+		r.inFlight.Add(-1)
+		return false
+	}
+	return true
+}
+
+// lookup returns the callback registered with the provided key. Lookups do not lock the router.
+func (r *Router) lookup(key string) (*Callback, bool) {
+	cb, ok := (*r.callbacks.Load())[key]
+	return cb, ok
+}
+
+// clone returns a copy of the registered callbacks map. Callers must hold the router lock and store the
+// modified copy to apply changes.
+func (r *Router) clone() map[string]*Callback {
+	current := *r.callbacks.Load()
+	callbacks := make(map[string]*Callback, len(current)+1)
+	for k, v := range current {
+		callbacks[k] = v
+	}
+	return callbacks
+}
+
+// Bytes returns the cumulative number of input bytes provided to, and output bytes returned by, the
+// callback registered with the provided namespace, capability, and operation. This enables usage-based
+// metering of host capabilities.
+//
+// Counts are kept from the time the callback is registered, including across RenameNamespace; they are
+// discarded when the callback is unregistered. If the callback is not found, zero is returned for both.
+func (r *Router) Bytes(namespace, capability, operation string) (in, out uint64) {
+	cb, ok := r.lookup(fmt.Sprintf("%s:%s:%s", namespace, capability, operation))
+	if !ok {
+		return 0, 0
+	}
+	return cb.bytes.in.Load(), cb.bytes.out.Load()
+}
+
+// Lookup returns a copy of the callback function registered to the router. As with Callback, if no callback
+// is registered for the exact operation, any WildcardOperation callback registered for the namespace and
+// capability is returned. If the callback function is not found, the function returns ErrNotFound.
+func (r *Router) Lookup(namespace, capability, operation string) (Callback, error) {
+	// Lookup callback, following any aliases
+	if cb, ok := r.match(r.resolve(namespace, capability, operation)); ok {
 		// Create copy of callback
-		cp := Callback{
-			Namespace:  cb.Namespace,
-			Capability: cb.Capability,
-			Operation:  cb.Operation,
-			Func:       cb.Func,
-		}
-		return cp, nil
+		return cb.copy(), nil
 	}
 
 	// Return not found error
 	return Callback{}, ErrNotFound
 }
+
+// Operations returns the sorted names of the operations registered to the router under the provided
+// namespace and capability. This enables self-describing host-call APIs, where guests enumerate the
+// operations a capability exposes before invoking them.
+//
+// If no operations are registered, an empty slice is returned.
+func (r *Router) Operations(namespace, capability string) []string {
+	ops := make([]string, 0)
+	for _, cb := range *r.callbacks.Load() {
+		if cb.Namespace == namespace && cb.Capability == capability {
+			ops = append(ops, cb.Operation)
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// List returns a copy of every callback registered to the router, sorted by namespace, capability, and
+// operation. As with Lookup, callers cannot modify the registered callbacks via the returned copies.
+//
+// List reads a consistent snapshot of the registered callbacks and is safe to call concurrently with
+// registration. If no callbacks are registered, an empty slice is returned.
+func (r *Router) List() []Callback {
+	callbacks := *r.callbacks.Load()
+
+	keys := make([]string, 0, len(callbacks))
+	for key := range callbacks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	list := make([]Callback, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, callbacks[key].copy())
+	}
+	return list
+}
+
+// Count returns the number of callbacks registered to the router. Count reads a consistent snapshot of the
+// registered callbacks and is safe to call concurrently with registration.
+func (r *Router) Count() int {
+	return len(*r.callbacks.Load())
+}
+
+// CountByNamespace returns the number of callbacks registered to the router under each namespace. As with
+// Count, the counts are read from a consistent snapshot. If no callbacks are registered, an empty map is
+// returned.
+func (r *Router) CountByNamespace() map[string]int {
+	counts := make(map[string]int)
+	for _, cb := range *r.callbacks.Load() {
+		counts[cb.Namespace]++
+	}
+	return counts
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"sync"
 	"testing"
 	"time"
 )
@@ -163,3 +164,78 @@ func BenchmarkRouter(b *testing.B) { //nolint:gocognit // Benchmark test using c
 		})
 	}
 }
+
+// rwMutexRouter is a baseline RWMutex guarded callback map used to compare against the router's
+// lock-free lookups.
+type rwMutexRouter struct {
+	sync.RWMutex
+	callbacks map[string]*Callback
+}
+
+func (r *rwMutexRouter) Lookup(namespace, capability, operation string) (Callback, error) {
+	key := fmt.Sprintf("%s:%s:%s", namespace, capability, operation)
+
+	r.RLock()
+	defer r.RUnlock()
+
+	if cb, ok := r.callbacks[key]; ok {
+		return cb.copy(), nil
+	}
+	return Callback{}, ErrNotFound
+}
+
+func BenchmarkRouterLookupParallel(b *testing.B) {
+	numCallbacks := 10000
+
+	router, err := New(RouterConfig{})
+	if err != nil {
+		b.Fatalf("Failed to create router: %s", err)
+	}
+	defer router.Close()
+
+	baseline := &rwMutexRouter{callbacks: make(map[string]*Callback)}
+
+	// Register callbacks
+	for i := 0; i < numCallbacks; i++ {
+		cfg := CallbackConfig{
+			Namespace:  "benchmarks",
+			Capability: "testing",
+			Operation:  fmt.Sprintf("operation-%d", i),
+			Func: func(_ []byte) ([]byte, error) {
+				return []byte{}, nil
+			},
+		}
+		if err := router.RegisterCallback(cfg); err != nil {
+			b.Fatalf("Failed to register callback: %s", err)
+		}
+		baseline.callbacks[fmt.Sprintf("%s:%s:%s", cfg.Namespace, cfg.Capability, cfg.Operation)] = &Callback{
+			Namespace:  cfg.Namespace,
+			Capability: cfg.Capability,
+			Operation:  cfg.Operation,
+			Func:       cfg.Func,
+		}
+	}
+
+	lookups := map[string]func(namespace, capability, operation string) (Callback, error){
+		"Copy-on-write": router.Lookup,
+		"RWMutex":       baseline.Lookup,
+	}
+
+	for name, lookup := range lookups {
+		b.Run(name, func(b *testing.B) {
+			b.SetParallelism(8)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					_, err := lookup("benchmarks", "testing", fmt.Sprintf("operation-%d", i%numCallbacks))
+					if err != nil {
+						b.Errorf("Failed to lookup callback: %s", err)
+						return
+					}
+					i++
+				}
+			})
+		})
+	}
+}
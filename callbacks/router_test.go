@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -364,6 +365,1728 @@ func TestRouter(t *testing.T) { //nolint:gocyclo,gocognit,cyclop // Test functio
 	}
 }
 
+type OnErrorTestCase struct {
+	Name        string
+	Ctx         func() context.Context
+	Operation   string
+	PreFuncErr  error
+	CallbackErr error
+	Err         error
+}
+
+func TestRouterOnError(t *testing.T) {
+	canceled := func() context.Context {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		return ctx
+	}
+
+	tt := []OnErrorTestCase{
+		{
+			Name:      "No error",
+			Ctx:       context.Background,
+			Operation: "increment",
+		},
+		{
+			Name:      "Not found",
+			Ctx:       context.Background,
+			Operation: "decrement",
+			Err:       ErrNotFound,
+		},
+		{
+			Name:      "Canceled context",
+			Ctx:       canceled,
+			Operation: "increment",
+			Err:       ErrCanceled,
+		},
+		{
+			Name:       "PreFunc error",
+			Ctx:        context.Background,
+			Operation:  "increment",
+			PreFuncErr: ErrTestError,
+			Err:        ErrTestError,
+		},
+		{
+			Name:        "Callback error",
+			Ctx:         context.Background,
+			Operation:   "increment",
+			CallbackErr: ErrTestError,
+			Err:         ErrTestError,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			onErrorCounter := &Counter{}
+			var onErrorErr error
+
+			router, err := New(RouterConfig{
+				PreFunc: func(CallbackRequest) ([]byte, error) {
+					return nil, tc.PreFuncErr
+				},
+				OnError: func(req CallbackRequest, err error) {
+					if req.Operation != tc.Operation {
+						t.Errorf("Unexpected OnError operation: %s, expected: %s", req.Operation, tc.Operation)
+					}
+					onErrorErr = err
+					onErrorCounter.Increment()
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error creating router: %s", err)
+			}
+			defer router.Close()
+
+			err = router.RegisterCallback(CallbackConfig{
+				Namespace:  "default",
+				Capability: "counter",
+				Operation:  "increment",
+				Func: func(input []byte) ([]byte, error) {
+					return input, tc.CallbackErr
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error registering callback: %s", err)
+			}
+
+			_, err = router.Callback(tc.Ctx(), "default", "counter", tc.Operation, []byte("Hello World"))
+			if !errors.Is(err, tc.Err) {
+				t.Fatalf("Unexpected error calling callback: %s, expected: %s", err, tc.Err)
+			}
+
+			if tc.Err == nil {
+				if onErrorCounter.Value() != 0 {
+					t.Errorf("Unexpected OnError count: %d, expected: 0", onErrorCounter.Value())
+				}
+				return
+			}
+
+			if onErrorCounter.Value() != 1 {
+				t.Errorf("Unexpected OnError count: %d, expected: 1", onErrorCounter.Value())
+			}
+			if !errors.Is(onErrorErr, tc.Err) {
+				t.Errorf("Unexpected OnError error: %s, expected: %s", onErrorErr, tc.Err)
+			}
+		})
+	}
+}
+
+func TestRouterOnMiss(t *testing.T) {
+	onMissCounter := &Counter{}
+	router, err := New(RouterConfig{
+		OnMiss: func(namespace, capability, operation string) (CallbackConfig, bool) {
+			onMissCounter.Increment()
+			switch operation {
+			case "dynamic":
+				return CallbackConfig{
+					Namespace:  namespace,
+					Capability: capability,
+					Operation:  operation,
+					Func: func(input []byte) ([]byte, error) {
+						return input, nil
+					},
+				}, true
+			case "invalid":
+				return CallbackConfig{
+					Namespace:  namespace,
+					Capability: capability,
+					Operation:  operation,
+				}, true
+			default:
+				return CallbackConfig{}, false
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	t.Run("Resolve on miss", func(t *testing.T) {
+		rsp, err := router.Callback(context.Background(), "default", "lazy", "dynamic", []byte("Hello World"))
+		if err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+		if !bytes.Equal(rsp, []byte("Hello World")) {
+			t.Errorf("Unexpected callback response: %s", rsp)
+		}
+		if onMissCounter.Value() != 1 {
+			t.Errorf("Unexpected OnMiss count: %d, expected: 1", onMissCounter.Value())
+		}
+	})
+
+	t.Run("Registered after miss", func(t *testing.T) {
+		_, err := router.Lookup("default", "lazy", "dynamic")
+		if err != nil {
+			t.Fatalf("Unexpected error looking up callback: %s", err)
+		}
+
+		_, err = router.Callback(context.Background(), "default", "lazy", "dynamic", []byte("Hello World"))
+		if err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+		if onMissCounter.Value() != 1 {
+			t.Errorf("Unexpected OnMiss count: %d, expected: 1", onMissCounter.Value())
+		}
+	})
+
+	t.Run("Unresolved miss", func(t *testing.T) {
+		_, err := router.Callback(context.Background(), "default", "lazy", "unknown", []byte("Hello World"))
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected notfound error calling callback, got: %s", err)
+		}
+	})
+
+	t.Run("Invalid resolved config", func(t *testing.T) {
+		_, err := router.Callback(context.Background(), "default", "lazy", "invalid", []byte("Hello World"))
+		if !errors.Is(err, ErrInvalidFunc) {
+			t.Errorf("Expected invalid func error calling callback, got: %s", err)
+		}
+	})
+}
+
+func TestRouterDefaultFunc(t *testing.T) {
+	var defaults, pre []CallbackRequest
+	var post []CallbackResult
+	router, err := New(RouterConfig{
+		PreFunc: func(req CallbackRequest) ([]byte, error) {
+			pre = append(pre, req)
+			return nil, nil
+		},
+		PostFunc: func(res CallbackResult) {
+			post = append(post, res)
+		},
+		SyncPostFunc: true,
+		DefaultFunc: func(req CallbackRequest) ([]byte, error) {
+			defaults = append(defaults, req)
+			if req.Operation == "fail" {
+				return nil, ErrTestError
+			}
+			return []byte("default " + req.Namespace + ":" + req.Capability + ":" + req.Operation), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "kv",
+		Operation:  "get",
+		Func: func(input []byte) ([]byte, error) {
+			return input, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	t.Run("Registered callback", func(t *testing.T) {
+		rsp, err := router.Callback(context.Background(), "default", "kv", "get", []byte("Hello World"))
+		if err != nil || string(rsp) != "Hello World" {
+			t.Errorf("Unexpected callback result: %s, %v", rsp, err)
+		}
+		if len(defaults) != 0 {
+			t.Errorf("Unexpected DefaultFunc calls: %d", len(defaults))
+		}
+	})
+
+	t.Run("Unregistered callback", func(t *testing.T) {
+		pre, post = nil, nil
+		rsp, err := router.Callback(context.Background(), "another", "sql", "query", []byte("Hello World"))
+		if err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+		if string(rsp) != "default another:sql:query" {
+			t.Errorf("Unexpected callback response: %s", rsp)
+		}
+		if len(defaults) != 1 || string(defaults[0].Input) != "Hello World" {
+			t.Errorf("Unexpected DefaultFunc requests: %v", defaults)
+		}
+		if len(pre) != 1 || len(post) != 1 || post[0].Operation != "query" {
+			t.Errorf("Expected PreFunc and PostFunc to be called, got: %v, %v", pre, post)
+		}
+	})
+
+	t.Run("DefaultFunc error", func(t *testing.T) {
+		_, err := router.Callback(context.Background(), "another", "sql", "fail", []byte("Hello World"))
+		if !errors.Is(err, ErrTestError) {
+			t.Errorf("Expected test error, got: %s", err)
+		}
+	})
+}
+
+func TestRouterHookContext(t *testing.T) {
+	type traceKey struct{}
+
+	var pre []CallbackRequest
+	var post []CallbackResult
+	router, err := New(RouterConfig{
+		PreFunc: func(req CallbackRequest) ([]byte, error) {
+			pre = append(pre, req)
+			return nil, nil
+		},
+		PreFuncWithContext: func(ctx context.Context, _ CallbackRequest) (context.Context, []byte, error) {
+			return context.WithValue(ctx, traceKey{}, "span"), nil, nil
+		},
+		PostFunc: func(res CallbackResult) {
+			post = append(post, res)
+		},
+		SyncPostFunc: true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "kv",
+		Operation:  "get",
+		Func: func(input []byte) ([]byte, error) {
+			return input, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	if _, err := router.Callback(ctx, "default", "kv", "get", []byte("Hello World")); err != nil {
+		t.Fatalf("Unexpected error calling callback: %s", err)
+	}
+
+	t.Run("PreFunc", func(t *testing.T) {
+		if len(pre) != 1 || pre[0].Context == nil {
+			t.Fatalf("Expected PreFunc request to carry the context, got: %v", pre)
+		}
+		if d, ok := pre[0].Context.Deadline(); !ok || !d.Equal(deadline) {
+			t.Errorf("Unexpected PreFunc context deadline: %s, expected: %s", d, deadline)
+		}
+	})
+
+	t.Run("PostFunc", func(t *testing.T) {
+		if len(post) != 1 || post[0].Context == nil {
+			t.Fatalf("Expected PostFunc result to carry the context, got: %v", post)
+		}
+		if post[0].Context.Value(traceKey{}) != "span" {
+			t.Errorf("Expected PostFunc context to carry the PreFuncWithContext value")
+		}
+
+		// The PostFunc context is detached from the request deadline and cancellation
+		cancel()
+		if _, ok := post[0].Context.Deadline(); ok {
+			t.Errorf("Unexpected PostFunc context deadline")
+		}
+		if post[0].Context.Err() != nil {
+			t.Errorf("Unexpected PostFunc context error: %s", post[0].Context.Err())
+		}
+	})
+}
+
+func TestRouterCallbackWithMatch(t *testing.T) {
+	router, err := New(RouterConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "counter",
+		Operation:  "increment",
+		Func: func(input []byte) ([]byte, error) {
+			return input, nil
+		},
+		Metadata: map[string]string{"owner": "counters"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	t.Run("Matched callback", func(t *testing.T) {
+		rsp, cb, err := router.CallbackWithMatch(context.Background(), "default", "counter", "increment", []byte("Hello"))
+		if err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+		if !bytes.Equal(rsp, []byte("Hello")) {
+			t.Errorf("Unexpected callback response: %s", rsp)
+		}
+		if cb.Namespace != "default" || cb.Capability != "counter" || cb.Operation != "increment" {
+			t.Errorf("Unexpected matched callback: %+v", cb)
+		}
+		if cb.Metadata["owner"] != "counters" {
+			t.Errorf("Unexpected matched callback metadata: %v", cb.Metadata)
+		}
+
+		// Modifying the returned metadata should not modify the registered callback
+		cb.Metadata["owner"] = "modified"
+		registered, err := router.Lookup("default", "counter", "increment")
+		if err != nil {
+			t.Fatalf("Unexpected error looking up callback: %s", err)
+		}
+		if registered.Metadata["owner"] != "counters" {
+			t.Errorf("Registered callback metadata was modified: %v", registered.Metadata)
+		}
+	})
+
+	t.Run("Unmatched callback", func(t *testing.T) {
+		_, cb, err := router.CallbackWithMatch(context.Background(), "default", "counter", "decrement", []byte("Hello"))
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected notfound error calling callback, got: %s", err)
+		}
+		if cb.Namespace != "" {
+			t.Errorf("Expected empty matched callback, got: %+v", cb)
+		}
+	})
+}
+
+type ctxKey struct{}
+
+func TestRouterCtxFunc(t *testing.T) {
+	router, err := New(RouterConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "ctx",
+		Operation:  "both",
+		Func: func(_ []byte) ([]byte, error) {
+			return []byte("func"), nil
+		},
+		CtxFunc: func(_ context.Context, _ []byte) ([]byte, error) {
+			return []byte("ctxfunc"), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "ctx",
+		Operation:  "wait",
+		CtxFunc: func(ctx context.Context, _ []byte) ([]byte, error) {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(5 * time.Second):
+				return []byte("finished"), nil
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	t.Run("CtxFunc takes precedence", func(t *testing.T) {
+		rsp, err := router.Callback(context.Background(), "default", "ctx", "both", []byte(""))
+		if err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+		if string(rsp) != "ctxfunc" {
+			t.Errorf("Unexpected response: %s, expected: ctxfunc", rsp)
+		}
+	})
+
+	t.Run("Canceled mid-execution", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+
+		start := time.Now()
+		_, err := router.Callback(ctx, "default", "ctx", "wait", []byte(""))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected canceled error, got: %s", err)
+		}
+		if time.Since(start) > time.Second {
+			t.Errorf("Expected callback to return early on cancellation")
+		}
+	})
+}
+
+func TestRouterPreFuncWithContext(t *testing.T) {
+	router, err := New(RouterConfig{
+		PreFuncWithContext: func(ctx context.Context, rq CallbackRequest) (context.Context, []byte, error) {
+			if string(rq.Input) == "deny" {
+				return nil, []byte("denied"), ErrTestError
+			}
+			if string(rq.Input) == "passthrough" {
+				return nil, nil, nil
+			}
+			return context.WithValue(ctx, ctxKey{}, "user"), nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "auth",
+		Operation:  "whoami",
+		CtxFunc: func(ctx context.Context, _ []byte) ([]byte, error) {
+			user, _ := ctx.Value(ctxKey{}).(string)
+			return []byte(user), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	t.Run("Context value passed to callback", func(t *testing.T) {
+		rsp, err := router.Callback(context.Background(), "default", "auth", "whoami", []byte("allow"))
+		if err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+		if string(rsp) != "user" {
+			t.Errorf("Unexpected callback response: %s", rsp)
+		}
+	})
+
+	t.Run("Nil context passes original context", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), ctxKey{}, "original")
+		rsp, err := router.Callback(ctx, "default", "auth", "whoami", []byte("passthrough"))
+		if err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+		if string(rsp) != "original" {
+			t.Errorf("Unexpected callback response: %s", rsp)
+		}
+	})
+
+	t.Run("Error aborts callback", func(t *testing.T) {
+		rsp, err := router.Callback(context.Background(), "default", "auth", "whoami", []byte("deny"))
+		if !errors.Is(err, ErrTestError) {
+			t.Errorf("Expected error from PreFuncWithContext, got: %s", err)
+		}
+		if string(rsp) != "denied" {
+			t.Errorf("Unexpected callback response: %s", rsp)
+		}
+	})
+}
+
+func TestRouterPriority(t *testing.T) {
+	router, err := New(RouterConfig{
+		MaxConcurrency:      2,
+		ReservedConcurrency: 1,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	for op, priority := range map[string]int{"low": 0, "high": 1} {
+		err = router.RegisterCallback(CallbackConfig{
+			Namespace:  "default",
+			Capability: "priority",
+			Operation:  op,
+			Priority:   priority,
+			Func: func(input []byte) ([]byte, error) {
+				if string(input) == "block" {
+					started <- struct{}{}
+					<-release
+				}
+				return input, nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error registering callback: %s", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	call := func(op, input string) {
+		defer wg.Done()
+		_, err := router.Callback(context.Background(), "default", "priority", op, []byte(input))
+		if err != nil {
+			t.Errorf("Unexpected error calling callback: %s", err)
+		}
+	}
+
+	// Occupy the unreserved capacity with a low priority callback
+	wg.Add(1)
+	go call("low", "block")
+	<-started
+
+	t.Run("Low priority shed", func(t *testing.T) {
+		_, err := router.Callback(context.Background(), "default", "priority", "low", []byte(""))
+		if !errors.Is(err, ErrOverloaded) {
+			t.Errorf("Expected overloaded error calling callback, got: %s", err)
+		}
+	})
+
+	t.Run("High priority admitted", func(t *testing.T) {
+		_, err := router.Callback(context.Background(), "default", "priority", "high", []byte(""))
+		if err != nil {
+			t.Errorf("Unexpected error calling callback: %s", err)
+		}
+	})
+
+	// Occupy the reserved capacity with a high priority callback
+	wg.Add(1)
+	go call("high", "block")
+	<-started
+
+	t.Run("High priority shed at limit", func(t *testing.T) {
+		_, err := router.Callback(context.Background(), "default", "priority", "high", []byte(""))
+		if !errors.Is(err, ErrOverloaded) {
+			t.Errorf("Expected overloaded error calling callback, got: %s", err)
+		}
+	})
+
+	close(release)
+	wg.Wait()
+
+	t.Run("Low priority admitted after release", func(t *testing.T) {
+		_, err := router.Callback(context.Background(), "default", "priority", "low", []byte(""))
+		if err != nil {
+			t.Errorf("Unexpected error calling callback: %s", err)
+		}
+	})
+}
+
+func TestRouterUpdateCallback(t *testing.T) {
+	router, err := New(RouterConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	cfg := CallbackConfig{
+		Namespace:  "default",
+		Capability: "greeting",
+		Operation:  "hello",
+		Func: func([]byte) ([]byte, error) {
+			return []byte("v1"), nil
+		},
+		Metadata: map[string]string{"owner": "greeting"},
+	}
+
+	t.Run("Not registered", func(t *testing.T) {
+		if err := router.UpdateCallback(cfg); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected not found error, got: %s", err)
+		}
+	})
+
+	t.Run("Invalid config", func(t *testing.T) {
+		if err := router.UpdateCallback(CallbackConfig{Namespace: "default"}); err == nil {
+			t.Errorf("Expected error updating callback with invalid config")
+		}
+	})
+
+	if err := router.RegisterCallback(cfg); err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+	if _, err := router.Callback(context.Background(), "default", "greeting", "hello", []byte("in")); err != nil {
+		t.Fatalf("Unexpected error calling callback: %s", err)
+	}
+
+	t.Run("Replaced", func(t *testing.T) {
+		err := router.UpdateCallback(CallbackConfig{
+			Namespace:  "default",
+			Capability: "greeting",
+			Operation:  "hello",
+			Func: func([]byte) ([]byte, error) {
+				return []byte("v2"), nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error updating callback: %s", err)
+		}
+
+		rsp, err := router.Callback(context.Background(), "default", "greeting", "hello", []byte("in"))
+		if err != nil || string(rsp) != "v2" {
+			t.Errorf("Unexpected callback result: %s, %v, expected: v2", rsp, err)
+		}
+
+		// Settings and statistics are retained
+		cb, err := router.Lookup("default", "greeting", "hello")
+		if err != nil {
+			t.Fatalf("Unexpected error looking up callback: %s", err)
+		}
+		if cb.Metadata["owner"] != "greeting" {
+			t.Errorf("Unexpected metadata: %v", cb.Metadata)
+		}
+		if in, _ := router.Bytes("default", "greeting", "hello"); in != 4 {
+			t.Errorf("Unexpected input bytes: %d, expected: 4", in)
+		}
+	})
+}
+
+func TestRouterRenameNamespace(t *testing.T) {
+	router, err := New(RouterConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	for _, cfg := range []CallbackConfig{
+		{Namespace: "old", Capability: "counter", Operation: "increment"},
+		{Namespace: "old", Capability: "counter", Operation: "decrement"},
+		{Namespace: "other", Capability: "counter", Operation: "increment"},
+	} {
+		cfg.Func = func(input []byte) ([]byte, error) {
+			return input, nil
+		}
+		if err := router.RegisterCallback(cfg); err != nil {
+			t.Fatalf("Unexpected error registering callback: %s", err)
+		}
+	}
+
+	t.Run("Invalid namespace", func(t *testing.T) {
+		_, err := router.RenameNamespace("old", "")
+		if !errors.Is(err, ErrInvalidNamespace) {
+			t.Errorf("Expected invalid namespace error, got: %s", err)
+		}
+	})
+
+	t.Run("Collision", func(t *testing.T) {
+		n, err := router.RenameNamespace("old", "other")
+		if !errors.Is(err, ErrCallbackExists) {
+			t.Errorf("Expected callback exists error, got: %s", err)
+		}
+		if n != 0 {
+			t.Errorf("Unexpected number of callbacks moved: %d, expected: 0", n)
+		}
+		if _, err := router.Lookup("old", "counter", "decrement"); err != nil {
+			t.Errorf("Expected callbacks to remain in old namespace, got: %s", err)
+		}
+	})
+
+	t.Run("Rename", func(t *testing.T) {
+		n, err := router.RenameNamespace("old", "new")
+		if err != nil {
+			t.Fatalf("Unexpected error renaming namespace: %s", err)
+		}
+		if n != 2 {
+			t.Errorf("Unexpected number of callbacks moved: %d, expected: 2", n)
+		}
+
+		for _, op := range []string{"increment", "decrement"} {
+			if _, err := router.Lookup("old", "counter", op); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected callback %s removed from old namespace, got: %s", op, err)
+			}
+			cb, err := router.Lookup("new", "counter", op)
+			if err != nil {
+				t.Errorf("Expected callback %s in new namespace, got: %s", op, err)
+			}
+			if cb.Namespace != "new" {
+				t.Errorf("Unexpected callback namespace: %s", cb.Namespace)
+			}
+		}
+
+		rsp, err := router.Callback(context.Background(), "new", "counter", "increment", []byte("Hello"))
+		if err != nil || string(rsp) != "Hello" {
+			t.Errorf("Unexpected callback result: %s, %s", rsp, err)
+		}
+	})
+}
+
+type CopyTestCase struct {
+	Name       string
+	CopyInput  bool
+	CopyOutput bool
+}
+
+func TestRouterCopy(t *testing.T) {
+	tt := []CopyTestCase{
+		{Name: "No copy"},
+		{Name: "Copy input", CopyInput: true},
+		{Name: "Copy output", CopyOutput: true},
+		{Name: "Copy input and output", CopyInput: true, CopyOutput: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			router, err := New(RouterConfig{
+				CopyInput:  tc.CopyInput,
+				CopyOutput: tc.CopyOutput,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error creating router: %s", err)
+			}
+			defer router.Close()
+
+			var retained []byte
+			output := []byte("output")
+			err = router.RegisterCallback(CallbackConfig{
+				Namespace:  "default",
+				Capability: "copy",
+				Operation:  "retain",
+				Func: func(input []byte) ([]byte, error) {
+					retained = input
+					return output, nil
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error registering callback: %s", err)
+			}
+
+			input := []byte("input")
+			rsp, err := router.Callback(context.Background(), "default", "copy", "retain", input)
+			if err != nil {
+				t.Fatalf("Unexpected error calling callback: %s", err)
+			}
+
+			// Reuse the input and output buffers
+			copy(input, "XXXXX")
+			copy(output, "XXXXXX")
+
+			if (string(retained) == "input") != tc.CopyInput {
+				t.Errorf("Unexpected retained input: %s", retained)
+			}
+			if (string(rsp) == "output") != tc.CopyOutput {
+				t.Errorf("Unexpected callback response: %s", rsp)
+			}
+		})
+	}
+}
+
+type SkipFuncTestCase struct {
+	Name         string
+	SkipPreFunc  bool
+	SkipPostFunc bool
+}
+
+func TestRouterSkipFuncs(t *testing.T) {
+	tt := []SkipFuncTestCase{
+		{Name: "No skip"},
+		{Name: "Skip PreFunc", SkipPreFunc: true},
+		{Name: "Skip PostFunc", SkipPostFunc: true},
+		{Name: "Skip both", SkipPreFunc: true, SkipPostFunc: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			preFuncCounter := &Counter{}
+			postFuncCounter := &Counter{}
+			router, err := New(RouterConfig{
+				PreFunc: func(CallbackRequest) ([]byte, error) {
+					preFuncCounter.Increment()
+					return nil, nil
+				},
+				PreFuncWithContext: func(ctx context.Context, _ CallbackRequest) (context.Context, []byte, error) {
+					preFuncCounter.Increment()
+					return ctx, nil, nil
+				},
+				PostFunc: func(CallbackResult) {
+					postFuncCounter.Increment()
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error creating router: %s", err)
+			}
+
+			err = router.RegisterCallback(CallbackConfig{
+				Namespace:    "default",
+				Capability:   "health",
+				Operation:    "check",
+				SkipPreFunc:  tc.SkipPreFunc,
+				SkipPostFunc: tc.SkipPostFunc,
+				Func: func(input []byte) ([]byte, error) {
+					return input, nil
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error registering callback: %s", err)
+			}
+
+			_, err = router.Callback(context.Background(), "default", "health", "check", []byte(""))
+			if err != nil {
+				t.Fatalf("Unexpected error calling callback: %s", err)
+			}
+
+			// Wait for PostFunc to complete
+			router.Close()
+
+			expectedPre := 2
+			if tc.SkipPreFunc {
+				expectedPre = 0
+			}
+			if preFuncCounter.Value() != expectedPre {
+				t.Errorf("Unexpected PreFunc count: %d, expected: %d", preFuncCounter.Value(), expectedPre)
+			}
+
+			expectedPost := 1
+			if tc.SkipPostFunc {
+				expectedPost = 0
+			}
+			if postFuncCounter.Value() != expectedPost {
+				t.Errorf("Unexpected PostFunc count: %d, expected: %d", postFuncCounter.Value(), expectedPost)
+			}
+		})
+	}
+}
+
+type PostFuncOnPreErrorTestCase struct {
+	Name               string
+	PostFuncOnPreError bool
+	WithContext        bool
+	PostFuncCalls      int
+}
+
+func TestRouterPostFuncOnPreError(t *testing.T) {
+	tt := []PostFuncOnPreErrorTestCase{
+		{Name: "Disabled", PostFuncOnPreError: false, PostFuncCalls: 0},
+		{Name: "PreFunc", PostFuncOnPreError: true, PostFuncCalls: 1},
+		{Name: "PreFuncWithContext", PostFuncOnPreError: true, WithContext: true, PostFuncCalls: 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var mu sync.Mutex
+			var results []CallbackResult
+			cfg := RouterConfig{
+				PostFunc: func(r CallbackResult) {
+					mu.Lock()
+					defer mu.Unlock()
+					results = append(results, r)
+				},
+				PostFuncOnPreError: tc.PostFuncOnPreError,
+			}
+			if tc.WithContext {
+				cfg.PreFuncWithContext = func(ctx context.Context, _ CallbackRequest) (context.Context, []byte, error) {
+					return ctx, []byte("denied"), ErrTestError
+				}
+			} else {
+				cfg.PreFunc = func(CallbackRequest) ([]byte, error) {
+					return []byte("denied"), ErrTestError
+				}
+			}
+
+			router, err := New(cfg)
+			if err != nil {
+				t.Fatalf("Unexpected error creating router: %s", err)
+			}
+
+			called := &Counter{}
+			err = router.RegisterCallback(CallbackConfig{
+				Namespace:  "default",
+				Capability: "audit",
+				Operation:  "denied",
+				Func: func(input []byte) ([]byte, error) {
+					called.Increment()
+					return input, nil
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error registering callback: %s", err)
+			}
+
+			rsp, err := router.Callback(context.Background(), "default", "audit", "denied", []byte("input"))
+			if !errors.Is(err, ErrTestError) {
+				t.Fatalf("Expected PreFunc error, got: %s", err)
+			}
+			if string(rsp) != "denied" {
+				t.Errorf("Unexpected response: %s", rsp)
+			}
+
+			// Wait for PostFunc to complete
+			router.Close()
+
+			if called.Value() != 0 {
+				t.Errorf("Unexpected callback calls: %d, expected: 0", called.Value())
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(results) != tc.PostFuncCalls {
+				t.Fatalf("Unexpected PostFunc calls: %d, expected: %d", len(results), tc.PostFuncCalls)
+			}
+			for _, r := range results {
+				if !errors.Is(r.Err, ErrTestError) {
+					t.Errorf("Expected PreFunc error in result, got: %s", r.Err)
+				}
+				if r.Output != nil {
+					t.Errorf("Unexpected output in result: %s", r.Output)
+				}
+				if string(r.Input) != "input" || r.Operation != "denied" {
+					t.Errorf("Unexpected request details in result: %+v", r)
+				}
+			}
+		})
+	}
+}
+
+type PanicTestCase struct {
+	Name    string
+	OnPanic func(any) error
+	Err     error
+}
+
+func TestRouterCallbackPanic(t *testing.T) {
+	tt := []PanicTestCase{
+		{
+			Name: "Default panic error",
+			Err:  ErrCallbackPanic,
+		},
+		{
+			Name: "OnPanic error",
+			OnPanic: func(any) error {
+				return ErrTestError
+			},
+			Err: ErrTestError,
+		},
+		{
+			Name: "OnPanic nil error",
+			OnPanic: func(any) error {
+				return nil
+			},
+			Err: ErrCallbackPanic,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var mu sync.Mutex
+			var results []CallbackResult
+			router, err := New(RouterConfig{
+				PostFunc: func(res CallbackResult) {
+					mu.Lock()
+					defer mu.Unlock()
+					results = append(results, res)
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error creating router: %s", err)
+			}
+
+			err = router.RegisterCallback(CallbackConfig{
+				Namespace:  "default",
+				Capability: "panic",
+				Operation:  "now",
+				OnPanic:    tc.OnPanic,
+				Func: func([]byte) ([]byte, error) {
+					panic("oh no")
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error registering callback: %s", err)
+			}
+
+			_, err = router.Callback(context.Background(), "default", "panic", "now", []byte(""))
+			if !errors.Is(err, tc.Err) {
+				t.Errorf("Unexpected error calling callback: %s, expected: %s", err, tc.Err)
+			}
+
+			// Wait for PostFunc to complete
+			router.Close()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(results) != 1 || !errors.Is(results[0].Err, tc.Err) {
+				t.Errorf("Unexpected PostFunc results: %+v", results)
+			}
+		})
+	}
+}
+
+type CallbackTimeoutTestCase struct {
+	Name    string
+	Timeout time.Duration
+	Func    func(input []byte) ([]byte, error)
+	CtxFunc func(ctx context.Context, input []byte) ([]byte, error)
+	Err     error
+}
+
+func TestRouterCallbackTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	tt := []CallbackTimeoutTestCase{
+		{
+			Name: "No timeout",
+			Func: func(input []byte) ([]byte, error) {
+				time.Sleep(20 * time.Millisecond)
+				return input, nil
+			},
+		},
+		{
+			Name:    "Within timeout",
+			Timeout: time.Second,
+			Func: func(input []byte) ([]byte, error) {
+				return input, nil
+			},
+		},
+		{
+			Name:    "Func exceeds timeout",
+			Timeout: 10 * time.Millisecond,
+			Func: func(input []byte) ([]byte, error) {
+				<-block
+				return input, nil
+			},
+			Err: ErrCallbackTimeout,
+		},
+		{
+			Name:    "CtxFunc exceeds timeout",
+			Timeout: 10 * time.Millisecond,
+			CtxFunc: func(ctx context.Context, _ []byte) ([]byte, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+			Err: ErrCallbackTimeout,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var mu sync.Mutex
+			var results []CallbackResult
+			router, err := New(RouterConfig{
+				PostFunc: func(r CallbackResult) {
+					mu.Lock()
+					defer mu.Unlock()
+					results = append(results, r)
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error creating router: %s", err)
+			}
+
+			err = router.RegisterCallback(CallbackConfig{
+				Namespace:  "default",
+				Capability: "timeout",
+				Operation:  "call",
+				Timeout:    tc.Timeout,
+				Func:       tc.Func,
+				CtxFunc:    tc.CtxFunc,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error registering callback: %s", err)
+			}
+
+			start := time.Now()
+			_, err = router.Callback(context.Background(), "default", "timeout", "call", []byte("input"))
+			if !errors.Is(err, tc.Err) {
+				t.Errorf("Unexpected error calling callback: %s, expected: %s", err, tc.Err)
+			}
+			if tc.Timeout > 0 && time.Since(start) > tc.Timeout+time.Second {
+				t.Errorf("Callback exceeded timeout: %s", time.Since(start))
+			}
+
+			// Wait for PostFunc to complete
+			router.Close()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(results) != 1 || !errors.Is(results[0].Err, tc.Err) {
+				t.Errorf("Expected PostFunc to be called with error %s, got: %v", tc.Err, results)
+			}
+		})
+	}
+}
+
+func TestRouterBytes(t *testing.T) {
+	router, err := New(RouterConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "bytes",
+		Operation:  "double",
+		Func: func(input []byte) ([]byte, error) {
+			return append(input, input...), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := router.Callback(context.Background(), "default", "bytes", "double", []byte("Hello"))
+		if err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+	}
+
+	in, out := router.Bytes("default", "bytes", "double")
+	if in != 15 || out != 30 {
+		t.Errorf("Unexpected bytes: %d in, %d out, expected: 15 in, 30 out", in, out)
+	}
+
+	t.Run("Renamed namespace", func(t *testing.T) {
+		if _, err := router.RenameNamespace("default", "renamed"); err != nil {
+			t.Fatalf("Unexpected error renaming namespace: %s", err)
+		}
+		in, out := router.Bytes("renamed", "bytes", "double")
+		if in != 15 || out != 30 {
+			t.Errorf("Unexpected bytes: %d in, %d out, expected: 15 in, 30 out", in, out)
+		}
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		in, out := router.Bytes("default", "bytes", "missing")
+		if in != 0 || out != 0 {
+			t.Errorf("Unexpected bytes: %d in, %d out, expected: 0 in, 0 out", in, out)
+		}
+	})
+}
+
+func TestRouterOnRegisterCtx(t *testing.T) {
+	router, err := New(RouterConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+
+	contexts := make(map[string]context.Context)
+	register := func(operation string) error {
+		return router.RegisterCallback(CallbackConfig{
+			Namespace:  "default",
+			Capability: "worker",
+			Operation:  operation,
+			Func:       func(input []byte) ([]byte, error) { return input, nil },
+			OnRegisterCtx: func(ctx context.Context) error {
+				contexts[operation] = ctx
+				return nil
+			},
+		})
+	}
+
+	for _, op := range []string{"unregistered", "renamed", "closed"} {
+		if err := register(op); err != nil {
+			t.Fatalf("Unexpected error registering callback: %s", err)
+		}
+		if contexts[op].Err() != nil {
+			t.Fatalf("Unexpected canceled context after registration")
+		}
+	}
+
+	t.Run("Canceled on unregister", func(t *testing.T) {
+		err := router.UnregisterCallback(CallbackConfig{
+			Namespace:  "default",
+			Capability: "worker",
+			Operation:  "unregistered",
+			Func:       func(input []byte) ([]byte, error) { return input, nil },
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error unregistering callback: %s", err)
+		}
+		if contexts["unregistered"].Err() == nil {
+			t.Errorf("Expected context to be canceled on unregister")
+		}
+	})
+
+	t.Run("Kept on rename", func(t *testing.T) {
+		if _, err := router.RenameNamespace("default", "renamed"); err != nil {
+			t.Fatalf("Unexpected error renaming namespace: %s", err)
+		}
+		if contexts["renamed"].Err() != nil {
+			t.Errorf("Unexpected canceled context after rename")
+		}
+	})
+
+	t.Run("Canceled on close", func(t *testing.T) {
+		router.Close()
+		for op, ctx := range contexts {
+			if ctx.Err() == nil {
+				t.Errorf("Expected context for %s to be canceled on close", op)
+			}
+		}
+	})
+
+	t.Run("Registration error", func(t *testing.T) {
+		router, err := New(RouterConfig{})
+		if err != nil {
+			t.Fatalf("Unexpected error creating router: %s", err)
+		}
+		defer router.Close()
+
+		var registerCtx context.Context
+		err = router.RegisterCallback(CallbackConfig{
+			Namespace:  "default",
+			Capability: "worker",
+			Operation:  "failed",
+			Func:       func(input []byte) ([]byte, error) { return input, nil },
+			OnRegisterCtx: func(ctx context.Context) error {
+				registerCtx = ctx
+				return ErrTestError
+			},
+		})
+		if !errors.Is(err, ErrTestError) {
+			t.Fatalf("Expected registration error, got: %s", err)
+		}
+		if registerCtx.Err() == nil {
+			t.Errorf("Expected context to be canceled on registration error")
+		}
+		if _, err := router.Callback(context.Background(), "default", "worker", "failed", nil); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected callback not to be registered, got: %s", err)
+		}
+	})
+}
+
+func TestRouterOperations(t *testing.T) {
+	router, err := New(RouterConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	for _, cfg := range []CallbackConfig{
+		{Namespace: "default", Capability: "kv", Operation: "set"},
+		{Namespace: "default", Capability: "kv", Operation: "get"},
+		{Namespace: "default", Capability: "kv", Operation: "delete"},
+		{Namespace: "default", Capability: "sql", Operation: "query"},
+		{Namespace: "other", Capability: "kv", Operation: "list"},
+	} {
+		cfg.Func = func(input []byte) ([]byte, error) { return input, nil }
+		if err := router.RegisterCallback(cfg); err != nil {
+			t.Fatalf("Unexpected error registering callback: %s", err)
+		}
+	}
+
+	ops := router.Operations("default", "kv")
+	expected := []string{"delete", "get", "set"}
+	if len(ops) != len(expected) {
+		t.Fatalf("Unexpected operations: %v, expected: %v", ops, expected)
+	}
+	for i := range ops {
+		if ops[i] != expected[i] {
+			t.Errorf("Unexpected operations: %v, expected: %v", ops, expected)
+		}
+	}
+
+	t.Run("Not found", func(t *testing.T) {
+		ops := router.Operations("default", "missing")
+		if ops == nil || len(ops) != 0 {
+			t.Errorf("Unexpected operations: %v, expected empty slice", ops)
+		}
+	})
+}
+
+func TestRouterList(t *testing.T) {
+	router, err := New(RouterConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	t.Run("Empty", func(t *testing.T) {
+		list := router.List()
+		if list == nil || len(list) != 0 {
+			t.Errorf("Unexpected callbacks: %v, expected empty slice", list)
+		}
+	})
+
+	for _, cfg := range []CallbackConfig{
+		{Namespace: "default", Capability: "kv", Operation: "set", Metadata: map[string]string{"owner": "kv"}},
+		{Namespace: "default", Capability: "kv", Operation: "get"},
+		{Namespace: "another", Capability: "sql", Operation: "query"},
+	} {
+		cfg.Func = func(input []byte) ([]byte, error) { return input, nil }
+		if err := router.RegisterCallback(cfg); err != nil {
+			t.Fatalf("Unexpected error registering callback: %s", err)
+		}
+	}
+
+	t.Run("Sorted", func(t *testing.T) {
+		list := router.List()
+		expected := []string{"another:sql:query", "default:kv:get", "default:kv:set"}
+		if len(list) != len(expected) {
+			t.Fatalf("Unexpected number of callbacks: %d, expected: %d", len(list), len(expected))
+		}
+		for i, cb := range list {
+			key := fmt.Sprintf("%s:%s:%s", cb.Namespace, cb.Capability, cb.Operation)
+			if key != expected[i] {
+				t.Errorf("Unexpected callback at %d: %s, expected: %s", i, key, expected[i])
+			}
+		}
+	})
+
+	t.Run("Copies", func(t *testing.T) {
+		list := router.List()
+		list[2].Operation = "modified"
+		list[2].Metadata["owner"] = "modified"
+
+		cb, err := router.Lookup("default", "kv", "set")
+		if err != nil {
+			t.Fatalf("Unexpected error looking up callback: %s", err)
+		}
+		if cb.Metadata["owner"] != "kv" {
+			t.Errorf("Registered callback metadata was modified: %v", cb.Metadata)
+		}
+	})
+
+	t.Run("Concurrent registration", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				err := router.RegisterCallback(CallbackConfig{
+					Namespace:  "concurrent",
+					Capability: "kv",
+					Operation:  fmt.Sprintf("op-%d", i),
+					Func:       func(input []byte) ([]byte, error) { return input, nil },
+				})
+				if err != nil {
+					t.Errorf("Unexpected error registering callback: %s", err)
+				}
+			}(i)
+			go func() {
+				defer wg.Done()
+				_ = router.List()
+			}()
+		}
+		wg.Wait()
+
+		if len(router.List()) != 13 {
+			t.Errorf("Unexpected number of callbacks: %d, expected: 13", len(router.List()))
+		}
+	})
+}
+
+func TestRouterRegisterIfAbsent(t *testing.T) {
+	router, err := New(RouterConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	cfg := CallbackConfig{
+		Namespace:  "default",
+		Capability: "kv",
+		Operation:  "get",
+		Func: func([]byte) ([]byte, error) {
+			return []byte("first"), nil
+		},
+	}
+
+	t.Run("Absent", func(t *testing.T) {
+		registered, err := router.RegisterIfAbsent(cfg)
+		if err != nil || !registered {
+			t.Errorf("Expected callback to be registered, got: %t, %v", registered, err)
+		}
+	})
+
+	t.Run("Present", func(t *testing.T) {
+		replacement := cfg
+		replacement.Func = func([]byte) ([]byte, error) {
+			return []byte("second"), nil
+		}
+		registered, err := router.RegisterIfAbsent(replacement)
+		if err != nil || registered {
+			t.Errorf("Expected callback not to be registered, got: %t, %v", registered, err)
+		}
+
+		rsp, err := router.Callback(context.Background(), "default", "kv", "get", []byte(""))
+		if err != nil || string(rsp) != "first" {
+			t.Errorf("Unexpected callback result: %s, %v, expected: first", rsp, err)
+		}
+	})
+
+	t.Run("Invalid config", func(t *testing.T) {
+		registered, err := router.RegisterIfAbsent(CallbackConfig{Namespace: "default", Capability: "kv"})
+		if err == nil || registered {
+			t.Errorf("Expected invalid config error, got: %t, %v", registered, err)
+		}
+	})
+}
+
+func TestRouterRegisterCallbacks(t *testing.T) {
+	fn := func(input []byte) ([]byte, error) { return input, nil }
+
+	router, err := New(RouterConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	err = router.RegisterCallback(CallbackConfig{Namespace: "default", Capability: "kv", Operation: "get", Func: fn})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	var lifetime context.Context
+	onRegister := func(ctx context.Context) error {
+		lifetime = ctx
+		return nil
+	}
+
+	t.Run("Invalid config", func(t *testing.T) {
+		err := router.RegisterCallbacks([]CallbackConfig{
+			{Namespace: "default", Capability: "kv", Operation: "set", Func: fn},
+			{Namespace: "default", Capability: "kv", Operation: "delete"},
+		})
+		if !errors.Is(err, ErrInvalidFunc) || !strings.Contains(err.Error(), "1 (default:kv:delete)") {
+			t.Errorf("Expected invalid func error identifying the config, got: %s", err)
+		}
+		if router.Count() != 1 {
+			t.Errorf("Unexpected callbacks registered: %d, expected: 1", router.Count())
+		}
+	})
+
+	t.Run("Existing callback", func(t *testing.T) {
+		err := router.RegisterCallbacks([]CallbackConfig{
+			{Namespace: "default", Capability: "kv", Operation: "set", Func: fn},
+			{Namespace: "default", Capability: "kv", Operation: "get", Func: fn},
+		})
+		if !errors.Is(err, ErrCallbackExists) || !strings.Contains(err.Error(), "1 (default:kv:get)") {
+			t.Errorf("Expected callback exists error identifying the config, got: %s", err)
+		}
+		if router.Count() != 1 {
+			t.Errorf("Unexpected callbacks registered: %d, expected: 1", router.Count())
+		}
+	})
+
+	t.Run("Duplicate config", func(t *testing.T) {
+		err := router.RegisterCallbacks([]CallbackConfig{
+			{Namespace: "default", Capability: "kv", Operation: "set", Func: fn},
+			{Namespace: "default", Capability: "kv", Operation: "set", Func: fn},
+		})
+		if !errors.Is(err, ErrCallbackExists) {
+			t.Errorf("Expected callback exists error, got: %s", err)
+		}
+		if router.Count() != 1 {
+			t.Errorf("Unexpected callbacks registered: %d, expected: 1", router.Count())
+		}
+	})
+
+	t.Run("OnRegisterCtx error", func(t *testing.T) {
+		err := router.RegisterCallbacks([]CallbackConfig{
+			{Namespace: "default", Capability: "kv", Operation: "set", Func: fn, OnRegisterCtx: onRegister},
+			{
+				Namespace:     "default",
+				Capability:    "kv",
+				Operation:     "delete",
+				Func:          fn,
+				OnRegisterCtx: func(context.Context) error { return ErrTestError },
+			},
+		})
+		if !errors.Is(err, ErrTestError) {
+			t.Errorf("Expected test error, got: %s", err)
+		}
+		if lifetime == nil || lifetime.Err() == nil {
+			t.Errorf("Expected started lifetime to be canceled")
+		}
+		if router.Count() != 1 {
+			t.Errorf("Unexpected callbacks registered: %d, expected: 1", router.Count())
+		}
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		err := router.RegisterCallbacks([]CallbackConfig{
+			{Namespace: "default", Capability: "kv", Operation: "set", Func: fn},
+			{Namespace: "default", Capability: "kv", Operation: "delete", Func: fn},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error registering callbacks: %s", err)
+		}
+		if router.Count() != 3 {
+			t.Errorf("Unexpected callbacks registered: %d, expected: 3", router.Count())
+		}
+		if _, err := router.Callback(context.Background(), "default", "kv", "delete", []byte("")); err != nil {
+			t.Errorf("Unexpected error calling callback: %s", err)
+		}
+	})
+}
+
+func TestRouterCount(t *testing.T) {
+	router, err := New(RouterConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	t.Run("Empty", func(t *testing.T) {
+		if router.Count() != 0 {
+			t.Errorf("Unexpected count: %d, expected: 0", router.Count())
+		}
+		counts := router.CountByNamespace()
+		if counts == nil || len(counts) != 0 {
+			t.Errorf("Unexpected counts: %v, expected empty map", counts)
+		}
+	})
+
+	for _, cfg := range []CallbackConfig{
+		{Namespace: "default", Capability: "kv", Operation: "set"},
+		{Namespace: "default", Capability: "kv", Operation: "get"},
+		{Namespace: "another", Capability: "sql", Operation: "query"},
+	} {
+		cfg.Func = func(input []byte) ([]byte, error) { return input, nil }
+		if err := router.RegisterCallback(cfg); err != nil {
+			t.Fatalf("Unexpected error registering callback: %s", err)
+		}
+	}
+
+	t.Run("Registered", func(t *testing.T) {
+		if router.Count() != 3 {
+			t.Errorf("Unexpected count: %d, expected: 3", router.Count())
+		}
+		counts := router.CountByNamespace()
+		if len(counts) != 2 || counts["default"] != 2 || counts["another"] != 1 {
+			t.Errorf("Unexpected counts: %v", counts)
+		}
+	})
+
+	t.Run("Unregistered", func(t *testing.T) {
+		err := router.UnregisterCallback(CallbackConfig{
+			Namespace:  "another",
+			Capability: "sql",
+			Operation:  "query",
+			Func:       func(input []byte) ([]byte, error) { return input, nil },
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error unregistering callback: %s", err)
+		}
+		if router.Count() != 2 {
+			t.Errorf("Unexpected count: %d, expected: 2", router.Count())
+		}
+		if _, ok := router.CountByNamespace()["another"]; ok {
+			t.Errorf("Unexpected count for unregistered namespace")
+		}
+	})
+}
+
+type RouterConfigTestCase struct {
+	Name      string
+	RouterCfg RouterConfig
+	Err       error
+}
+
+func TestRouterConfigValidation(t *testing.T) {
+	postFunc := func(CallbackResult) {}
+
+	tt := []RouterConfigTestCase{
+		{
+			Name:      "Empty config",
+			RouterCfg: RouterConfig{},
+		},
+		{
+			Name: "Valid PostFunc queue",
+			RouterCfg: RouterConfig{
+				PostFunc:            postFunc,
+				PostFuncQueueSize:   10,
+				PostFuncWorkers:     2,
+				PostFuncQueuePolicy: QueueBlock,
+			},
+		},
+		{
+			Name: "Negative queue size",
+			RouterCfg: RouterConfig{
+				PostFunc:          postFunc,
+				PostFuncQueueSize: -1,
+			},
+			Err: ErrInvalidRouterConfig,
+		},
+		{
+			Name: "Negative workers",
+			RouterCfg: RouterConfig{
+				PostFunc:        postFunc,
+				PostFuncWorkers: -1,
+			},
+			Err: ErrInvalidRouterConfig,
+		},
+		{
+			Name: "Unknown queue policy",
+			RouterCfg: RouterConfig{
+				PostFunc:            postFunc,
+				PostFuncQueuePolicy: QueuePolicy(99),
+			},
+			Err: ErrInvalidRouterConfig,
+		},
+		{
+			Name: "Valid SyncPostFunc",
+			RouterCfg: RouterConfig{
+				PostFunc:     postFunc,
+				SyncPostFunc: true,
+			},
+		},
+		{
+			Name: "SyncPostFunc with queue settings",
+			RouterCfg: RouterConfig{
+				PostFunc:          postFunc,
+				PostFuncQueueSize: 10,
+				SyncPostFunc:      true,
+			},
+			Err: ErrInvalidRouterConfig,
+		},
+		{
+			Name: "Valid concurrency",
+			RouterCfg: RouterConfig{
+				MaxConcurrency:      10,
+				ReservedConcurrency: 2,
+			},
+		},
+		{
+			Name: "Negative MaxConcurrency",
+			RouterCfg: RouterConfig{
+				MaxConcurrency: -1,
+			},
+			Err: ErrInvalidRouterConfig,
+		},
+		{
+			Name: "Negative ReservedConcurrency",
+			RouterCfg: RouterConfig{
+				MaxConcurrency:      10,
+				ReservedConcurrency: -1,
+			},
+			Err: ErrInvalidRouterConfig,
+		},
+		{
+			Name: "ReservedConcurrency exceeds MaxConcurrency",
+			RouterCfg: RouterConfig{
+				MaxConcurrency:      2,
+				ReservedConcurrency: 2,
+			},
+			Err: ErrInvalidRouterConfig,
+		},
+		{
+			Name: "ReservedConcurrency without MaxConcurrency",
+			RouterCfg: RouterConfig{
+				ReservedConcurrency: 2,
+			},
+			Err: ErrInvalidRouterConfig,
+		},
+		{
+			Name: "Negative RetryAfter",
+			RouterCfg: RouterConfig{
+				RetryAfter: -1,
+			},
+			Err: ErrInvalidRouterConfig,
+		},
+		{
+			Name: "Queue settings without PostFunc",
+			RouterCfg: RouterConfig{
+				PostFuncWorkers: 2,
+			},
+			Err: ErrInvalidRouterConfig,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.RouterCfg.Validate()
+			if !errors.Is(err, tc.Err) {
+				t.Errorf("Unexpected error validating router config: %s", err)
+			}
+
+			router, err := New(tc.RouterCfg)
+			if !errors.Is(err, tc.Err) {
+				t.Fatalf("Unexpected error creating router: %s", err)
+			}
+			if err == nil {
+				router.Close()
+			}
+		})
+	}
+}
+
 func ExampleNew() {
 	// Create a new router
 	router, err := New(RouterConfig{})
@@ -397,3 +2120,219 @@ func ExampleNew() {
 
 	// Output: Hello World!
 }
+
+type spanKey struct{}
+
+func TestRouterSpanFunc(t *testing.T) {
+	var started []string
+	var ended []error
+	router, err := New(RouterConfig{
+		SpanFunc: func(ctx context.Context, req CallbackRequest) (context.Context, func(error)) {
+			name := fmt.Sprintf("%s:%s:%s", req.Namespace, req.Capability, req.Operation)
+			started = append(started, name)
+			return context.WithValue(ctx, spanKey{}, name), func(err error) {
+				ended = append(ended, err)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "tracing",
+		Operation:  "span",
+		CtxFunc: func(ctx context.Context, input []byte) ([]byte, error) {
+			span, _ := ctx.Value(spanKey{}).(string)
+			if string(input) == "fail" {
+				return nil, ErrTestError
+			}
+			return []byte(span), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	t.Run("Span context propagated", func(t *testing.T) {
+		rsp, err := router.Callback(context.Background(), "default", "tracing", "span", []byte(""))
+		if err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+		if string(rsp) != "default:tracing:span" {
+			t.Errorf("Expected span context within callback, got: %q", rsp)
+		}
+		if len(started) != 1 || len(ended) != 1 || ended[0] != nil {
+			t.Errorf("Unexpected spans: started %v, ended %v", started, ended)
+		}
+	})
+
+	t.Run("Error recorded", func(t *testing.T) {
+		_, err := router.Callback(context.Background(), "default", "tracing", "span", []byte("fail"))
+		if !errors.Is(err, ErrTestError) {
+			t.Fatalf("Expected callback error, got: %s", err)
+		}
+		if len(ended) != 2 || !errors.Is(ended[1], ErrTestError) {
+			t.Errorf("Expected error to be recorded on span, got: %v", ended)
+		}
+	})
+
+	t.Run("Span for missing callback", func(t *testing.T) {
+		_, err := router.Callback(context.Background(), "default", "tracing", "missing", []byte(""))
+		if !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Expected not found error, got: %s", err)
+		}
+		if len(started) != 3 || started[2] != "default:tracing:missing" {
+			t.Errorf("Unexpected spans started: %v", started)
+		}
+		if len(ended) != 3 || !errors.Is(ended[2], ErrNotFound) {
+			t.Errorf("Expected not found error to be recorded on span, got: %v", ended)
+		}
+	})
+
+	t.Run("Span for overloaded callback", func(t *testing.T) {
+		var mu sync.Mutex
+		var overloaded []error
+		release := make(chan struct{})
+		limited, err := New(RouterConfig{
+			MaxConcurrency: 1,
+			SpanFunc: func(ctx context.Context, _ CallbackRequest) (context.Context, func(error)) {
+				return ctx, func(err error) {
+					mu.Lock()
+					defer mu.Unlock()
+					overloaded = append(overloaded, err)
+				}
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error creating router: %s", err)
+		}
+		defer limited.Close()
+
+		started := make(chan struct{})
+		err = limited.RegisterCallbackFunc("default", "tracing", "block", func([]byte) ([]byte, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error registering callback: %s", err)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = limited.Callback(context.Background(), "default", "tracing", "block", []byte(""))
+		}()
+		<-started
+
+		_, err = limited.Callback(context.Background(), "default", "tracing", "block", []byte(""))
+		if !errors.Is(err, ErrOverloaded) {
+			t.Fatalf("Expected overloaded error, got: %s", err)
+		}
+		close(release)
+		<-done
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(overloaded) != 2 || !errors.Is(overloaded[0], ErrOverloaded) {
+			t.Errorf("Expected overloaded error to be recorded on span, got: %v", overloaded)
+		}
+	})
+}
+
+func TestRouterRegisterCallbackFunc(t *testing.T) {
+	router, err := New(RouterConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	fn := func(input []byte) ([]byte, error) {
+		return input, nil
+	}
+
+	t.Run("Registered", func(t *testing.T) {
+		if err := router.RegisterCallbackFunc("default", "echo", "hello", fn); err != nil {
+			t.Fatalf("Unexpected error registering callback: %s", err)
+		}
+
+		rsp, err := router.Callback(context.Background(), "default", "echo", "hello", []byte("hi"))
+		if err != nil || string(rsp) != "hi" {
+			t.Errorf("Unexpected callback response: %q - %v", rsp, err)
+		}
+	})
+
+	t.Run("Exists", func(t *testing.T) {
+		err := router.RegisterCallbackFunc("default", "echo", "hello", fn)
+		if !errors.Is(err, ErrCallbackExists) {
+			t.Errorf("Expected callback exists error, got: %s", err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		if err := router.RegisterCallbackFunc("", "echo", "hello", fn); !errors.Is(err, ErrInvalidNamespace) {
+			t.Errorf("Expected invalid namespace error, got: %s", err)
+		}
+		if err := router.RegisterCallbackFunc("default", "echo", "nil", nil); !errors.Is(err, ErrInvalidFunc) {
+			t.Errorf("Expected invalid func error, got: %s", err)
+		}
+	})
+}
+
+func TestRouterPreFuncTransform(t *testing.T) {
+	var results []CallbackResult
+	router, err := New(RouterConfig{
+		PreFunc: func(req CallbackRequest) ([]byte, error) {
+			if string(req.Input) == "keep" {
+				return nil, nil
+			}
+			return bytes.ToUpper(req.Input), nil
+		},
+		PostFunc: func(res CallbackResult) {
+			results = append(results, res)
+		},
+		SyncPostFunc: true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "transform",
+		Operation:  "echo",
+		Func: func(input []byte) ([]byte, error) {
+			return input, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	t.Run("Transformed", func(t *testing.T) {
+		rsp, err := router.Callback(context.Background(), "default", "transform", "echo", []byte("hello"))
+		if err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+		if string(rsp) != "HELLO" {
+			t.Errorf("Expected transformed input within callback, got: %q", rsp)
+		}
+		if len(results) != 1 || string(results[0].Input) != "HELLO" {
+			t.Errorf("Expected transformed input within CallbackResult, got: %+v", results)
+		}
+	})
+
+	t.Run("Unchanged", func(t *testing.T) {
+		rsp, err := router.Callback(context.Background(), "default", "transform", "echo", []byte("keep"))
+		if err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+		if string(rsp) != "keep" {
+			t.Errorf("Expected unchanged input within callback, got: %q", rsp)
+		}
+	})
+}
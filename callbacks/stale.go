@@ -0,0 +1,58 @@
+package callbacks
+
+import (
+	"bytes"
+	"sync"
+)
+
+const (
+	// DefaultStaleCacheSize is the maximum number of responses retained per callback for ServeStaleOnError.
+	DefaultStaleCacheSize = 1000
+)
+
+// staleCache retains the most recent successful response for each callback input, used to serve stale
+// responses when a callback returns an error.
+type staleCache struct {
+	sync.RWMutex
+
+	// responses maps callback inputs to their most recent successful response.
+	responses map[string][]byte
+
+	// size is the maximum number of responses retained.
+	size int
+}
+
+// newStaleCache creates a new stale response cache.
+func newStaleCache(size int) *staleCache {
+	return &staleCache{
+		responses: make(map[string][]byte),
+		size:      size,
+	}
+}
+
+// get returns a copy of the most recent successful response for the input.
+func (c *staleCache) get(input []byte) ([]byte, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	rsp, ok := c.responses[string(input)]
+	if !ok {
+		return nil, false
+	}
+	return bytes.Clone(rsp), true
+}
+
+// put records a copy of the successful response for the input. If the cache is full, an arbitrary response
+// is evicted.
+func (c *staleCache) put(input, rsp []byte) {
+	c.Lock()
+	defer c.Unlock()
+
+	if _, ok := c.responses[string(input)]; !ok && len(c.responses) >= c.size {
+		for k := range c.responses {
+			delete(c.responses, k)
+			break
+		}
+	}
+	c.responses[string(input)] = bytes.Clone(rsp)
+}
@@ -0,0 +1,109 @@
+package callbacks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRouterServeStaleOnError(t *testing.T) {
+	var mu sync.Mutex
+	var results []CallbackResult
+	router, err := New(RouterConfig{
+		PostFunc: func(res CallbackResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, res)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+
+	var failing atomic.Bool
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:         "default",
+		Capability:        "kv",
+		Operation:         "get",
+		ServeStaleOnError: true,
+		Func: func(input []byte) ([]byte, error) {
+			if failing.Load() {
+				return nil, ErrTestError
+			}
+			return append([]byte("value-"), input...), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	call := func(input string) ([]byte, error) {
+		return router.Callback(context.Background(), "default", "kv", "get", []byte(input))
+	}
+
+	if _, err := call("a"); err != nil {
+		t.Fatalf("Unexpected error calling callback: %s", err)
+	}
+	failing.Store(true)
+
+	t.Run("Stale response", func(t *testing.T) {
+		rsp, err := call("a")
+		if err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+		if string(rsp) != "value-a" {
+			t.Errorf("Unexpected stale response: %s", rsp)
+		}
+	})
+
+	t.Run("No prior response", func(t *testing.T) {
+		_, err := call("b")
+		if !errors.Is(err, ErrTestError) {
+			t.Errorf("Expected callback error, got: %s", err)
+		}
+	})
+
+	// Wait for PostFunc to complete
+	router.Close()
+
+	t.Run("PostFunc results", func(t *testing.T) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(results) != 3 {
+			t.Fatalf("Unexpected number of PostFunc results: %d, expected: 3", len(results))
+		}
+		if results[0].Stale || results[0].Err != nil {
+			t.Errorf("Unexpected result for successful callback: %+v", results[0])
+		}
+		if !results[1].Stale || !errors.Is(results[1].Err, ErrTestError) {
+			t.Errorf("Unexpected result for stale callback: %+v", results[1])
+		}
+		if results[2].Stale || !errors.Is(results[2].Err, ErrTestError) {
+			t.Errorf("Unexpected result for failed callback: %+v", results[2])
+		}
+	})
+}
+
+func TestStaleCacheEviction(t *testing.T) {
+	c := newStaleCache(2)
+	c.put([]byte("a"), []byte("1"))
+	c.put([]byte("b"), []byte("2"))
+	c.put([]byte("b"), []byte("3"))
+
+	if len(c.responses) != 2 {
+		t.Fatalf("Unexpected cache size: %d, expected: 2", len(c.responses))
+	}
+	if rsp, ok := c.get([]byte("b")); !ok || string(rsp) != "3" {
+		t.Errorf("Unexpected cached response: %s", rsp)
+	}
+
+	c.put([]byte("c"), []byte("4"))
+	if len(c.responses) != 2 {
+		t.Errorf("Unexpected cache size after eviction: %d, expected: 2", len(c.responses))
+	}
+	if rsp, ok := c.get([]byte("c")); !ok || string(rsp) != "4" {
+		t.Errorf("Unexpected cached response: %s", rsp)
+	}
+}
@@ -0,0 +1,35 @@
+package callbacks
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// WildcardOperation is the operation name used to register a callback handling every operation under
+	// a namespace and capability. Callbacks registered with an exact operation always take precedence over
+	// the wildcard callback.
+	WildcardOperation = "*"
+)
+
+// operationKey is the context key for the operation requested of a wildcard callback.
+type operationKey struct{}
+
+// match returns the callback registered for the namespace, capability, and operation, falling back to the
+// wildcard callback registered for the namespace and capability.
+func (r *Router) match(namespace, capability, operation string) (*Callback, bool) {
+	if cb, ok := r.lookup(fmt.Sprintf("%s:%s:%s", namespace, capability, operation)); ok {
+		return cb, true
+	}
+	return r.lookup(fmt.Sprintf("%s:%s:%s", namespace, capability, WildcardOperation))
+}
+
+// OperationFromContext returns the operation requested by the guest from the context provided to a
+// wildcard callback's CtxFunc, allowing a single callback to handle every operation under a capability.
+// The operation is also available to PreFunc and PostFunc via CallbackRequest and CallbackResult.
+//
+// It returns false for callbacks registered with an exact operation.
+func OperationFromContext(ctx context.Context) (string, bool) {
+	op, ok := ctx.Value(operationKey{}).(string)
+	return op, ok
+}
@@ -0,0 +1,147 @@
+package callbacks
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type WildcardTestCase struct {
+	Name      string
+	Operation string
+	Output    string
+	Err       error
+}
+
+func TestRouterWildcard(t *testing.T) {
+	var requests []CallbackRequest
+	router, err := New(RouterConfig{
+		PreFunc: func(req CallbackRequest) ([]byte, error) {
+			requests = append(requests, req)
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "metrics",
+		Operation:  WildcardOperation,
+		CtxFunc: func(ctx context.Context, _ []byte) ([]byte, error) {
+			op, ok := OperationFromContext(ctx)
+			if !ok {
+				return nil, ErrTestError
+			}
+			return []byte("wildcard " + op), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	err = router.RegisterCallback(CallbackConfig{
+		Namespace:  "default",
+		Capability: "metrics",
+		Operation:  "gauge.set",
+		CtxFunc: func(ctx context.Context, _ []byte) ([]byte, error) {
+			if _, ok := OperationFromContext(ctx); ok {
+				return nil, ErrTestError
+			}
+			return []byte("exact"), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering callback: %s", err)
+	}
+
+	tt := []WildcardTestCase{
+		{Name: "Wildcard match", Operation: "counter.inc", Output: "wildcard counter.inc"},
+		{Name: "Another wildcard match", Operation: "counter.dec", Output: "wildcard counter.dec"},
+		{Name: "Exact match takes precedence", Operation: "gauge.set", Output: "exact"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			requests = nil
+			rsp, err := router.Callback(context.Background(), "default", "metrics", tc.Operation, []byte(""))
+			if !errors.Is(err, tc.Err) {
+				t.Fatalf("Unexpected error calling callback: %s, expected: %s", err, tc.Err)
+			}
+			if string(rsp) != tc.Output {
+				t.Errorf("Unexpected output: %s, expected: %s", rsp, tc.Output)
+			}
+			if len(requests) != 1 || requests[0].Operation != tc.Operation {
+				t.Errorf("Expected request to carry operation %s, got: %v", tc.Operation, requests)
+			}
+		})
+	}
+
+	t.Run("Other capability not matched", func(t *testing.T) {
+		_, err := router.Callback(context.Background(), "default", "logs", "counter.inc", []byte(""))
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected not found error, got: %s", err)
+		}
+	})
+
+	t.Run("Lookup", func(t *testing.T) {
+		cb, err := router.Lookup("default", "metrics", "counter.inc")
+		if err != nil {
+			t.Fatalf("Unexpected error looking up callback: %s", err)
+		}
+		if cb.Operation != WildcardOperation {
+			t.Errorf("Unexpected callback operation: %s, expected: %s", cb.Operation, WildcardOperation)
+		}
+
+		cb, err = router.Lookup("default", "metrics", "gauge.set")
+		if err != nil {
+			t.Fatalf("Unexpected error looking up callback: %s", err)
+		}
+		if cb.Operation != "gauge.set" {
+			t.Errorf("Unexpected callback operation: %s, expected: gauge.set", cb.Operation)
+		}
+	})
+}
+
+func TestRouterOnResolve(t *testing.T) {
+	var resolved [][2]string
+	router, err := New(RouterConfig{
+		OnResolve: func(requested, matched string) {
+			resolved = append(resolved, [2]string{requested, matched})
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating router: %s", err)
+	}
+	defer router.Close()
+
+	for _, op := range []string{WildcardOperation, "gauge.set"} {
+		err = router.RegisterCallback(CallbackConfig{
+			Namespace:  "default",
+			Capability: "metrics",
+			Operation:  op,
+			Func: func(input []byte) ([]byte, error) {
+				return input, nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error registering callback: %s", err)
+		}
+	}
+
+	for _, op := range []string{"counter.inc", "gauge.set", WildcardOperation} {
+		if _, err := router.Callback(context.Background(), "default", "metrics", op, []byte("")); err != nil {
+			t.Fatalf("Unexpected error calling callback: %s", err)
+		}
+	}
+
+	// Only the non-exact match is reported
+	if len(resolved) != 1 {
+		t.Fatalf("Unexpected resolutions: %v, expected one", resolved)
+	}
+	if resolved[0][0] != "default:metrics:counter.inc" || resolved[0][1] != "default:metrics:*" {
+		t.Errorf("Unexpected resolution: %v", resolved[0])
+	}
+}
@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"context"
+	"sync"
+)
+
+// RunBatch calls the user-provided function once for each of the user-provided payloads, fanning the
+// invocations out across the module pool. It returns the responses and errors of each invocation, in the
+// same order as the payloads; an entry of the returned errors is nil if the invocation succeeded.
+//
+// Invocations are made via RunWithContext, with the provided context shared by every invocation. At most
+// PoolSize invocations run concurrently, further limited by MaxConcurrentRuns when configured, so that
+// batch invocations queue for instances rather than timing out. If the context is done, the remaining
+// invocations return the context error without invoking the guest.
+func (m *Module) RunBatch(ctx context.Context, function string, payloads [][]byte) ([][]byte, []error) {
+	responses := make([][]byte, len(payloads))
+	errs := make([]error, len(payloads))
+
+	workers := int(m.poolSize.Load())
+	if m.runs != nil && cap(m.runs) < workers {
+		workers = cap(m.runs)
+	}
+	if len(payloads) < workers {
+		workers = len(payloads)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				responses[i], errs[i] = m.RunWithContext(ctx, function, payloads[i])
+			}
+		}()
+	}
+
+	for i := range payloads {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return responses, errs
+}
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRunBatch(t *testing.T) {
+	var calls atomic.Int64
+	s, err := New(ServerConfig{
+		Callback: func(_ context.Context, _, _, _ string, payload []byte) ([]byte, error) {
+			calls.Add(1)
+			if string(payload) == "fail" {
+				return nil, ErrTestCallback
+			}
+			return []byte(""), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 2,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Ordered results", func(t *testing.T) {
+		payloads := make([][]byte, 10)
+		for i := range payloads {
+			payloads[i] = []byte(strconv.Itoa(i))
+		}
+		payloads[3] = []byte("fail")
+
+		responses, errs := m.RunBatch(context.Background(), "example", payloads)
+		if len(responses) != len(payloads) || len(errs) != len(payloads) {
+			t.Fatalf("Unexpected number of results: %d responses, %d errors", len(responses), len(errs))
+		}
+		for i := range payloads {
+			if i == 3 {
+				if errs[i] == nil {
+					t.Errorf("Expected error for payload %d", i)
+				}
+				continue
+			}
+			if errs[i] != nil || string(responses[i]) != "Hello World!" {
+				t.Errorf("Unexpected result for payload %d: %q - %v", i, responses[i], errs[i])
+			}
+		}
+		if n := calls.Load(); n != int64(len(payloads)) {
+			t.Errorf("Unexpected number of host calls: %d", n)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		responses, errs := m.RunBatch(context.Background(), "example", nil)
+		if len(responses) != 0 || len(errs) != 0 {
+			t.Errorf("Unexpected results for empty batch: %v, %v", responses, errs)
+		}
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, errs := m.RunBatch(ctx, "example", [][]byte{[]byte("a"), []byte("b")})
+		for i, err := range errs {
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected canceled error for payload %d, got: %v", i, err)
+			}
+		}
+	})
+}
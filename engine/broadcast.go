@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// InvokeResult is the result of invoking a function on a single module.
+type InvokeResult struct {
+	// Response is the response returned by the guest.
+	Response []byte
+
+	// Err is the error returned by the invocation, if any.
+	Err error
+}
+
+// InvokeAll calls the user-provided function with the user-provided payload on every loaded module that
+// provides it, returning the result of each invocation keyed by module name. This is useful for broadcast
+// operations, such as instructing every guest to reload its configuration.
+//
+// Modules are invoked concurrently via RunWithContext. Functions registered with a waPC guest SDK are not
+// WebAssembly exports, so modules are skipped when the guest reports the function is not found; skipped
+// modules are omitted from the results.
+func (s *Server) InvokeAll(ctx context.Context, function string, payload []byte) map[string]InvokeResult {
+	s.RLock()
+	modules := make([]*Module, 0, len(s.modules))
+	for _, m := range s.modules {
+		modules = append(modules, m)
+	}
+	s.RUnlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]InvokeResult, len(modules))
+	for _, m := range modules {
+		wg.Add(1)
+		go func(m *Module) {
+			defer wg.Done()
+
+			rsp, err := m.RunWithContext(ctx, function, payload)
+			if errors.Is(err, ErrFunctionNotFound) {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[m.Name] = InvokeResult{Response: rsp, Err: err}
+		}(m)
+	}
+	wg.Wait()
+
+	return results
+}
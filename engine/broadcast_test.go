@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestServerInvokeAll(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(_ context.Context, _, _, _ string, payload []byte) ([]byte, error) {
+			if string(payload) == "fail" {
+				return nil, ErrTestCallback
+			}
+			return []byte(""), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	for _, name := range []string{"AModule", "BModule", "CModule"} {
+		err = s.LoadModule(ModuleConfig{
+			Name:     name,
+			PoolSize: 1,
+			Filepath: "../testdata/hello-go/hello.wasm",
+		})
+		if err != nil {
+			t.Fatalf("Failed to load module - %s", err)
+		}
+	}
+
+	t.Run("All modules", func(t *testing.T) {
+		results := s.InvokeAll(context.Background(), "example", []byte("hello"))
+		if len(results) != 3 {
+			t.Fatalf("Unexpected number of results: %d, expected: 3", len(results))
+		}
+		for name, r := range results {
+			if r.Err != nil {
+				t.Errorf("Unexpected error invoking %s - %s", name, r.Err)
+			}
+			if string(r.Response) != "Hello World!" {
+				t.Errorf("Unexpected response from %s: %s", name, r.Response)
+			}
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		results := s.InvokeAll(context.Background(), "example", []byte("fail"))
+		if len(results) != 3 {
+			t.Fatalf("Unexpected number of results: %d, expected: 3", len(results))
+		}
+		for name, r := range results {
+			if r.Err == nil {
+				t.Errorf("Expected error invoking %s", name)
+			}
+		}
+	})
+
+	t.Run("Function not found", func(t *testing.T) {
+		results := s.InvokeAll(context.Background(), "ThisBetterFail", []byte("hello"))
+		if len(results) != 0 {
+			t.Errorf("Unexpected results for missing function: %v", results)
+		}
+	})
+
+	t.Run("Canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		results := s.InvokeAll(ctx, "example", []byte("hello"))
+		for name, r := range results {
+			if !errors.Is(r.Err, context.Canceled) {
+				t.Errorf("Expected canceled error invoking %s, got: %s", name, r.Err)
+			}
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	wapc "github.com/wapc/wapc-go"
+)
+
+// Buffer holds the response of a RunBuffers invocation together with the module instance that produced
+// it. The response refers directly to the guest's result region within the instance's linear memory, so
+// the instance is held, and unavailable to other callers, until the Buffer is released.
+type Buffer struct {
+	sync.Mutex
+
+	// module is the module the instance belongs to.
+	module *Module
+
+	// instance is the module instance held by the buffer.
+	instance wapc.Instance
+
+	// response is the guest response.
+	response []byte
+
+	// released is set once the buffer has been released.
+	released bool
+}
+
+// RunBuffers calls the user-provided function with the user-provided payload in the same way as Run, but
+// returns the response without copying it out of guest memory. This reduces allocations for large responses
+// in hot paths.
+//
+// The instance that produced the response, and the concurrent run slot taken for the call, are held until
+// the returned Buffer is released, callers must call Release once they are done with the response. Responses
+// that are decompressed or captured from standard output, see ModuleConfig, are held in host memory but
+// follow the same lifetime.
+func (m *Module) RunBuffers(function string, in []byte) (b *Buffer, err error) {
+	var rsp []byte
+	defer m.recordMetrics(function, in, time.Now(), &rsp, &err)
+
+	if err := m.enterRun(m.ctx); err != nil {
+		return nil, err
+	}
+
+	// The run slot is held by the Buffer until it is released
+	defer func() {
+		if err != nil {
+			m.exitRun()
+		}
+	}()
+
+	function, in, err = m.prepare(function, in)
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := m.acquire(m.ctx, m.pool, function)
+	if err != nil {
+		return nil, err
+	}
+
+	// Invoke the module, keeping the instance if the response is available
+	r, aborted, err := m.invoke(m.invokeContext(m.ctx), i, function, in)
+	if err == nil {
+		r, err = decompress(m.codec, r, m.maxDecompressedSize)
+	}
+	if err != nil {
+		m.release(m.pool, i, aborted)
+		return nil, err
+	}
+
+	rsp = r
+	return &Buffer{module: m, instance: i, response: r}, nil
+}
+
+// Bytes returns the response held by the Buffer. The returned slice refers to guest memory and must not be
+// used or retained after the Buffer is released; copy it to keep the response. Once the Buffer is released,
+// Bytes returns nil.
+func (b *Buffer) Bytes() []byte {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.released {
+		return nil
+	}
+	return b.response
+}
+
+// Release returns the instance held by the Buffer to the module pool and frees its concurrent run slot.
+// Releasing a Buffer more than once has no effect.
+func (b *Buffer) Release() {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.released {
+		return
+	}
+	b.released = true
+	b.response = nil
+
+	b.module.release(b.module.pool, b.instance, false)
+	b.module.exitRun()
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestModuleRunBuffers(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Failed invocation", func(t *testing.T) {
+		if _, err := m.RunBuffers("ThisBetterFail", []byte("hello")); !errors.Is(err, ErrFunctionNotFound) {
+			t.Errorf("Expected function not found error, got: %s", err)
+		}
+		if m.inUse.Load() != 0 {
+			t.Errorf("Unexpected in use instances: %d, expected: 0", m.inUse.Load())
+		}
+	})
+
+	buf, err := m.RunBuffers("example", []byte("hello"))
+	if err != nil {
+		t.Fatalf("Unexpected error running module - %s", err)
+	}
+
+	t.Run("Response", func(t *testing.T) {
+		if string(buf.Bytes()) != "Hello World!" {
+			t.Errorf("Unexpected response: %s", buf.Bytes())
+		}
+	})
+
+	t.Run("Instance held", func(t *testing.T) {
+		if m.inUse.Load() != 1 {
+			t.Errorf("Unexpected in use instances: %d, expected: 1", m.inUse.Load())
+		}
+	})
+
+	t.Run("Released", func(t *testing.T) {
+		buf.Release()
+		buf.Release()
+		if buf.Bytes() != nil {
+			t.Errorf("Unexpected response after release: %s", buf.Bytes())
+		}
+		if m.inUse.Load() != 0 {
+			t.Errorf("Unexpected in use instances: %d, expected: 0", m.inUse.Load())
+		}
+		if _, err := m.Run("example", []byte("hello")); err != nil {
+			t.Errorf("Unexpected error running module after release - %s", err)
+		}
+	})
+}
+
+func TestModuleRunBuffersConcurrencyLimit(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:              "AModule",
+		PoolSize:          2,
+		MaxConcurrentRuns: 1,
+		Filepath:          "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Failed invocation frees slot", func(t *testing.T) {
+		if _, err := m.RunBuffers("ThisBetterFail", []byte("hello")); !errors.Is(err, ErrFunctionNotFound) {
+			t.Errorf("Expected function not found error, got: %s", err)
+		}
+		if _, err := m.Run("example", []byte("hello")); err != nil {
+			t.Errorf("Unexpected error running module - %s", err)
+		}
+	})
+
+	buf, err := m.RunBuffers("example", []byte("hello"))
+	if err != nil {
+		t.Fatalf("Unexpected error running module - %s", err)
+	}
+
+	t.Run("Slot held", func(t *testing.T) {
+		if _, err := m.Run("example", []byte("hello")); !errors.Is(err, ErrConcurrencyLimit) {
+			t.Errorf("Expected concurrency limit error, got: %v", err)
+		}
+		if _, err := m.RunBuffers("example", []byte("hello")); !errors.Is(err, ErrConcurrencyLimit) {
+			t.Errorf("Expected concurrency limit error, got: %v", err)
+		}
+	})
+
+	t.Run("Slot released", func(t *testing.T) {
+		buf.Release()
+		buf.Release()
+		if _, err := m.Run("example", []byte("hello")); err != nil {
+			t.Errorf("Unexpected error running module after release - %s", err)
+		}
+	})
+}
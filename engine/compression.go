@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	// ErrUnsupportedCodec is returned when a ModuleConfig specifies an unsupported CompressionCodec.
+	ErrUnsupportedCodec = errors.New("unsupported compression codec")
+
+	// ErrDecompressedSizeExceeded is returned when a guest response decompresses to more than the module's
+	// MaxDecompressedSize, protecting the host from decompression bombs.
+	ErrDecompressedSizeExceeded = errors.New("decompressed payload exceeds maximum size")
+
+	// zstdEncoder is the shared zstd encoder; EncodeAll is safe for concurrent use.
+	zstdEncoder, _ = zstd.NewWriter(nil)
+)
+
+// CompressionCodec is the compression applied to payloads crossing the host/guest boundary.
+//
+// Compression is negotiated when a module is loaded. A guest advertises support for a codec by exporting a
+// function named "wapc_codec_" followed by the codec name, for example "wapc_codec_gzip". The exported
+// function is never called; only its presence is checked.
+//
+// When a codec is negotiated, the request payload passed to the guest is the compressed form of the payload
+// provided to Run, and the guest must respond with a payload compressed using the same codec. The function
+// name and any guest errors are not compressed. If the guest does not advertise support for the configured
+// codec, payloads are passed uncompressed.
+type CompressionCodec string
+
+const (
+	// CompressionNone disables payload compression.
+	CompressionNone CompressionCodec = ""
+
+	// CompressionGzip compresses payloads using the gzip format (RFC 1952).
+	CompressionGzip CompressionCodec = "gzip"
+
+	// CompressionZstd compresses payloads using the Zstandard format (RFC 8878).
+	CompressionZstd CompressionCodec = "zstd"
+
+	// DefaultMaxDecompressedSize is the default maximum size, in bytes, of a decompressed guest response.
+	DefaultMaxDecompressedSize = 64 << 20
+
+	// codecExportPrefix is the prefix of the function exported by guests to advertise codec support.
+	codecExportPrefix = "wapc_codec_"
+)
+
+// negotiateCodec returns the codec to use based on the functions the guest exports.
+func negotiateCodec(codec CompressionCodec, exports map[string]struct{}) (CompressionCodec, error) {
+	switch codec {
+	case CompressionNone:
+		return CompressionNone, nil
+	case CompressionGzip, CompressionZstd:
+	default:
+		return CompressionNone, fmt.Errorf("%w: %s", ErrUnsupportedCodec, codec)
+	}
+
+	if _, ok := exports[codecExportPrefix+string(codec)]; ok {
+		return codec, nil
+	}
+
+	return CompressionNone, nil
+}
+
+// compress returns the payload compressed with the provided codec.
+func compress(codec CompressionCodec, payload []byte) ([]byte, error) {
+	switch codec {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(payload); err != nil {
+			return nil, fmt.Errorf("unable to compress payload - %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("unable to compress payload - %w", err)
+		}
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		return zstdEncoder.EncodeAll(payload, nil), nil
+	default:
+		return payload, nil
+	}
+}
+
+// decompress returns the payload decompressed with the provided codec. Payloads decompressing to more than
+// limit bytes return ErrDecompressedSizeExceeded.
+func decompress(codec CompressionCodec, payload []byte, limit int64) ([]byte, error) {
+	var r io.Reader
+	switch codec {
+	case CompressionGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("unable to decompress payload - %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	case CompressionZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(payload), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("unable to decompress payload - %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return payload, nil
+	}
+
+	// Read one byte past the limit to detect oversized payloads
+	b, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decompress payload - %w", err)
+	}
+	if int64(len(b)) > limit {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrDecompressedSizeExceeded, limit)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCompressionRoundTrip(t *testing.T) {
+	payload := bytes.Repeat([]byte("Hello World!"), 100)
+
+	for _, codec := range []CompressionCodec{CompressionNone, CompressionGzip, CompressionZstd} {
+		t.Run("Codec "+string(codec), func(t *testing.T) {
+			c, err := compress(codec, payload)
+			if err != nil {
+				t.Fatalf("Unexpected error compressing payload - %s", err)
+			}
+
+			d, err := decompress(codec, c, DefaultMaxDecompressedSize)
+			if err != nil {
+				t.Fatalf("Unexpected error decompressing payload - %s", err)
+			}
+
+			if !bytes.Equal(d, payload) {
+				t.Errorf("Decompressed payload does not match original")
+			}
+		})
+	}
+
+	for _, codec := range []CompressionCodec{CompressionGzip, CompressionZstd} {
+		t.Run("Invalid payload "+string(codec), func(t *testing.T) {
+			_, err := decompress(codec, payload, DefaultMaxDecompressedSize)
+			if err == nil {
+				t.Errorf("Expected error decompressing invalid payload")
+			}
+		})
+
+		t.Run("Size limit "+string(codec), func(t *testing.T) {
+			c, err := compress(codec, payload)
+			if err != nil {
+				t.Fatalf("Unexpected error compressing payload - %s", err)
+			}
+
+			if _, err := decompress(codec, c, int64(len(payload))); err != nil {
+				t.Errorf("Unexpected error decompressing payload at the limit - %s", err)
+			}
+
+			_, err = decompress(codec, c, int64(len(payload)-1))
+			if !errors.Is(err, ErrDecompressedSizeExceeded) {
+				t.Errorf("Expected decompressed size exceeded error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestCompressionNegotiation(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	t.Run("Unsupported codec", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{
+			Name:        "unsupported",
+			PoolSize:    1,
+			Filepath:    "../testdata/hello-go/hello.wasm",
+			Compression: CompressionCodec("lz4"),
+		})
+		if !errors.Is(err, ErrUnsupportedCodec) {
+			t.Errorf("Expected unsupported codec error, got: %s", err)
+		}
+	})
+
+	t.Run("Guest without codec support", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{
+			Name:        "uncompressed",
+			PoolSize:    1,
+			Filepath:    "../testdata/hello-go/hello.wasm",
+			Compression: CompressionGzip,
+		})
+		if err != nil {
+			t.Fatalf("Failed to load module - %s", err)
+		}
+
+		m, err := s.Module("uncompressed")
+		if err != nil {
+			t.Fatalf("Cannot find module - %s", err)
+		}
+		if m.codec != CompressionNone {
+			t.Errorf("Unexpected negotiated codec: %s, expected none", m.codec)
+		}
+
+		_, err = m.Run("example", []byte("hello"))
+		if err != nil {
+			t.Errorf("Unexpected error running module - %s", err)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrConcurrencyLimit is returned when a module is already running its MaxConcurrentRuns invocations.
+	ErrConcurrencyLimit = errors.New("module concurrency limit reached")
+)
+
+// enterRun takes one of the module's concurrent run slots. If every slot is taken, it returns
+// ErrConcurrencyLimit, or waits for a slot when BlockOnConcurrencyLimit is enabled. It returns immediately
+// for modules without MaxConcurrentRuns.
+//
+// Rejections are reported as a ConcurrencyLimitError carrying a retry-after hint. If the context is done while
+// waiting, the returned error wraps both ErrConcurrencyLimit and the context error.
+func (m *Module) enterRun(ctx context.Context) error {
+	if m.runs == nil {
+		return nil
+	}
+
+	select {
+	case m.runs <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if !m.config.BlockOnConcurrencyLimit {
+		return m.concurrencyLimit(fmt.Errorf("%w: %d runs in progress", ErrConcurrencyLimit, cap(m.runs)))
+	}
+
+	select {
+	case m.runs <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return m.concurrencyLimit(fmt.Errorf("%w - %w", ErrConcurrencyLimit, context.Cause(ctx)))
+	}
+}
+
+// exitRun releases a concurrent run slot taken by enterRun.
+func (m *Module) exitRun() {
+	if m.runs != nil {
+		<-m.runs
+	}
+}
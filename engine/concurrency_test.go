@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMaxConcurrentRuns(t *testing.T) {
+	started := make(chan struct{}, 10)
+	unblock := make(chan struct{})
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) {
+			started <- struct{}{}
+			<-unblock
+			return []byte(""), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	t.Run("Invalid config", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{
+			Name:              "Invalid",
+			Filepath:          "../testdata/hello-go/hello.wasm",
+			MaxConcurrentRuns: -1,
+		})
+		if !errors.Is(err, ErrInvalidModuleConfig) {
+			t.Errorf("Expected invalid module config error, got: %s", err)
+		}
+	})
+
+	for _, block := range []bool{false, true} {
+		name := "Limited"
+		if block {
+			name = "Blocking"
+		}
+		t.Run(name, func(t *testing.T) {
+			err := s.LoadModule(ModuleConfig{
+				Name:                    name,
+				Filepath:                "../testdata/hello-go/hello.wasm",
+				PoolSize:                10,
+				MaxConcurrentRuns:       1,
+				BlockOnConcurrencyLimit: block,
+			})
+			if err != nil {
+				t.Fatalf("Failed to load module - %s", err)
+			}
+
+			m, err := s.Module(name)
+			if err != nil {
+				t.Fatalf("Cannot find module - %s", err)
+			}
+
+			// Hold the only slot within the callback
+			done := make(chan error, 1)
+			go func() {
+				_, err := m.Run("example", []byte("hello"))
+				done <- err
+			}()
+			<-started
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			_, err = m.RunWithContext(ctx, "example", []byte("hello"))
+			if !errors.Is(err, ErrConcurrencyLimit) {
+				t.Errorf("Expected concurrency limit error, got: %s", err)
+			}
+			var limitErr *ConcurrencyLimitError
+			if !errors.As(err, &limitErr) || limitErr.RetryAfter < DefaultRetryAfter {
+				t.Errorf("Expected concurrency limit error with retry-after hint, got: %v", err)
+			}
+			if block && !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Expected blocked run to wait for the deadline, got: %s", err)
+			}
+
+			unblock <- struct{}{}
+			if err := <-done; err != nil {
+				t.Errorf("Unexpected error running module - %s", err)
+			}
+
+			// The slot is released once the run completes
+			go func() {
+				<-started
+				unblock <- struct{}{}
+			}()
+			if _, err := m.Run("example", []byte("hello")); err != nil {
+				t.Errorf("Unexpected error running module - %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package engine
+
+import (
+	"context"
+)
+
+// mergeContext returns a context derived from ctx, carrying its values and deadline, that is also canceled
+// when the lifetime context is done. The cause of the lifetime context is used as the cancellation cause.
+//
+// The returned cancel function must be called once the merged context is no longer needed; it releases the
+// resources associated with watching the lifetime context. No goroutine is held while waiting.
+func mergeContext(ctx, lifetime context.Context) (context.Context, context.CancelFunc) {
+	merged, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(lifetime, func() {
+		cancel(context.Cause(lifetime))
+	})
+
+	return merged, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
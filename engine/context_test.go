@@ -0,0 +1,281 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+type ctxValueKey struct{}
+
+func TestMergeContext(t *testing.T) {
+	t.Run("Caller cancel", func(t *testing.T) {
+		caller, cancelCaller := context.WithCancel(context.Background())
+		lifetime, cancelLifetime := context.WithCancel(context.Background())
+		defer cancelLifetime()
+
+		ctx, cancel := mergeContext(caller, lifetime)
+		defer cancel()
+
+		cancelCaller()
+		<-ctx.Done()
+		if !errors.Is(ctx.Err(), context.Canceled) {
+			t.Errorf("Unexpected merged context error: %s", ctx.Err())
+		}
+		if lifetime.Err() != nil {
+			t.Errorf("Lifetime context should not be canceled")
+		}
+	})
+
+	t.Run("Lifetime cancel", func(t *testing.T) {
+		lifetime, cancelLifetime := context.WithCancelCause(context.Background())
+		ctx, cancel := mergeContext(context.Background(), lifetime)
+		defer cancel()
+
+		cancelLifetime(ErrModuleNotFound)
+		<-ctx.Done()
+		if !errors.Is(context.Cause(ctx), ErrModuleNotFound) {
+			t.Errorf("Unexpected merged context cause: %s", context.Cause(ctx))
+		}
+	})
+
+	t.Run("Caller values and deadline", func(t *testing.T) {
+		deadline := time.Now().Add(time.Hour)
+		caller := context.WithValue(context.Background(), ctxValueKey{}, "value")
+		caller, cancelCaller := context.WithDeadline(caller, deadline)
+		defer cancelCaller()
+
+		ctx, cancel := mergeContext(caller, context.Background())
+		defer cancel()
+
+		if ctx.Value(ctxValueKey{}) != "value" {
+			t.Errorf("Expected merged context to carry caller values")
+		}
+		if d, ok := ctx.Deadline(); !ok || !d.Equal(deadline) {
+			t.Errorf("Unexpected merged context deadline: %s", d)
+		}
+	})
+
+	t.Run("Cancel releases resources", func(t *testing.T) {
+		lifetime, cancelLifetime := context.WithCancel(context.Background())
+		defer cancelLifetime()
+
+		before := runtime.NumGoroutine()
+		for i := 0; i < 1000; i++ {
+			ctx, cancel := mergeContext(context.Background(), lifetime)
+			cancel()
+			if ctx.Err() == nil {
+				t.Fatalf("Expected merged context to be canceled")
+			}
+		}
+		if after := runtime.NumGoroutine(); after > before {
+			t.Errorf("Unexpected goroutines after merging contexts: %d, before: %d", after, before)
+		}
+	})
+}
+
+func TestRunWithContextCancellation(t *testing.T) {
+	started := make(chan struct{}, 1)
+	s, err := New(ServerConfig{
+		Callback: func(ctx context.Context, _, _, _ string, _ []byte) ([]byte, error) {
+			// Block until the invocation is aborted
+			started <- struct{}{}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Caller cancel during run", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-started
+			cancel()
+		}()
+
+		_, err := m.RunWithContext(ctx, "example", []byte("hello"))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context canceled error, got: %s", err)
+		}
+
+		// The aborted instance should be replaced
+		if m.poolSize.Load() != 1 {
+			t.Errorf("Unexpected pool size: %d, expected: 1", m.poolSize.Load())
+		}
+		_, err = m.RunWithContext(context.Background(), "ThisBetterFail", []byte("hello"))
+		if !errors.Is(err, ErrFunctionNotFound) {
+			t.Errorf("Expected function not found error from replaced instance, got: %s", err)
+		}
+	})
+
+	t.Run("Caller deadline during run", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := m.RunWithContext(ctx, "example", []byte("hello"))
+		<-started
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected deadline exceeded error, got: %s", err)
+		}
+	})
+
+	t.Run("Module close during run", func(t *testing.T) {
+		go func() {
+			<-started
+			m.cancel()
+		}()
+
+		_, err := m.RunWithContext(context.Background(), "example", []byte("hello"))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context canceled error, got: %s", err)
+		}
+	})
+}
+
+func TestRunWithContextPoolWaitCancellation(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	session, err := m.Session()
+	if err != nil {
+		t.Fatalf("Unexpected error creating session - %s", err)
+	}
+
+	t.Run("Caller cancel during pool wait", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		start := time.Now()
+		_, err := m.RunWithContext(ctx, "example", []byte("hello"))
+		if !errors.Is(err, context.Canceled) || errors.Is(err, ErrPoolTimeout) {
+			t.Errorf("Expected context canceled error, got: %s", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Pool wait was not abandoned promptly: %s", elapsed)
+		}
+	})
+
+	t.Run("Instance returned after abandoned wait", func(t *testing.T) {
+		session.Close()
+
+		// The abandoned wait receives the instance and returns it to the pool
+		if _, err := m.Run("example", []byte("hello")); err != nil {
+			t.Errorf("Unexpected error running module - %s", err)
+		}
+	})
+}
+
+func TestRunWithContextDeadlineBudget(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(ctx context.Context, _, _, _ string, _ []byte) ([]byte, error) {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+				return []byte(""), nil
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Deadline bounds pool wait", func(t *testing.T) {
+		session, err := m.Session()
+		if err != nil {
+			t.Fatalf("Unexpected error creating session - %s", err)
+		}
+		defer session.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err = m.RunWithContext(ctx, "example", []byte("hello"))
+		if !errors.Is(err, ErrPoolTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected pool timeout and deadline exceeded errors, got: %s", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Pool wait exceeded deadline: %s", elapsed)
+		}
+	})
+
+	t.Run("Pool wait and invocation share deadline", func(t *testing.T) {
+		session, err := m.Session()
+		if err != nil {
+			t.Fatalf("Unexpected error creating session - %s", err)
+		}
+
+		// Release the instance partway through the deadline
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			session.Close()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err = m.RunWithContext(ctx, "example", []byte("hello"))
+		elapsed := time.Since(start)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected deadline exceeded error, got: %s", err)
+		}
+		if elapsed < 100*time.Millisecond || elapsed > 500*time.Millisecond {
+			t.Errorf("Pool wait and invocation did not honor a single deadline: %s", elapsed)
+		}
+	})
+}
@@ -0,0 +1,156 @@
+/*
+Package enginetest provides a harness for integration testing waPC guests together with their host callbacks.
+
+Run loads a guest module into an engine Server, invokes a function, and returns the ordered list of host
+calls the guest made, allowing tests to assert on the host-call sequence of a guest function.
+
+	res, err := enginetest.Run(enginetest.Config{
+		Filepath: "./guest.wasm",
+		Function: "Example",
+		Payload:  []byte("hello"),
+		Callback: router.Callback,
+	})
+	if err != nil {
+		t.Fatalf("Unable to run guest - %s", err)
+	}
+	if len(res.HostCalls) != 1 || res.HostCalls[0].Operation != "function" {
+		t.Errorf("Unexpected host calls: %v", res.HostCalls)
+	}
+
+Host calls are passed to the provided Callback, such as the Callback method of a callbacks.Router, after
+being recorded.
+*/
+package enginetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/tarmac-project/wapc-toolkit/engine"
+)
+
+var (
+	// ErrInvalidConfig is returned when a Config is invalid.
+	ErrInvalidConfig = errors.New("invalid config")
+)
+
+const (
+	// moduleName is the name the guest module is loaded with.
+	moduleName = "enginetest"
+)
+
+// Config is used to configure a Run.
+type Config struct {
+	// Filepath is the path to the guest WebAssembly module.
+	Filepath string
+
+	// Function is the guest function to invoke.
+	Function string
+
+	// Payload is the payload the guest function is invoked with.
+	Payload []byte
+
+	// Callback handles the host calls made by the guest once they are recorded. Callbacks registered with
+	// a callbacks.Router can be used by providing the router's Callback method.
+	//
+	// If Callback is not provided, host calls return an empty response.
+	Callback func(ctx context.Context, namespace, capability, operation string, input []byte) ([]byte, error)
+}
+
+// HostCall is a host call made by the guest.
+type HostCall struct {
+	// Namespace is the namespace, or binding, of the host call.
+	Namespace string
+
+	// Capability is the capability, or namespace, of the host call.
+	Capability string
+
+	// Operation is the operation of the host call.
+	Operation string
+
+	// Input is a copy of the payload provided by the guest.
+	Input []byte
+}
+
+// Result is the outcome of a Run.
+type Result struct {
+	// Response is the response returned by the guest function.
+	Response []byte
+
+	// Err is the error returned by the guest function invocation, if any.
+	Err error
+
+	// HostCalls are the host calls made by the guest during the invocation, in the order they were made.
+	HostCalls []HostCall
+}
+
+// recorder records host calls before passing them to the user-provided callback.
+type recorder struct {
+	sync.Mutex
+
+	// callback is the user-provided callback, if any.
+	callback func(context.Context, string, string, string, []byte) ([]byte, error)
+
+	// calls are the recorded host calls.
+	calls []HostCall
+}
+
+// Callback records the host call and passes it to the user-provided callback.
+func (r *recorder) Callback(
+	ctx context.Context,
+	namespace, capability, operation string,
+	input []byte,
+) ([]byte, error) {
+	r.Lock()
+	r.calls = append(r.calls, HostCall{
+		Namespace:  namespace,
+		Capability: capability,
+		Operation:  operation,
+		Input:      append([]byte{}, input...),
+	})
+	r.Unlock()
+
+	if r.callback == nil {
+		return []byte(""), nil
+	}
+	return r.callback(ctx, namespace, capability, operation, input)
+}
+
+// Run loads the guest module into a new engine Server, invokes the configured function, and returns the
+// result along with the host calls the guest made. The Server is closed before Run returns.
+//
+// Errors returned by the guest function are provided via Result.Err; the returned error is only non-nil
+// if the guest could not be loaded.
+func Run(cfg Config) (Result, error) {
+	if cfg.Filepath == "" || cfg.Function == "" {
+		return Result{}, fmt.Errorf("%w: Filepath and Function cannot be empty", ErrInvalidConfig)
+	}
+
+	rec := &recorder{callback: cfg.Callback}
+	server, err := engine.New(engine.ServerConfig{Callback: rec.Callback})
+	if err != nil {
+		return Result{}, fmt.Errorf("unable to create engine server - %w", err)
+	}
+	defer server.Close()
+
+	err = server.LoadModule(engine.ModuleConfig{Name: moduleName, Filepath: cfg.Filepath, PoolSize: 1})
+	if err != nil {
+		return Result{}, fmt.Errorf("unable to load guest module - %w", err)
+	}
+
+	m, err := server.Module(moduleName)
+	if err != nil {
+		return Result{}, fmt.Errorf("unable to fetch guest module - %w", err)
+	}
+
+	var res Result
+	res.Response, res.Err = m.Run(cfg.Function, cfg.Payload)
+
+	rec.Lock()
+	defer rec.Unlock()
+	res.HostCalls = rec.calls
+
+	return res, nil
+}
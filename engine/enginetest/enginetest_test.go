@@ -0,0 +1,81 @@
+package enginetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+var ErrTestCallback = errors.New("test callback error")
+
+type RunTestCase struct {
+	Name      string
+	Config    Config
+	Response  string
+	GuestErr  bool
+	HostCalls []HostCall
+	Err       error
+}
+
+func TestRun(t *testing.T) {
+	tt := []RunTestCase{
+		{
+			Name:   "Missing filepath",
+			Config: Config{Function: "example"},
+			Err:    ErrInvalidConfig,
+		},
+		{
+			Name:     "Single host call",
+			Config:   Config{Filepath: "../../testdata/hello-go/hello.wasm", Function: "example", Payload: []byte("hi")},
+			Response: "Hello World!",
+			HostCalls: []HostCall{
+				{Namespace: "namespace", Capability: "module", Operation: "function", Input: []byte("hi")},
+			},
+		},
+		{
+			Name: "Failing callback",
+			Config: Config{
+				Filepath: "../../testdata/hello-go/hello.wasm",
+				Function: "example",
+				Payload:  []byte("hi"),
+				Callback: func(context.Context, string, string, string, []byte) ([]byte, error) {
+					return nil, ErrTestCallback
+				},
+			},
+			GuestErr: true,
+			HostCalls: []HostCall{
+				{Namespace: "namespace", Capability: "module", Operation: "function", Input: []byte("hi")},
+			},
+		},
+		{
+			Name:     "No host calls",
+			Config:   Config{Filepath: "../../testdata/hello-go/hello.wasm", Function: "missing"},
+			GuestErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			res, err := Run(tc.Config)
+			if !errors.Is(err, tc.Err) {
+				t.Fatalf("Unexpected error: %s, expected: %s", err, tc.Err)
+			}
+			if (res.Err != nil) != tc.GuestErr {
+				t.Errorf("Unexpected guest error: %s", res.Err)
+			}
+			if string(res.Response) != tc.Response {
+				t.Errorf("Unexpected response: %s, expected: %s", res.Response, tc.Response)
+			}
+			if len(res.HostCalls) != len(tc.HostCalls) {
+				t.Fatalf("Unexpected host calls: %v, expected: %v", res.HostCalls, tc.HostCalls)
+			}
+			for i, call := range res.HostCalls {
+				expected := tc.HostCalls[i]
+				if call.Namespace != expected.Namespace || call.Capability != expected.Capability ||
+					call.Operation != expected.Operation || string(call.Input) != string(expected.Input) {
+					t.Errorf("Unexpected host call %d: %+v, expected: %+v", i, call, expected)
+				}
+			}
+		})
+	}
+}
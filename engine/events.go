@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"time"
+)
+
+// ServerEventType identifies the kind of ServerEvent.
+type ServerEventType int
+
+const (
+	// EventModuleLoaded is sent when a module is loaded.
+	EventModuleLoaded ServerEventType = iota + 1
+
+	// EventModuleUnloaded is sent when a module is unloaded, including when the Server is closed.
+	EventModuleUnloaded
+
+	// EventInvocationError is sent when a guest function invocation returns an error.
+	EventInvocationError
+
+	// EventPoolTimeout is sent when an invocation times out waiting for an instance from the module pool.
+	EventPoolTimeout
+
+	// EventInstantiationFailed is sent when a module instance fails to instantiate, and when an invocation
+	// fails because the module pool has no instances left.
+	EventInstantiationFailed
+
+	// EventModuleReloaded is sent when a module is replaced by ReloadModule, once the old module is closed.
+	EventModuleReloaded
+)
+
+// String returns the name of the event type.
+func (t ServerEventType) String() string {
+	switch t {
+	case EventModuleLoaded:
+		return "module loaded"
+	case EventModuleUnloaded:
+		return "module unloaded"
+	case EventInvocationError:
+		return "invocation error"
+	case EventPoolTimeout:
+		return "pool timeout"
+	case EventInstantiationFailed:
+		return "instantiation failed"
+	case EventModuleReloaded:
+		return "module reloaded"
+	default:
+		return "unknown"
+	}
+}
+
+// ServerEvent is a structured engine lifecycle event sent to the ServerConfig Events channel.
+type ServerEvent struct {
+	// Type is the kind of event.
+	Type ServerEventType
+
+	// Module is the name of the module the event relates to.
+	Module string
+
+	// Function is the guest function invoked, for invocation events.
+	Function string
+
+	// Err is the error that caused the event, for error events.
+	Err error
+
+	// Time is the time the event occurred.
+	Time time.Time
+}
+
+// emit sends the event to the Server events channel without blocking. If the channel is full, the event is
+// dropped and counted.
+func (s *Server) emit(ev ServerEvent) {
+	if s.events == nil {
+		return
+	}
+
+	ev.Time = time.Now()
+	select {
+	case s.events <- ev:
+	default:
+		s.eventsDropped.Add(1)
+	}
+}
+
+// EventsDropped returns the number of events discarded because the Events channel was full.
+func (s *Server) EventsDropped() uint64 {
+	return s.eventsDropped.Load()
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Workiva/go-datastructures/queue"
+)
+
+func TestServerEvents(t *testing.T) {
+	events := make(chan ServerEvent, 10)
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+		Events:   events,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Module loaded", func(t *testing.T) {
+		ev := <-events
+		if ev.Type != EventModuleLoaded || ev.Module != "AModule" || ev.Time.IsZero() {
+			t.Errorf("Unexpected event: %+v", ev)
+		}
+	})
+
+	t.Run("Invocation error", func(t *testing.T) {
+		if _, err := m.Run("ThisBetterFail", []byte("hello")); err == nil {
+			t.Fatalf("Expected error running unknown function")
+		}
+		ev := <-events
+		if ev.Type != EventInvocationError || ev.Function != "ThisBetterFail" || !errors.Is(ev.Err, ErrFunctionNotFound) {
+			t.Errorf("Unexpected event: %+v", ev)
+		}
+	})
+
+	t.Run("Pool timeout", func(t *testing.T) {
+		err := m.poolError(queue.ErrTimeout)
+		if !errors.Is(err, ErrPoolTimeout) {
+			t.Errorf("Expected pool timeout error, got: %s", err)
+		}
+		m.emitError("example", err)
+		ev := <-events
+		if ev.Type != EventPoolTimeout || !errors.Is(ev.Err, ErrPoolTimeout) {
+			t.Errorf("Unexpected event: %+v", ev)
+		}
+	})
+
+	t.Run("Module unloaded", func(t *testing.T) {
+		s.Close()
+		ev := <-events
+		if ev.Type != EventModuleUnloaded || ev.Module != "AModule" {
+			t.Errorf("Unexpected event: %+v", ev)
+		}
+	})
+}
+
+func TestServerEventsDropped(t *testing.T) {
+	events := make(chan ServerEvent, 1)
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+		Events:   events,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	s.emit(ServerEvent{Type: EventModuleLoaded})
+	s.emit(ServerEvent{Type: EventModuleLoaded})
+
+	if s.EventsDropped() != 1 {
+		t.Errorf("Unexpected dropped events: %d, expected: 1", s.EventsDropped())
+	}
+	if len(events) != 1 {
+		t.Errorf("Unexpected queued events: %d, expected: 1", len(events))
+	}
+}
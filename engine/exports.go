@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var (
+	// ErrInvalidWASM is returned when the WebAssembly module binary cannot be parsed.
+	ErrInvalidWASM = errors.New("invalid wasm module")
+)
+
+const (
+	// wasmExportSection is the section ID of the WebAssembly export section.
+	wasmExportSection = 7
+
+	// wasmExportFunc is the export kind of exported functions.
+	wasmExportFunc = 0
+
+	// wasmHeaderSize is the size of the WebAssembly magic number and version.
+	wasmHeaderSize = 8
+)
+
+// parseExports returns the names of the functions exported by the WebAssembly module binary.
+//
+// Only the module's export section is parsed; the module is not validated.
+func parseExports(guest []byte) ([]string, error) {
+	if len(guest) < wasmHeaderSize || !bytes.Equal(guest[:4], []byte("\x00asm")) {
+		return nil, ErrInvalidWASM
+	}
+
+	r := bytes.NewReader(guest[wasmHeaderSize:])
+	for r.Len() > 0 {
+		id, err := r.ReadByte()
+		if err != nil {
+			return nil, ErrInvalidWASM
+		}
+
+		size, err := binary.ReadUvarint(r)
+		if err != nil || size > uint64(r.Len()) {
+			return nil, ErrInvalidWASM
+		}
+
+		// Skip sections other than the export section
+		if id != wasmExportSection {
+			_, _ = r.Seek(int64(size), io.SeekCurrent)
+			continue
+		}
+
+		section := make([]byte, size)
+		_, _ = r.Read(section)
+		return parseExportSection(bytes.NewReader(section))
+	}
+
+	return []string{}, nil
+}
+
+// parseExportSection returns the names of the functions listed within a WebAssembly export section.
+func parseExportSection(r *bytes.Reader) ([]string, error) {
+	count, err := binary.ReadUvarint(r)
+	if err != nil || count > uint64(r.Len()) {
+		return nil, ErrInvalidWASM
+	}
+
+	funcs := make([]string, 0, count)
+	for i := uint64(0); i < count; i++ {
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n > uint64(r.Len()) {
+			return nil, ErrInvalidWASM
+		}
+
+		name := make([]byte, n)
+		_, _ = r.Read(name)
+
+		kind, err := r.ReadByte()
+		if err != nil {
+			return nil, ErrInvalidWASM
+		}
+
+		// Skip the export index
+		if _, err := binary.ReadUvarint(r); err != nil {
+			return nil, ErrInvalidWASM
+		}
+
+		if kind == wasmExportFunc {
+			funcs = append(funcs, string(name))
+		}
+	}
+
+	return funcs, nil
+}
@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestParseExports(t *testing.T) {
+	guest, err := os.ReadFile("../testdata/hello-go/hello.wasm")
+	if err != nil {
+		t.Fatalf("Failed to read wasm file - %s", err)
+	}
+
+	t.Run("Valid module", func(t *testing.T) {
+		exports, err := parseExports(guest)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing exports - %s", err)
+		}
+
+		var found bool
+		for _, name := range exports {
+			if name == "__guest_call" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected __guest_call within exports, got: %v", exports)
+		}
+	})
+
+	t.Run("Invalid module", func(t *testing.T) {
+		_, err := parseExports([]byte("not a wasm module"))
+		if !errors.Is(err, ErrInvalidWASM) {
+			t.Errorf("Expected invalid wasm error, got: %s", err)
+		}
+	})
+
+	t.Run("Truncated module", func(t *testing.T) {
+		_, err := parseExports(guest[:wasmHeaderSize+3])
+		if !errors.Is(err, ErrInvalidWASM) {
+			t.Errorf("Expected invalid wasm error, got: %s", err)
+		}
+	})
+}
@@ -2,9 +2,9 @@ module github.com/tarmac-project/wapc-toolkit/engine
 
 go 1.21.4
 
-require github.com/wapc/wapc-go v0.7.0
-
 require (
-	github.com/Workiva/go-datastructures v1.1.5 // indirect
-	github.com/tetratelabs/wazero v1.7.3 // indirect
+	github.com/Workiva/go-datastructures v1.1.5
+	github.com/klauspost/compress v1.17.9
+	github.com/tetratelabs/wazero v1.7.3
+	github.com/wapc/wapc-go v0.7.0
 )
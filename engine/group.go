@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrGroupNotFound is returned when a module group is not found.
+	ErrGroupNotFound = errors.New("group not found")
+
+	// ErrInvalidWeight is returned when a module is added to a group with a weight less than one.
+	ErrInvalidWeight = errors.New("invalid weight: must be greater than zero")
+)
+
+// group is a named set of modules that share traffic via weighted round-robin selection.
+type group struct {
+	sync.Mutex
+
+	// members is the list of modules within the group and their weights.
+	members []*groupMember
+}
+
+// groupMember is a module within a group.
+type groupMember struct {
+	// name is the name of the member module, used to look up the module from the Server.
+	name string
+
+	// weight is the user-provided weight of the member module.
+	weight int
+
+	// current is the running weight used by the smooth weighted round-robin selection.
+	current int
+}
+
+// next selects the next module within the group using smooth weighted round-robin. Over a full cycle,
+// each member is selected in proportion to its weight, with selections interleaved rather than
+// clustered.
+func (g *group) next() string {
+	g.Lock()
+	defer g.Unlock()
+
+	var total int
+	var selected *groupMember
+	for _, m := range g.members {
+		m.current += m.weight
+		total += m.weight
+		if selected == nil || m.current > selected.current {
+			selected = m
+		}
+	}
+
+	if selected == nil {
+		return ""
+	}
+
+	selected.current -= total
+	return selected.name
+}
+
+// AddToGroup adds a loaded module to the named group with the provided weight, creating the group if
+// it does not already exist. If the module is already a member of the group, its weight is updated.
+//
+// Weights are relative; a group with modules weighted 9 and 1 will send 90% of invocations to the first
+// module and 10% to the second. This enables canary rollouts of new guest versions behind a single
+// InvokeGroup call.
+func (s *Server) AddToGroup(name, module string, weight int) error {
+	if weight < 1 {
+		return ErrInvalidWeight
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.modules[module]; !ok {
+		return fmt.Errorf("%w: %s", ErrModuleNotFound, module)
+	}
+
+	g, ok := s.groups[name]
+	if !ok {
+		g = &group{}
+		s.groups[name] = g
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	// Reset running weights so the new distribution takes effect from a clean state.
+	for _, m := range g.members {
+		m.current = 0
+	}
+
+	for _, m := range g.members {
+		if m.name == module {
+			m.weight = weight
+			return nil
+		}
+	}
+
+	g.members = append(g.members, &groupMember{name: module, weight: weight})
+	return nil
+}
+
+// RemoveFromGroup removes a module from the named group. The group is removed once its last module is
+// removed.
+//
+// If the group is not found, ErrGroupNotFound will be returned. If the module is not a member of the group,
+// no error is returned.
+func (s *Server) RemoveFromGroup(name, module string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.groups[name]; !ok {
+		return ErrGroupNotFound
+	}
+	s.removeFromGroup(name, module)
+
+	return nil
+}
+
+// removeFromGroup removes a module from the named group, removing the group once it is empty. The caller must
+// hold the Server lock.
+func (s *Server) removeFromGroup(name, module string) {
+	g := s.groups[name]
+
+	g.Lock()
+	defer g.Unlock()
+
+	for i, m := range g.members {
+		if m.name != module {
+			continue
+		}
+		g.members = append(g.members[:i], g.members[i+1:]...)
+
+		for _, m := range g.members {
+			m.current = 0
+		}
+		break
+	}
+
+	if len(g.members) == 0 {
+		delete(s.groups, name)
+	}
+}
+
+// InvokeGroup selects a module from the named group using weighted round-robin and calls the user-provided
+// function with the user-provided payload.
+//
+// If the group is not found, ErrGroupNotFound will be returned.
+func (s *Server) InvokeGroup(ctx context.Context, name, function string, payload []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.RLock()
+	g, ok := s.groups[name]
+	s.RUnlock()
+	if !ok {
+		return nil, ErrGroupNotFound
+	}
+
+	m, err := s.Module(g.next())
+	if err != nil {
+		return nil, err
+	}
+
+	return m.RunWithContext(ctx, function, payload)
+}
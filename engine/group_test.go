@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGroupWeightedDistribution(t *testing.T) {
+	g := &group{
+		members: []*groupMember{
+			{name: "stable", weight: 9},
+			{name: "canary", weight: 1},
+		},
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		counts[g.next()]++
+	}
+
+	if counts["stable"] != 900 {
+		t.Errorf("Unexpected stable selection count: %d, expected: 900", counts["stable"])
+	}
+	if counts["canary"] != 100 {
+		t.Errorf("Unexpected canary selection count: %d, expected: 100", counts["canary"])
+	}
+}
+
+func TestGroupInvoke(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	for _, name := range []string{"stable", "canary"} {
+		err = s.LoadModule(ModuleConfig{
+			Name:     name,
+			PoolSize: 2,
+			Filepath: "../testdata/hello-go/hello.wasm",
+		})
+		if err != nil {
+			t.Fatalf("Failed to load module - %s", err)
+		}
+	}
+
+	t.Run("Invalid Weight", func(t *testing.T) {
+		err := s.AddToGroup("hello", "stable", 0)
+		if !errors.Is(err, ErrInvalidWeight) {
+			t.Errorf("Expected invalid weight error, got: %s", err)
+		}
+	})
+
+	t.Run("Non-existent Module", func(t *testing.T) {
+		err := s.AddToGroup("hello", "ThisBetterFail", 1)
+		if !errors.Is(err, ErrModuleNotFound) {
+			t.Errorf("Expected module not found error, got: %s", err)
+		}
+	})
+
+	t.Run("Non-existent Group", func(t *testing.T) {
+		_, err := s.InvokeGroup(context.Background(), "ThisBetterFail", "example", []byte("hello"))
+		if !errors.Is(err, ErrGroupNotFound) {
+			t.Errorf("Expected group not found error, got: %s", err)
+		}
+	})
+
+	if err := s.AddToGroup("hello", "stable", 9); err != nil {
+		t.Fatalf("Failed to add module to group - %s", err)
+	}
+	if err := s.AddToGroup("hello", "canary", 1); err != nil {
+		t.Fatalf("Failed to add module to group - %s", err)
+	}
+
+	t.Run("Invoke Group", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			_, err := s.InvokeGroup(context.Background(), "hello", "example", []byte("hello"))
+			if err != nil {
+				t.Fatalf("Unexpected error invoking group - %s", err)
+			}
+		}
+	})
+
+	t.Run("Invoke Group with Canceled Context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := s.InvokeGroup(ctx, "hello", "example", []byte("hello"))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected canceled error, got: %s", err)
+		}
+	})
+
+	t.Run("Remove from Group", func(t *testing.T) {
+		for _, name := range []string{"stable", "canary"} {
+			if err := s.RemoveFromGroup("hello", name); err != nil {
+				t.Fatalf("Unexpected error removing module from group - %s", err)
+			}
+		}
+
+		_, err := s.InvokeGroup(context.Background(), "hello", "example", []byte("hello"))
+		if !errors.Is(err, ErrGroupNotFound) {
+			t.Errorf("Expected group not found error after removing all modules, got: %s", err)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// invocationKey is the context key for the Invocation provided to host callbacks.
+type invocationKey struct{}
+
+// lastInvocationID is the last assigned invocation identifier, shared by every module so that identifiers
+// are unique within the process.
+var lastInvocationID atomic.Uint64
+
+// Invocation identifies the guest function invocation that triggered a host callback. It is carried by the
+// context provided to the Server callback, allowing host call logs to be correlated with the invocation
+// that caused them.
+type Invocation struct {
+	// ID identifies the invocation. Identifiers are unique within the process.
+	ID uint64
+
+	// Module is the name of the module invoked.
+	Module string
+
+	// Function is the guest function invoked.
+	Function string
+}
+
+// InvocationFromContext returns the Invocation carried by the context provided to host callbacks, if any.
+func InvocationFromContext(ctx context.Context) (Invocation, bool) {
+	inv, ok := ctx.Value(invocationKey{}).(Invocation)
+	return inv, ok
+}
+
+// invocationContext returns the provided context extended with a new Invocation of the provided function.
+func (m *Module) invocationContext(ctx context.Context, function string) context.Context {
+	return context.WithValue(ctx, invocationKey{}, Invocation{
+		ID:       lastInvocationID.Add(1),
+		Module:   m.Name,
+		Function: function,
+	})
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestInvocationFromContext(t *testing.T) {
+	invocations := make(chan Invocation, 10)
+	s, err := New(ServerConfig{
+		Callback: func(ctx context.Context, _, _, _ string, _ []byte) ([]byte, error) {
+			inv, ok := InvocationFromContext(ctx)
+			if !ok {
+				t.Errorf("Expected invocation within callback context")
+			}
+			invocations <- inv
+			return []byte(""), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Not an invocation", func(t *testing.T) {
+		if _, ok := InvocationFromContext(context.Background()); ok {
+			t.Errorf("Unexpected invocation within background context")
+		}
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		if _, err := m.Run("example", []byte("hello")); err != nil {
+			t.Fatalf("Unexpected error running module - %s", err)
+		}
+		if _, err := m.RunWithContext(context.Background(), "example", []byte("hello")); err != nil {
+			t.Fatalf("Unexpected error running module - %s", err)
+		}
+
+		first, second := <-invocations, <-invocations
+		if first.Module != "AModule" || first.Function != "example" || first.ID == 0 {
+			t.Errorf("Unexpected invocation: %+v", first)
+		}
+		if second.ID == first.ID {
+			t.Errorf("Expected unique invocation IDs, got: %d and %d", first.ID, second.ID)
+		}
+	})
+
+	t.Run("Session", func(t *testing.T) {
+		session, err := m.Session()
+		if err != nil {
+			t.Fatalf("Unexpected error creating session - %s", err)
+		}
+		defer session.Close()
+
+		if _, err := session.Call("example", []byte("hello")); err != nil {
+			t.Fatalf("Unexpected error calling session - %s", err)
+		}
+		if inv := <-invocations; inv.Module != "AModule" || inv.Function != "example" {
+			t.Errorf("Unexpected invocation: %+v", inv)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrMarshalFailed is returned by InvokeJSON when the request cannot be marshaled to JSON.
+	ErrMarshalFailed = errors.New("unable to marshal request")
+
+	// ErrInvokeFailed is returned by InvokeJSON when the guest function invocation fails. The underlying
+	// invocation error, such as ErrFunctionNotFound, is wrapped.
+	ErrInvokeFailed = errors.New("unable to invoke function")
+
+	// ErrUnmarshalFailed is returned by InvokeJSON when the guest response cannot be unmarshaled from JSON.
+	ErrUnmarshalFailed = errors.New("unable to unmarshal response")
+)
+
+// InvokeJSON looks up the named module, marshals the request to JSON, calls the user-provided function with
+// the marshaled request, and unmarshals the guest response into out. If out is nil, the guest response is
+// discarded.
+//
+// Each stage returns a distinct error: ErrModuleNotFound if the module is not loaded, ErrMarshalFailed if
+// the request cannot be marshaled, ErrInvokeFailed if the invocation fails, and ErrUnmarshalFailed if the
+// response cannot be unmarshaled.
+func (s *Server) InvokeJSON(ctx context.Context, module, function string, in, out any) error {
+	m, err := s.Module(module)
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, module)
+	}
+
+	payload, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("%w - %w", ErrMarshalFailed, err)
+	}
+
+	rsp, err := m.RunWithContext(ctx, function, payload)
+	if err != nil {
+		return fmt.Errorf("%w: %s - %w", ErrInvokeFailed, function, err)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(rsp, out); err != nil {
+		return fmt.Errorf("%w - %w", ErrUnmarshalFailed, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type InvokeJSONTestCase struct {
+	Name     string
+	Module   string
+	Function string
+	In       any
+	Out      any
+	Errs     []error
+}
+
+func TestServerInvokeJSON(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	tt := []InvokeJSONTestCase{
+		{
+			Name:     "Discarded response",
+			Module:   "AModule",
+			Function: "example",
+			In:       map[string]string{"name": "world"},
+		},
+		{
+			Name:     "Module not found",
+			Module:   "ThisBetterFail",
+			Function: "example",
+			Errs:     []error{ErrModuleNotFound},
+		},
+		{
+			Name:     "Marshal failure",
+			Module:   "AModule",
+			Function: "example",
+			In:       make(chan int),
+			Errs:     []error{ErrMarshalFailed},
+		},
+		{
+			Name:     "Invoke failure",
+			Module:   "AModule",
+			Function: "ThisBetterFail",
+			Errs:     []error{ErrInvokeFailed, ErrFunctionNotFound},
+		},
+		{
+			Name:     "Unmarshal failure",
+			Module:   "AModule",
+			Function: "example",
+			Out:      &map[string]string{},
+			Errs:     []error{ErrUnmarshalFailed},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := s.InvokeJSON(context.Background(), tc.Module, tc.Function, tc.In, tc.Out)
+			if len(tc.Errs) == 0 && err != nil {
+				t.Fatalf("Unexpected error invoking function - %s", err)
+			}
+			for _, e := range tc.Errs {
+				if !errors.Is(err, e) {
+					t.Errorf("Expected error %s, got: %s", e, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"time"
+)
+
+// ModuleMetrics describes a single call to Run, RunWithContext or RunBuffers. ModuleMetrics are provided to the
+// MetricsFunc defined within the ServerConfig, allowing per-module and per-function metrics, such as
+// invocation counts, latency, and error rates, to be exported.
+type ModuleMetrics struct {
+	// Module is the name of the module called.
+	Module string
+
+	// Function is the guest function called, as requested by the caller.
+	Function string
+
+	// Duration is the time taken by the call, including any wait for an instance from the pool.
+	Duration time.Duration
+
+	// PayloadSize is the size, in bytes, of the payload provided by the caller.
+	PayloadSize int
+
+	// ResponseSize is the size, in bytes, of the response returned to the caller.
+	ResponseSize int
+
+	// Err is the error returned by the call, if any.
+	Err error
+}
+
+// recordMetrics calls the MetricsFunc with the metrics of a call started at the provided time. It is
+// deferred by Run, RunWithContext and RunBuffers with pointers to their results.
+func (m *Module) recordMetrics(function string, payload []byte, start time.Time, rsp *[]byte, err *error) {
+	if m.metricsFunc == nil {
+		return
+	}
+
+	m.metricsFunc(ModuleMetrics{
+		Module:       m.Name,
+		Function:     function,
+		Duration:     time.Since(start),
+		PayloadSize:  len(payload),
+		ResponseSize: len(*rsp),
+		Err:          *err,
+	})
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestMetricsFunc(t *testing.T) {
+	var mu sync.Mutex
+	var metrics []ModuleMetrics
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+		MetricsFunc: func(mm ModuleMetrics) {
+			mu.Lock()
+			defer mu.Unlock()
+			metrics = append(metrics, mm)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	if _, err := m.Run("example", []byte("hello")); err != nil {
+		t.Fatalf("Unexpected error running module - %s", err)
+	}
+	if _, err := m.RunWithContext(context.Background(), "ThisBetterFail", []byte("hi")); err == nil {
+		t.Fatalf("Expected error running unknown function")
+	}
+	buf, err := m.RunBuffers("example", []byte("buffered"))
+	if err != nil {
+		t.Fatalf("Unexpected error running module - %s", err)
+	}
+	buf.Release()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(metrics) != 3 {
+		t.Fatalf("Unexpected number of metrics: %d", len(metrics))
+	}
+
+	t.Run("Run", func(t *testing.T) {
+		mm := metrics[0]
+		if mm.Module != "AModule" || mm.Function != "example" || mm.Err != nil {
+			t.Errorf("Unexpected metrics: %+v", mm)
+		}
+		if mm.PayloadSize != 5 || mm.ResponseSize != len("Hello World!") || mm.Duration <= 0 {
+			t.Errorf("Unexpected metrics: %+v", mm)
+		}
+	})
+
+	t.Run("RunWithContext error", func(t *testing.T) {
+		mm := metrics[1]
+		if mm.Function != "ThisBetterFail" || mm.PayloadSize != 2 || !errors.Is(mm.Err, ErrFunctionNotFound) {
+			t.Errorf("Unexpected metrics: %+v", mm)
+		}
+	})
+
+	t.Run("RunBuffers", func(t *testing.T) {
+		mm := metrics[2]
+		if mm.Function != "example" || mm.PayloadSize != 8 || mm.ResponseSize != len("Hello World!") || mm.Err != nil {
+			t.Errorf("Unexpected metrics: %+v", mm)
+		}
+	})
+}
@@ -1,20 +1,61 @@
 package engine
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Workiva/go-datastructures/queue"
 	wapc "github.com/wapc/wapc-go"
 )
 
 var (
 	// ErrInvalidModuleConfig is returned when a ModuleConfig is invalid.
 	ErrInvalidModuleConfig = errors.New("invalid module config")
+
+	// ErrFunctionNotFound is returned when the guest does not have the called function registered.
+	ErrFunctionNotFound = errors.New("function not found")
+
+	// ErrPoolTimeout is returned when no instance becomes available from the module pool within the pool
+	// timeout. The returned error is a *PoolTimeoutError, providing a retry-after hint.
+	ErrPoolTimeout = errors.New("timed out waiting for module from pool")
+
+	// ErrInvalidModuleName is returned when a ModuleConfig Name does not match the allowed module name pattern.
+	ErrInvalidModuleName = errors.New("invalid module name")
+
+	// ErrFunctionNotAllowed is returned when a module's FunctionFilter rejects the called function.
+	ErrFunctionNotAllowed = errors.New("function not allowed")
+
+	// ErrInstantiationFailed is returned when module instances cannot be instantiated, such as when the host
+	// is out of memory. Unlike ErrPoolTimeout, which indicates the pool is busy, it indicates the pool has
+	// no instances left to serve invocations.
+	ErrInstantiationFailed = errors.New("module instantiation failed")
 )
 
+// moduleNamePattern is the pattern module names must match.
+var moduleNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
 const (
+	// guestFunctionNotFound is the error message prefix used by waPC guest SDKs when the called function is
+	// not registered.
+	guestFunctionNotFound = "Could not find function"
+
+	// DefaultDrainTimeout is the maximum duration ReloadModule waits for in-flight invocations, sessions,
+	// and buffers of the replaced module to finish before closing it.
+	DefaultDrainTimeout = 30 * time.Second
+
+	// drainInterval is how often a draining module checks whether its instances are still in use.
+	drainInterval = 10 * time.Millisecond
+
 	// Default WebAssembly Module Pool Size.
 	DefaultPoolSize = 100
 
@@ -31,9 +72,20 @@ type ModuleConfig struct {
 	// fetching modules.
 	Name string
 
-	// Filepath is the path to load the .wasm module file from the file system.
+	// Filepath is the path to load the .wasm module file from the file system. Either Filepath or Source
+	// must be provided, but not both.
 	Filepath string
 
+	// Source is the WebAssembly module binary to load, for hosts that build modules in memory or fetch them
+	// from object storage, avoiding a round trip via the file system. Either Filepath or Source must be
+	// provided, but not both.
+	Source []byte
+
+	// SHA256 is the expected hex-encoded SHA-256 digest of the WebAssembly module binary. When provided, the
+	// binary read from Filepath or Source is verified before the module is compiled, and ErrChecksumMismatch
+	// is returned if it does not match. This guards against corrupted or tampered module files.
+	SHA256 string
+
 	// PoolSize is used to control the size of the WebAssembly Modules pool. Each module has its
 	// own pool; for each invocation of the Run function, the module is taken from the pool and
 	// re-added upon completion. The pool size should be large enough to support concurrent executions of
@@ -41,6 +93,172 @@ type ModuleConfig struct {
 	//
 	// If PoolSize is not provided, DefaultPoolSize will be used.
 	PoolSize int
+
+	// Compression is the codec used to compress payloads passed to and returned from the guest. The codec
+	// is only used if the guest advertises support for it; see CompressionCodec for details on negotiation
+	// and framing.
+	//
+	// If Compression is not provided, payloads are not compressed.
+	Compression CompressionCodec
+
+	// MaxDecompressedSize is the maximum size, in bytes, a compressed guest response may decompress to. Larger
+	// responses return ErrDecompressedSizeExceeded, protecting the host from decompression bombs.
+	//
+	// If MaxDecompressedSize is not provided, DefaultMaxDecompressedSize will be used.
+	MaxDecompressedSize int64
+
+	// Stdout is the writer guest standard output is written to. If Stdout is not provided, os.Stdout
+	// will be used.
+	Stdout io.Writer
+
+	// Stderr is the writer guest standard error is written to. If Stderr is not provided, os.Stderr
+	// will be used.
+	Stderr io.Writer
+
+	// Env is the set of environment variables provided to the guest via WASI, such as those read with
+	// os.Getenv. Keys cannot be empty or contain '=', and neither keys nor values may contain NUL
+	// characters. If Env is not provided, the guest has no environment variables.
+	Env map[string]string
+
+	// Args is the command-line arguments provided to the guest via WASI. By convention, the first argument
+	// is the program name. If Args is not provided, the guest has no arguments.
+	Args []string
+
+	// Callback, when provided, handles the waPC HostCalls of this module in place of the Server's Callback or
+	// Router. This isolates the capabilities exposed to modules from different trust domains, as HostCalls
+	// from this module never reach the server-wide callback.
+	Callback func(context.Context, string, string, string, []byte) ([]byte, error)
+
+	// Logger is called with each message the guest logs via waPC, allowing guest diagnostics to be routed to
+	// a structured logging pipeline. If Logger is not provided, wapc.PrintlnLogger will be used.
+	Logger wapc.Logger
+
+	// LogPrefix, when enabled, prefixes each line of guest output, including Stdout, Stderr, and
+	// guest log messages, with the module name. This makes output readable when multiple modules
+	// share the same writers.
+	LogPrefix bool
+
+	// RunStatsFunc is an optional user-defined function called after each guest function invocation made
+	// via Run. It is provided with RunStats describing the invocation, including the size of the
+	// instance's linear memory before and after the invocation, enabling detection of guests that leak
+	// memory over time.
+	//
+	// RunStatsFunc is called synchronously before Run returns; it should not block.
+	RunStatsFunc func(RunStats)
+
+	// ReentrantPoolSize is the size of a separate pool of instances reserved for reentrant invocations,
+	// which are invocations made via RunWithContext from within a host callback triggered by the same
+	// module. See RunWithContext for details on reentrancy.
+	//
+	// If ReentrantPoolSize is not provided, no reentrancy pool is created, and reentrant invocations use the
+	// module pool.
+	ReentrantPoolSize int
+
+	// FunctionFilter is an optional user-defined function applied to the function name of every
+	// invocation before the guest is called. It returns the function name to invoke, allowing names to
+	// be rewritten, such as to namespace them, or an error to reject the invocation.
+	//
+	// Rejected invocations return ErrFunctionNotAllowed, wrapping the error returned by FunctionFilter,
+	// and do not take an instance from the pool. This provides a policy layer over guest functions
+	// without relying on every caller to validate function names.
+	FunctionFilter func(name string) (string, error)
+
+	// ReturnStdout, when enabled, captures the standard output written by the guest during each invocation
+	// and returns it as the response in place of the guest's waPC response. This supports guests built with
+	// toolchains that write their result to standard output rather than returning it via waPC.
+	//
+	// Captured output is not written to Stdout. Guest errors are returned as normal. ReturnStdout cannot be
+	// used with Compression.
+	ReturnStdout bool
+
+	// CompileTimeout is the maximum duration compiling the module may take. If compilation exceeds
+	// CompileTimeout, LoadModule returns ErrCompileTimeout. This protects callers, such as upload endpoints,
+	// from modules that are expensive to compile.
+	//
+	// Compilation cannot be interrupted, so it continues in the background after the timeout, and the
+	// compiled module is discarded. If CompileTimeout is not provided, compilation is not limited.
+	CompileTimeout time.Duration
+
+	// Singleton, when enabled, loads the module with exactly one instance shared by every invocation.
+	// Invocations are serialized, queueing until the instance is available for up to the pool timeout, or
+	// a RunWithContext deadline if sooner, before failing with ErrPoolTimeout. This suits inherently
+	// stateful guests, as every invocation observes the state left by the previous one.
+	//
+	// Throughput is limited to one invocation at a time. The instance is only replaced if an invocation is
+	// aborted, in which case guest state is lost. Singleton cannot be used with a PoolSize greater than one
+	// or a ReentrantPoolSize, and host callbacks invoking the module with their context are rejected with
+	// ErrSingletonReentrant.
+	Singleton bool
+
+	// MaxConcurrentRuns limits the number of Run and RunWithContext calls in progress at once, regardless
+	// of PoolSize. This protects resources the guest depends on, such as a downstream service called via
+	// host callbacks, without reducing the number of instances. Calls beyond the limit return
+	// ErrConcurrencyLimit, unless BlockOnConcurrencyLimit is enabled.
+	//
+	// Reentrant invocations are not counted, as the invocation that triggered them already holds a slot.
+	// If MaxConcurrentRuns is not provided, concurrent runs are only limited by the pool.
+	MaxConcurrentRuns int
+
+	// BlockOnConcurrencyLimit, when enabled, makes calls beyond MaxConcurrentRuns wait for a run to finish
+	// rather than failing. The wait is bounded by the RunWithContext context; Run waits until a slot is
+	// available or the module is closed.
+	BlockOnConcurrencyLimit bool
+
+	// RetryAfter is the minimum retry-after hint provided with ErrPoolTimeout and ErrConcurrencyLimit, see
+	// PoolTimeoutError for how the hint is derived. If RetryAfter is not provided, DefaultRetryAfter is used.
+	RetryAfter time.Duration
+}
+
+// Validate validates the module configuration. It returns an error if the configuration values are invalid
+// or missing any required fields.
+//
+// Module names must start with a letter or digit and contain only letters, digits, '.', '_', and '-'; other
+// names return ErrInvalidModuleName. This keeps names safe to use as map keys, log fields, and metric labels.
+func (cfg ModuleConfig) Validate() error {
+	if cfg.Name == "" || (cfg.Filepath == "" && cfg.Source == nil) {
+		return fmt.Errorf("%w: key and file cannot be empty", ErrInvalidModuleConfig)
+	}
+	if !moduleNamePattern.MatchString(cfg.Name) {
+		return fmt.Errorf("%w: %q", ErrInvalidModuleName, cfg.Name)
+	}
+	if cfg.Filepath != "" && cfg.Source != nil {
+		return fmt.Errorf("%w: Filepath and Source cannot both be provided", ErrInvalidModuleConfig)
+	}
+	if cfg.SHA256 != "" {
+		if b, err := hex.DecodeString(cfg.SHA256); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("%w: SHA256 must be a hex-encoded SHA-256 digest", ErrInvalidModuleConfig)
+		}
+	}
+	if cfg.CompileTimeout < 0 {
+		return fmt.Errorf("%w: CompileTimeout cannot be negative", ErrInvalidModuleConfig)
+	}
+	if cfg.ReturnStdout && cfg.Compression != CompressionNone {
+		return fmt.Errorf("%w: ReturnStdout cannot be used with Compression", ErrInvalidModuleConfig)
+	}
+	if cfg.Singleton && (cfg.PoolSize > 1 || cfg.ReentrantPoolSize > 0) {
+		return fmt.Errorf("%w: Singleton cannot be used with a PoolSize or ReentrantPoolSize", ErrInvalidModuleConfig)
+	}
+	if cfg.MaxDecompressedSize < 0 {
+		return fmt.Errorf("%w: MaxDecompressedSize cannot be negative", ErrInvalidModuleConfig)
+	}
+	if cfg.MaxConcurrentRuns < 0 {
+		return fmt.Errorf("%w: MaxConcurrentRuns cannot be negative", ErrInvalidModuleConfig)
+	}
+	if cfg.RetryAfter < 0 {
+		return fmt.Errorf("%w: RetryAfter cannot be negative", ErrInvalidModuleConfig)
+	}
+	for k, v := range cfg.Env {
+		if k == "" || strings.ContainsAny(k, "=\x00") || strings.ContainsRune(v, 0) {
+			return fmt.Errorf("%w: invalid Env variable %q", ErrInvalidModuleConfig, k)
+		}
+	}
+	for _, arg := range cfg.Args {
+		if strings.ContainsRune(arg, 0) {
+			return fmt.Errorf("%w: Args cannot contain NUL characters", ErrInvalidModuleConfig)
+		}
+	}
+
+	return nil
 }
 
 // Module is a specific WebAssembly Module loaded via the WebAssembly Engine Server. Each WebAssembly
@@ -53,6 +271,9 @@ type Module struct {
 	// fetching modules.
 	Name string
 
+	// config is the user-provided configuration the module was loaded with.
+	config ModuleConfig
+
 	// ctx is a context used to clean up module instances.
 	ctx context.Context
 
@@ -66,35 +287,556 @@ type Module struct {
 	// module instances as needed.
 	pool *wapc.Pool
 
-	// poolSize will determine the size of a module pool.
-	poolSize uint64
+	// reentrantPool is the optional pool of instances used for reentrant invocations.
+	reentrantPool *wapc.Pool
+
+	// functionFilter rewrites or rejects function names, see ModuleConfig for details.
+	functionFilter func(name string) (string, error)
+
+	// emit sends lifecycle events to the Server events channel.
+	emit func(ServerEvent)
+
+	// poolSize is the number of live instances within the module pool. It is reduced as instances are
+	// closed by ScaleDown.
+	poolSize atomic.Uint64
+
+	// scale guards scaleCancel.
+	scale sync.Mutex
+
+	// scaleCancel stops any in-progress ScaleDown.
+	scaleCancel context.CancelFunc
+
+	// codec is the compression codec negotiated with the guest.
+	codec CompressionCodec
+
+	// maxDecompressedSize is the maximum size of a decompressed guest response.
+	maxDecompressedSize int64
+
+	// exports is the set of function names exported by the WebAssembly module.
+	exports map[string]struct{}
+
+	// broadcast serializes InvokeAll calls, preventing concurrent broadcasts from each holding part of
+	// the pool while waiting for the rest.
+	broadcast sync.Mutex
+
+	// turn serializes use of the instance of a singleton module; it is nil for other modules.
+	turn chan struct{}
+
+	// runs holds a slot for each run in progress, limiting concurrent runs to MaxConcurrentRuns; it is nil
+	// for modules without a limit.
+	runs chan struct{}
+
+	// inUse is the number of instances currently taken from the pool.
+	inUse atomic.Uint64
+
+	// active is the number of callers waiting for, or holding, instances taken from the pool.
+	active atomic.Int64
+
+	// invocations is the number of guest function invocations made.
+	invocations atomic.Uint64
+
+	// invocationTime is the total duration of guest function invocations, in nanoseconds.
+	invocationTime atomic.Int64
+
+	// failures is the number of guest function invocations that returned an error.
+	failures atomic.Uint64
+
+	// instantiationFailures is the number of module instances that failed to instantiate.
+	instantiationFailures atomic.Uint64
+
+	// metricsFunc is called with the metrics of each Run and RunWithContext call, see ServerConfig for
+	// details.
+	metricsFunc func(ModuleMetrics)
+
+	// runStatsFunc is called with the stats of each invocation, see ModuleConfig for details.
+	runStatsFunc func(RunStats)
+
+	// instanceIDs maps pool instances to the identifiers reported within RunStats.
+	instanceIDs sync.Map
+
+	// nextInstanceID is the last assigned instance identifier.
+	nextInstanceID atomic.Uint64
 }
 
 // Run will fetch a WASM module from the available pool and call the user-provided function with the
 // user-provided payload.
 //
 // Upon completion, Run will add the module back to the available pool.
-func (m *Module) Run(function string, payload []byte) ([]byte, error) {
-	var r []byte
-	// Get a module instance from the pool
-	i, err := m.pool.Get(DefaultPoolTimeout * time.Second)
+func (m *Module) Run(function string, payload []byte) (rsp []byte, err error) {
+	defer m.recordMetrics(function, payload, time.Now(), &rsp, &err)
+
+	if err := m.enterRun(m.ctx); err != nil {
+		return nil, err
+	}
+	defer m.exitRun()
+
+	return m.run(m.invokeContext(m.ctx), m.pool, function, payload)
+}
+
+// RunWithContext calls the user-provided function with the user-provided payload in the same way as Run,
+// using the provided context for the invocation.
+//
+// The provided context is merged with the module's lifetime context, so the invocation is aborted if
+// either the provided context is canceled or expires, or the module is closed. Context values are taken
+// from the provided context and are available to host callbacks triggered by the invocation. Aborted
+// invocations return an error wrapping the context error, and the aborted instance is replaced within
+// the pool.
+//
+// A context deadline bounds the whole call: the wait for an instance from the pool, and the invocation
+// itself, which is left with the time remaining after the wait. If the deadline expires while waiting,
+// the returned error wraps both ErrPoolTimeout and context.DeadlineExceeded. If the context is canceled
+// while waiting, the wait is abandoned immediately and the context error is returned.
+//
+// The context provided to host callbacks identifies the module that triggered the callback. When a
+// callback invokes a function on that same module by passing its context to RunWithContext, the
+// invocation is reentrant: the triggering instance remains taken from the pool until the callback
+// returns. If the pool is saturated, a reentrant invocation waiting on the same pool would never be
+// satisfied.
+//
+// To avoid this deadlock, reentrant invocations use the module's reentrancy pool when one is configured
+// via ModuleConfig.ReentrantPoolSize. Each level of reentrancy holds one instance from the reentrancy
+// pool, so the pool should be sized for the expected number of concurrent reentrant invocations,
+// including nested reentrancy. Non-reentrant invocations always use the module pool.
+//
+// If the context is canceled or expired, RunWithContext returns the context error without invoking the
+// guest.
+func (m *Module) RunWithContext(ctx context.Context, function string, payload []byte) (rsp []byte, err error) {
+	defer m.recordMetrics(function, payload, time.Now(), &rsp, &err)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Reject reentrant invocations of a singleton module, which would wait on their own turn
+	if err := m.checkReentrant(ctx); err != nil {
+		m.emitError(function, err)
+		return nil, err
+	}
+
+	pool := m.poolFor(ctx)
+
+	// Abort the invocation if either the caller or the module is done
+	ctx, cancel := mergeContext(ctx, m.ctx)
+	defer cancel()
+
+	// Reentrant invocations run within the slot held by the invocation that triggered them
+	if !m.reentrant(ctx) {
+		if err := m.enterRun(ctx); err != nil {
+			return nil, err
+		}
+		defer m.exitRun()
+	}
+
+	return m.run(m.invokeContext(ctx), pool, function, payload)
+}
+
+// run fetches an instance from the provided pool and calls the user-provided function with the user-provided
+// payload using the provided invocation context.
+func (m *Module) run(ctx context.Context, pool *wapc.Pool, function string, payload []byte) ([]byte, error) {
+	function, payload, err := m.prepare(function, payload)
 	if err != nil {
-		return r, fmt.Errorf("could not fetch module from pool - %w", err)
+		return nil, err
+	}
+
+	// Get a module instance from the pool, leaving the remaining deadline to bound the invocation
+	i, err := m.acquire(ctx, pool, function)
+	if err != nil {
+		return nil, err
 	}
 
 	// Return the module to the pool
+	var aborted bool
+	defer func() {
+		m.release(pool, i, aborted)
+	}()
+
+	// Invoke the module with the user-provided function and payload
+	r, aborted, err := m.invoke(ctx, i, function, payload)
+	if err != nil {
+		return r, err
+	}
+
+	// Decompress the response using the negotiated codec
+	return decompress(m.codec, r, m.maxDecompressedSize)
+}
+
+// prepare applies the function filter and compresses the payload using the negotiated codec.
+func (m *Module) prepare(function string, payload []byte) (string, []byte, error) {
+	// Apply the function filter
+	function, err := m.filterFunction(function)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Compress the payload using the negotiated codec
+	payload, err = compress(m.codec, payload)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return function, payload, nil
+}
+
+// acquire fetches a module instance from the provided pool. The wait for an instance is bounded by the pool
+// timeout, or the context deadline if sooner, so that time spent waiting counts against the same deadline
+// as the invocation. If the wait is bounded by the deadline, the returned ErrPoolTimeout also wraps
+// context.DeadlineExceeded.
+//
+// The caller is counted as active from the moment it starts waiting, so that a draining module is not closed
+// under callers still waiting for an instance; see drain.
+func (m *Module) acquire(ctx context.Context, pool *wapc.Pool, function string) (i wapc.Instance, err error) {
+	m.active.Add(1)
 	defer func() {
-		err := m.pool.Return(i) //nolint:govet // Ignore govet warning about shadowing err as it is not shadowed.
 		if err != nil {
-			defer i.Close(m.ctx)
+			m.active.Add(-1)
 		}
 	}()
 
+	// Queue for the instance of a singleton module
+	if err := m.takeTurn(ctx); err != nil {
+		m.emitError(function, err)
+		return nil, err
+	}
+
+	timeout := DefaultPoolTimeout * time.Second
+	deadline, bounded := ctx.Deadline()
+	if bounded {
+		remaining := time.Until(deadline)
+		bounded = remaining < timeout
+		if bounded {
+			timeout = remaining
+		}
+	}
+
+	// Fail fast if every instance of the module pool failed to be replaced
+	if pool == m.pool && m.poolSize.Load() == 0 {
+		err := fmt.Errorf("%w: no instances within module pool", ErrInstantiationFailed)
+		m.giveTurn()
+		m.emitError(function, err)
+		return nil, err
+	}
+
+	err = m.poolError(queue.ErrTimeout)
+	if timeout > 0 {
+		i, err = m.get(ctx, pool, timeout)
+	}
+	if err != nil {
+		if bounded && errors.Is(err, ErrPoolTimeout) && !errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("%w - %w", err, context.DeadlineExceeded)
+		}
+		if errors.Is(err, ErrPoolTimeout) {
+			err = m.poolTimeout(err)
+		}
+		m.giveTurn()
+		m.emitError(function, err)
+		return nil, err
+	}
+	m.inUse.Add(1)
+
+	return i, nil
+}
+
+// get fetches an instance from the provided pool, waiting up to the timeout. The wait is abandoned as soon
+// as the context is done, returning the context error as with takeTurn; an instance fetched after the wait
+// is abandoned is returned to the pool. Returned errors are classified with poolError.
+func (m *Module) get(ctx context.Context, pool *wapc.Pool, timeout time.Duration) (wapc.Instance, error) {
+	if ctx.Done() == nil {
+		i, err := pool.Get(timeout)
+		if err != nil {
+			return nil, m.poolError(err)
+		}
+		return i, nil
+	}
+
+	type result struct {
+		i   wapc.Instance
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		i, err := pool.Get(timeout)
+		ch <- result{i: i, err: err}
+	}()
+
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			return nil, m.poolError(r.err)
+		}
+		return r.i, nil
+	case <-ctx.Done():
+		go func() {
+			if r := <-ch; r.err == nil {
+				if err := pool.Return(r.i); err != nil {
+					_ = r.i.Close(m.ctx)
+				}
+			}
+		}()
+		return nil, contextPoolError(ctx)
+	}
+}
+
+// release returns a module instance to the provided pool. Aborted instances are closed by the runtime and
+// are replaced.
+func (m *Module) release(pool *wapc.Pool, i wapc.Instance, aborted bool) {
+	m.inUse.Add(^uint64(0))
+	defer m.active.Add(-1)
+	defer m.giveTurn()
+
+	if aborted {
+		m.replace(pool, i)
+		return
+	}
+
+	if err := pool.Return(i); err != nil {
+		defer i.Close(m.ctx)
+	}
+}
+
+// invoke calls the user-provided function with the user-provided payload on the provided instance, recording
+// invocation stats. It reports whether the invocation was aborted by the context.
+func (m *Module) invoke(ctx context.Context, i wapc.Instance, function string, payload []byte) ([]byte, bool, error) {
+	// Record memory size before invocation
+	var stats RunStats
+	if m.runStatsFunc != nil {
+		stats = RunStats{Function: function, Instance: m.instanceID(i), MemoryBefore: i.MemorySize()}
+	}
+
 	// Invoke the module with the user-provided function and payload
-	r, err = i.Invoke(m.ctx, function, payload)
+	var aborted bool
+	// Capture standard output written during the invocation
+	var stdout *bytes.Buffer
+	if m.config.ReturnStdout {
+		stdout = &bytes.Buffer{}
+		ctx = withStdoutCapture(ctx, stdout)
+	}
+
+	m.invocations.Add(1)
+	start := time.Now()
+	r, err := i.Invoke(m.invocationContext(ctx, function), function, payload)
+	m.invocationTime.Add(int64(time.Since(start)))
+	if err == nil && stdout != nil {
+		r = stdout.Bytes()
+	}
 	if err != nil {
-		return r, err
+		m.failures.Add(1)
+		err = invokeError(function, err)
+
+		// Identify invocations aborted by the context
+		if ctx.Err() != nil {
+			aborted = true
+			err = fmt.Errorf("%w - %w", context.Cause(ctx), err)
+		}
+
+		m.emitError(function, err)
+	}
+
+	// Record memory size after invocation
+	if m.runStatsFunc != nil {
+		stats.MemoryAfter = i.MemorySize()
+		stats.Err = err
+		m.runStatsFunc(stats)
+	}
+
+	return r, aborted, err
+}
+
+// InvokeAll will call the user-provided function with the user-provided payload on every instance within the
+// module pool. This is useful for guests that maintain per-instance state, such as caches, that need
+// coordinated updates.
+//
+// Each instance is taken from the pool and held until every instance has been invoked, guaranteeing each
+// instance is invoked exactly once. Once complete, all instances are returned to the pool. While InvokeAll
+// is running, calls to Run will wait for an available instance.
+//
+// The returned slice contains one entry per pool instance; entries are nil for successful invocations.
+func (m *Module) InvokeAll(function string, payload []byte) []error {
+	m.broadcast.Lock()
+	defer m.broadcast.Unlock()
+
+	m.active.Add(1)
+	defer m.active.Add(-1)
+
+	errs := make([]error, m.poolSize.Load())
+	instances := make([]wapc.Instance, 0, len(errs))
+
+	// Apply the function filter
+	function, err := m.filterFunction(function)
+	if err != nil {
+		for n := range errs {
+			errs[n] = err
+		}
+		return errs
+	}
+
+	// Compress the payload using the negotiated codec
+	payload, err = compress(m.codec, payload)
+	if err != nil {
+		for n := range errs {
+			errs[n] = err
+		}
+		return errs
+	}
+
+	// Queue for the instance of a singleton module
+	if err := m.takeTurn(m.ctx); err != nil {
+		for n := range errs {
+			errs[n] = err
+		}
+		return errs
+	}
+	defer m.giveTurn()
+
+	// Return the module instances to the pool
+	defer func() {
+		m.inUse.Add(^uint64(len(instances) - 1))
+		for _, i := range instances {
+			err := m.pool.Return(i)
+			if err != nil {
+				defer i.Close(m.ctx)
+			}
+		}
+	}()
+
+	for n := range errs {
+		// Get a module instance from the pool
+		i, err := m.pool.Get(DefaultPoolTimeout * time.Second)
+		if err != nil {
+			err = m.poolError(err)
+			m.emitError(function, err)
+			for ; n < len(errs); n++ {
+				errs[n] = err
+			}
+			break
+		}
+		instances = append(instances, i)
+		m.inUse.Add(1)
+
+		// Invoke the module with the user-provided function and payload
+		m.invocations.Add(1)
+		start := time.Now()
+		_, err = i.Invoke(m.invocationContext(m.invokeContext(m.ctx), function), function, payload)
+		m.invocationTime.Add(int64(time.Since(start)))
+		if err != nil {
+			m.failures.Add(1)
+			errs[n] = invokeError(function, err)
+			m.emitError(function, errs[n])
+		}
+	}
+
+	return errs
+}
+
+// replace closes an aborted instance and adds a new instance to the pool in its place. If a new instance
+// cannot be created, the pool shrinks.
+func (m *Module) replace(pool *wapc.Pool, i wapc.Instance) {
+	_ = i.Close(m.ctx)
+
+	n, err := m.module.Instantiate(m.ctx)
+	if err != nil {
+		m.instantiationFailures.Add(1)
+		if m.emit != nil {
+			m.emit(ServerEvent{
+				Type:   EventInstantiationFailed,
+				Module: m.Name,
+				Err:    fmt.Errorf("%w - %w", ErrInstantiationFailed, err),
+			})
+		}
+	} else {
+		err = pool.Return(n)
+	}
+	if err != nil {
+		if n != nil {
+			_ = n.Close(m.ctx)
+		}
+		if pool == m.pool {
+			m.poolSize.Add(^uint64(0))
+		}
+	}
+}
+
+// close closes the module pools and the module, and cancels the module context.
+func (m *Module) close() {
+	if m.reentrantPool != nil {
+		m.reentrantPool.Close(m.ctx)
 	}
+	m.pool.Close(m.ctx)
+	m.module.Close(m.ctx)
+	m.cancel()
+}
 
-	return r, nil
+// drain waits until no callers are waiting for or holding instances of the module, such as in-flight
+// invocations, sessions, and buffers, for up to the provided timeout. It returns false if the timeout
+// elapsed first.
+func (m *Module) drain(timeout time.Duration) bool {
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for m.active.Load() > 0 {
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			return false
+		}
+	}
+	return true
+}
+
+// filterFunction applies the module's FunctionFilter, returning the function name to invoke.
+func (m *Module) filterFunction(function string) (string, error) {
+	if m.functionFilter == nil {
+		return function, nil
+	}
+
+	name, err := m.functionFilter(function)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s - %w", ErrFunctionNotAllowed, function, err)
+	}
+	return name, nil
+}
+
+// poolError classifies errors returned when fetching an instance from the pool. Timeouts are wrapped with
+// ErrPoolTimeout.
+func (m *Module) poolError(err error) error {
+	if errors.Is(err, queue.ErrTimeout) {
+		return fmt.Errorf("%w - could not fetch module from pool - %w", ErrPoolTimeout, err)
+	}
+	return fmt.Errorf("could not fetch module from pool - %w", err)
+}
+
+// contextPoolError returns the error for a wait on the pool abandoned because the context is done. As with pool
+// timeouts, an expired deadline is reported as ErrPoolTimeout.
+func contextPoolError(ctx context.Context) error {
+	err := context.Cause(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w - could not fetch module from pool - %w", ErrPoolTimeout, err)
+	}
+	return fmt.Errorf("could not fetch module from pool - %w", err)
+}
+
+// emitError sends an event describing the invocation error to the Server events channel.
+func (m *Module) emitError(function string, err error) {
+	if m.emit == nil {
+		return
+	}
+
+	ev := ServerEvent{Type: EventInvocationError, Module: m.Name, Function: function, Err: err}
+	switch {
+	case errors.Is(err, ErrPoolTimeout):
+		ev.Type = EventPoolTimeout
+	case errors.Is(err, ErrInstantiationFailed):
+		ev.Type = EventInstantiationFailed
+	}
+	m.emit(ev)
+}
+
+// invokeError classifies errors returned when invoking a guest function. If the guest reports the function is
+// not registered, the error is wrapped with ErrFunctionNotFound.
+func invokeError(function string, err error) error {
+	if strings.HasPrefix(err.Error(), guestFunctionNotFound) {
+		return fmt.Errorf("%w: %s - %w", ErrFunctionNotFound, function, err)
+	}
+	return err
 }
@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultRetryAfter is the retry-after hint used for pool timeouts and concurrency limit rejections when
+// ModuleConfig.RetryAfter is not provided.
+const DefaultRetryAfter = 100 * time.Millisecond
+
+// PoolTimeoutError is returned when no instance becomes available from the module pool in time. It wraps the
+// pool error, so errors.Is(err, ErrPoolTimeout) continues to match, while errors.As provides the retry-after
+// hint.
+type PoolTimeoutError struct {
+	// RetryAfter is how long the caller should wait before retrying the invocation.
+	//
+	// The hint is the mean duration of the module's invocations, the time an in-use instance can be expected
+	// to return to the pool, but never less than ModuleConfig.RetryAfter. It is a hint only; the pool may
+	// still be busy after waiting.
+	RetryAfter time.Duration
+
+	// Err is the underlying pool error.
+	Err error
+}
+
+// Error returns the error message, including the retry-after hint.
+func (e *PoolTimeoutError) Error() string {
+	return fmt.Sprintf("%s - retry after %s", e.Err, e.RetryAfter)
+}
+
+// Unwrap returns the underlying pool error.
+func (e *PoolTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// ConcurrencyLimitError is returned when a module is already running its MaxConcurrentRuns invocations. It
+// wraps the concurrency limit error, so errors.Is(err, ErrConcurrencyLimit) continues to match, while
+// errors.As provides the retry-after hint.
+type ConcurrencyLimitError struct {
+	// RetryAfter is how long the caller should wait before retrying the invocation, derived in the same way
+	// as PoolTimeoutError.RetryAfter.
+	RetryAfter time.Duration
+
+	// Err is the underlying concurrency limit error.
+	Err error
+}
+
+// Error returns the error message, including the retry-after hint.
+func (e *ConcurrencyLimitError) Error() string {
+	return fmt.Sprintf("%s - retry after %s", e.Err, e.RetryAfter)
+}
+
+// Unwrap returns the underlying concurrency limit error.
+func (e *ConcurrencyLimitError) Unwrap() error {
+	return e.Err
+}
+
+// poolTimeout wraps the pool timeout error with a retry-after hint derived from the module's invocation
+// durations.
+func (m *Module) poolTimeout(err error) error {
+	return &PoolTimeoutError{RetryAfter: m.retryAfter(), Err: err}
+}
+
+// concurrencyLimit wraps the concurrency limit error with a retry-after hint derived from the module's
+// invocation durations.
+func (m *Module) concurrencyLimit(err error) error {
+	return &ConcurrencyLimitError{RetryAfter: m.retryAfter(), Err: err}
+}
+
+// retryAfter returns the mean duration of the module's invocations, but never less than the configured
+// RetryAfter, or DefaultRetryAfter if not provided.
+func (m *Module) retryAfter() time.Duration {
+	retryAfter := m.config.RetryAfter
+	if retryAfter == 0 {
+		retryAfter = DefaultRetryAfter
+	}
+	if n := m.invocations.Load(); n > 0 {
+		if mean := time.Duration(m.invocationTime.Load() / int64(n)); mean > retryAfter {
+			retryAfter = mean
+		}
+	}
+	return retryAfter
+}
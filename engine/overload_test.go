@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPoolTimeoutRetryAfter(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	t.Run("Invalid config", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{
+			Name:       "Invalid",
+			Filepath:   "../testdata/hello-go/hello.wasm",
+			RetryAfter: -time.Second,
+		})
+		if !errors.Is(err, ErrInvalidModuleConfig) {
+			t.Errorf("Expected invalid module config error, got: %s", err)
+		}
+	})
+
+	err = s.LoadModule(ModuleConfig{
+		Name:       "AModule",
+		PoolSize:   1,
+		Filepath:   "../testdata/hello-go/hello.wasm",
+		RetryAfter: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Pool timeout", func(t *testing.T) {
+		session, err := m.Session()
+		if err != nil {
+			t.Fatalf("Unexpected error creating session - %s", err)
+		}
+		defer session.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err = m.RunWithContext(ctx, "example", []byte("hello"))
+		if !errors.Is(err, ErrPoolTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected pool timeout and deadline exceeded errors, got: %s", err)
+		}
+
+		var perr *PoolTimeoutError
+		if !errors.As(err, &perr) {
+			t.Fatalf("Expected PoolTimeoutError, got: %T", err)
+		}
+		if perr.RetryAfter != time.Second {
+			t.Errorf("Unexpected retry-after hint: %s", perr.RetryAfter)
+		}
+	})
+
+	t.Run("Derived from invocation time", func(t *testing.T) {
+		m.invocations.Store(2)
+		m.invocationTime.Store(int64(6 * time.Second))
+
+		var perr *PoolTimeoutError
+		if !errors.As(m.poolTimeout(ErrPoolTimeout), &perr) {
+			t.Fatalf("Expected PoolTimeoutError")
+		}
+		if perr.RetryAfter != 3*time.Second {
+			t.Errorf("Unexpected retry-after hint: %s", perr.RetryAfter)
+		}
+	})
+}
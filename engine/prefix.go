@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// prefixWriter is an io.Writer that prefixes each line written to the underlying writer.
+type prefixWriter struct {
+	sync.Mutex
+
+	// w is the underlying writer.
+	w io.Writer
+
+	// prefix is written before each line.
+	prefix []byte
+
+	// lineStart is true when the next byte written starts a new line.
+	lineStart bool
+}
+
+// newPrefixWriter returns a writer that prefixes each line written to w with the provided prefix.
+func newPrefixWriter(w io.Writer, prefix string) *prefixWriter {
+	return &prefixWriter{
+		w:         w,
+		prefix:    []byte(prefix),
+		lineStart: true,
+	}
+}
+
+// Write writes p to the underlying writer, prefixing the start of each line.
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	buf := make([]byte, 0, len(p)+len(w.prefix))
+	for _, line := range bytes.SplitAfter(p, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if w.lineStart {
+			buf = append(buf, w.prefix...)
+		}
+		buf = append(buf, line...)
+		w.lineStart = line[len(line)-1] == '\n'
+	}
+
+	if _, err := w.w.Write(buf); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"bytes"
+	"testing"
+)
+
+type PrefixWriterTestCase struct {
+	Name   string
+	Writes []string
+	Output string
+}
+
+func TestPrefixWriter(t *testing.T) {
+	tt := []PrefixWriterTestCase{
+		{
+			Name:   "Single line",
+			Writes: []string{"hello\n"},
+			Output: "[mod] hello\n",
+		},
+		{
+			Name:   "Multiple lines",
+			Writes: []string{"hello\nworld\n"},
+			Output: "[mod] hello\n[mod] world\n",
+		},
+		{
+			Name:   "Partial lines",
+			Writes: []string{"hel", "lo\nwor", "ld\n"},
+			Output: "[mod] hello\n[mod] world\n",
+		},
+		{
+			Name:   "No trailing newline",
+			Writes: []string{"hello"},
+			Output: "[mod] hello",
+		},
+		{
+			Name:   "Empty write",
+			Writes: []string{""},
+			Output: "",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := newPrefixWriter(&buf, "[mod] ")
+			for _, s := range tc.Writes {
+				n, err := w.Write([]byte(s))
+				if err != nil {
+					t.Fatalf("Unexpected error writing - %s", err)
+				}
+				if n != len(s) {
+					t.Errorf("Unexpected write length: %d, expected: %d", n, len(s))
+				}
+			}
+
+			if buf.String() != tc.Output {
+				t.Errorf("Unexpected output: %q, expected: %q", buf.String(), tc.Output)
+			}
+		})
+	}
+}
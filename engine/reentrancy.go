@@ -0,0 +1,30 @@
+package engine
+
+import (
+	"context"
+
+	wapc "github.com/wapc/wapc-go"
+)
+
+// invokingModuleKey is the context key used to identify the module that triggered a host callback.
+type invokingModuleKey struct{}
+
+// invokeContext returns the provided context extended to identify this module, so that reentrant
+// invocations made from host callbacks can be detected.
+func (m *Module) invokeContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, invokingModuleKey{}, m)
+}
+
+// reentrant returns true if the provided context belongs to a host callback triggered by this module.
+func (m *Module) reentrant(ctx context.Context) bool {
+	invoking, ok := ctx.Value(invokingModuleKey{}).(*Module)
+	return ok && invoking == m
+}
+
+// poolFor returns the pool used for invocations made with the provided context.
+func (m *Module) poolFor(ctx context.Context) *wapc.Pool {
+	if m.reentrantPool != nil && m.reentrant(ctx) {
+		return m.reentrantPool
+	}
+	return m.pool
+}
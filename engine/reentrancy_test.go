@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestModuleReentrancy(t *testing.T) {
+	var m *Module
+	var reentrantErr error
+	s, err := New(ServerConfig{
+		Callback: func(ctx context.Context, _, _, _ string, _ []byte) ([]byte, error) {
+			// Call back into the module that triggered the callback
+			_, reentrantErr = m.RunWithContext(ctx, "ThisBetterFail", []byte("hello"))
+			return []byte(""), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:              "AModule",
+		PoolSize:          1,
+		ReentrantPoolSize: 1,
+		Filepath:          "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err = s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Reentrant invocation", func(t *testing.T) {
+		_, err := m.RunWithContext(context.Background(), "example", []byte("hello"))
+		if err != nil {
+			t.Fatalf("Unexpected error running module - %s", err)
+		}
+
+		// The reentrant invocation should reach the guest rather than waiting on the saturated pool
+		if !errors.Is(reentrantErr, ErrFunctionNotFound) {
+			t.Errorf("Expected function not found error from reentrant invocation, got: %s", reentrantErr)
+		}
+	})
+
+	t.Run("Non-reentrant context", func(t *testing.T) {
+		if m.reentrant(context.Background()) {
+			t.Errorf("Expected background context to be non-reentrant")
+		}
+		if !m.reentrant(m.invokeContext(context.Background())) {
+			t.Errorf("Expected invocation context to be reentrant")
+		}
+	})
+
+	t.Run("Canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := m.RunWithContext(ctx, "example", []byte("hello"))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context canceled error, got: %s", err)
+		}
+	})
+}
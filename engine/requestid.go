@@ -0,0 +1,27 @@
+package engine
+
+import (
+	"context"
+)
+
+// requestIDKey is the context key for request IDs.
+//
+// The key is an unnamed struct type with an exported field, rather than a named type, so that it is identical
+// across packages. This allows the callbacks package to read request IDs set by this package without either
+// package importing the other.
+type requestIDKey = struct{ WapcToolkitRequestID struct{} }
+
+// WithRequestID returns a copy of the context carrying the provided request ID.
+//
+// When the context is provided to RunWithContext, the request ID is propagated to the host callbacks
+// performed by the guest, where it can be read with callbacks.RequestIDFromContext. This ties guest and
+// host logs together for a single request.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by the context, if any.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
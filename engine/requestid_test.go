@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRequestIDPropagation(t *testing.T) {
+	var received string
+	s, err := New(ServerConfig{
+		Callback: func(ctx context.Context, _, _, _ string, _ []byte) ([]byte, error) {
+			// Read the request ID using the key convention shared with the callbacks package
+			received, _ = ctx.Value(struct{ WapcToolkitRequestID struct{} }{}).(string)
+			return []byte(""), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	ctx := WithRequestID(context.Background(), "request-1")
+	if id, ok := RequestIDFromContext(ctx); !ok || id != "request-1" {
+		t.Fatalf("Unexpected request ID: %s", id)
+	}
+
+	if _, err := m.RunWithContext(ctx, "example", []byte("hello")); err != nil {
+		t.Fatalf("Unexpected error running module - %s", err)
+	}
+
+	if received != "request-1" {
+		t.Errorf("Unexpected request ID received by callback: %q, expected: request-1", received)
+	}
+}
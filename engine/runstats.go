@@ -0,0 +1,46 @@
+package engine
+
+import (
+	wapc "github.com/wapc/wapc-go"
+)
+
+// RunStats describes a single guest function invocation made via Run. RunStats are provided to the
+// RunStatsFunc defined within the ModuleConfig.
+//
+// Guest linear memory can grow but never shrinks; comparing MemoryBefore and MemoryAfter across calls on the
+// same Instance shows whether a guest is continually growing its memory, which is a common symptom of a leak.
+type RunStats struct {
+	// Function is the guest function invoked.
+	Function string
+
+	// Instance identifies the pool instance that executed the invocation. Identifiers are assigned the
+	// first time an instance is used and are unique within the module.
+	Instance uint64
+
+	// MemoryBefore is the size, in bytes, of the instance's linear memory before the invocation.
+	MemoryBefore uint32
+
+	// MemoryAfter is the size, in bytes, of the instance's linear memory after the invocation.
+	MemoryAfter uint32
+
+	// Err is the error returned by the invocation, if any.
+	Err error
+}
+
+// MemoryGrowth returns the number of bytes the instance's linear memory grew during the invocation.
+func (rs RunStats) MemoryGrowth() uint32 {
+	if rs.MemoryAfter < rs.MemoryBefore {
+		return 0
+	}
+	return rs.MemoryAfter - rs.MemoryBefore
+}
+
+// instanceID returns the identifier of the provided pool instance, assigning one if needed.
+func (m *Module) instanceID(i wapc.Instance) uint64 {
+	if id, ok := m.instanceIDs.Load(i); ok {
+		return id.(uint64) //nolint:forcetypeassert // instanceIDs only stores uint64 values
+	}
+
+	id, _ := m.instanceIDs.LoadOrStore(i, m.nextInstanceID.Add(1))
+	return id.(uint64) //nolint:forcetypeassert // instanceIDs only stores uint64 values
+}
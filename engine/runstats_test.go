@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestRunStats(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	var mu sync.Mutex
+	var stats []RunStats
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+		RunStatsFunc: func(rs RunStats) {
+			mu.Lock()
+			defer mu.Unlock()
+			stats = append(stats, rs)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	if _, err := m.Run("example", []byte("hello")); err != nil {
+		t.Fatalf("Unexpected error running module - %s", err)
+	}
+	if _, err := m.Run("ThisBetterFail", []byte("hello")); err == nil {
+		t.Fatalf("Expected error running unknown function")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(stats) != 2 {
+		t.Fatalf("Unexpected number of RunStats: %d, expected: 2", len(stats))
+	}
+
+	t.Run("Successful invocation", func(t *testing.T) {
+		rs := stats[0]
+		if rs.Function != "example" || rs.Err != nil {
+			t.Errorf("Unexpected RunStats: %+v", rs)
+		}
+		if rs.MemoryBefore == 0 || rs.MemoryAfter < rs.MemoryBefore {
+			t.Errorf("Unexpected memory sizes: %d before, %d after", rs.MemoryBefore, rs.MemoryAfter)
+		}
+		if rs.MemoryGrowth() != rs.MemoryAfter-rs.MemoryBefore {
+			t.Errorf("Unexpected memory growth: %d", rs.MemoryGrowth())
+		}
+	})
+
+	t.Run("Failed invocation", func(t *testing.T) {
+		rs := stats[1]
+		if !errors.Is(rs.Err, ErrFunctionNotFound) {
+			t.Errorf("Expected function not found error, got: %s", rs.Err)
+		}
+	})
+
+	t.Run("Same instance", func(t *testing.T) {
+		if stats[0].Instance == 0 || stats[0].Instance != stats[1].Instance {
+			t.Errorf("Expected both invocations on the same instance, got: %d, %d", stats[0].Instance, stats[1].Instance)
+		}
+	})
+}
+
+func TestRunStatsMemoryGrowth(t *testing.T) {
+	rs := RunStats{MemoryBefore: 65536, MemoryAfter: 131072}
+	if rs.MemoryGrowth() != 65536 {
+		t.Errorf("Unexpected memory growth: %d, expected: 65536", rs.MemoryGrowth())
+	}
+
+	rs = RunStats{MemoryBefore: 131072, MemoryAfter: 65536}
+	if rs.MemoryGrowth() != 0 {
+		t.Errorf("Unexpected memory growth: %d, expected: 0", rs.MemoryGrowth())
+	}
+}
@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	wazeroruntime "github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/assemblyscript"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+var (
+	// ErrInvalidHostFunction is returned when a HostFunction or its host module name is invalid.
+	ErrInvalidHostFunction = errors.New("invalid host function")
+)
+
+const (
+	// DefaultHostModule is the default module name guests import HostFunctions from.
+	DefaultHostModule = "host"
+)
+
+// HostFunction is a typed host function that guests can import and call directly, bypassing the waPC
+// host call. HostFunctions enable advanced guests to call host functionality with WebAssembly value types
+// rather than serialized payloads.
+type HostFunction struct {
+	// Params are the WebAssembly value types of the function parameters.
+	Params []api.ValueType
+
+	// Results are the WebAssembly value types of the function results.
+	Results []api.ValueType
+
+	// Func is the host function implementation. Parameters are read from the stack, and results are
+	// written back to the stack in order.
+	Func api.GoModuleFunc
+}
+
+// validateHostFunctions validates the user-provided host module name and functions.
+func validateHostFunctions(module string, funcs map[string]HostFunction) error {
+	switch module {
+	case "wapc", "env", "wasi_snapshot_preview1":
+		return fmt.Errorf("%w: host module name %s is reserved", ErrInvalidHostFunction, module)
+	}
+
+	for name, fn := range funcs {
+		if name == "" || fn.Func == nil {
+			return fmt.Errorf("%w: host function %q must have a name and func", ErrInvalidHostFunction, name)
+		}
+	}
+
+	return nil
+}
+
+// newRuntime creates the wazero runtime used for each loaded module. It extends the default waPC runtime
+// with any user-provided HostFunctions.
+//
+// Unlike the default waPC runtime, the runtime closes module instances when the context of an invocation
+// is done, aborting the invocation.
+func (s *Server) newRuntime(ctx context.Context) (wazeroruntime.Runtime, error) {
+	return s.newRuntimeWithWASI(ctx, wasi_snapshot_preview1.NewFunctionExporter())
+}
+
+// newRuntimeWithWASI creates the wazero runtime used for each loaded module, exporting WASI host functions
+// using the provided exporter.
+func (s *Server) newRuntimeWithWASI(
+	ctx context.Context,
+	wasi wasi_snapshot_preview1.FunctionExporter,
+) (wazeroruntime.Runtime, error) {
+	rc := wazeroruntime.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if s.compilationCache != nil {
+		rc = rc.WithCompilationCache(s.compilationCache)
+	}
+	r := wazeroruntime.NewRuntimeWithConfig(ctx, rc)
+
+	// Instantiate WASI and AssemblyScript host functions, as with the default waPC runtime
+	w := r.NewHostModuleBuilder(wasi_snapshot_preview1.ModuleName)
+	wasi.ExportFunctions(w)
+	if _, err := w.Instantiate(ctx); err != nil {
+		_ = r.Close(ctx)
+		return nil, fmt.Errorf("unable to instantiate wasi - %w", err)
+	}
+
+	env := r.NewHostModuleBuilder("env")
+	assemblyscript.NewFunctionExporter().WithAbortMessageDisabled().ExportFunctions(env)
+	if _, err := env.Instantiate(ctx); err != nil {
+		_ = r.Close(ctx)
+		return nil, fmt.Errorf("unable to instantiate env - %w", err)
+	}
+
+	if len(s.hostFunctions) == 0 {
+		return r, nil
+	}
+
+	// Export host functions under the host module namespace
+	b := r.NewHostModuleBuilder(s.hostModule)
+	for name, fn := range s.hostFunctions {
+		b.NewFunctionBuilder().WithGoModuleFunction(fn.Func, fn.Params, fn.Results).Export(name)
+	}
+
+	if _, err := b.Instantiate(ctx); err != nil {
+		_ = r.Close(ctx)
+		return nil, fmt.Errorf("unable to instantiate host functions - %w", err)
+	}
+
+	return r, nil
+}
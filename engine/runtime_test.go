@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+func TestHostFunctions(t *testing.T) {
+	add := HostFunction{
+		Params:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI32},
+		Results: []api.ValueType{api.ValueTypeI32},
+		Func: func(_ context.Context, _ api.Module, stack []uint64) {
+			stack[0] = uint64(api.DecodeI32(stack[0]) + api.DecodeI32(stack[1]))
+		},
+	}
+
+	t.Run("Reserved host module", func(t *testing.T) {
+		_, err := New(ServerConfig{
+			Callback:      func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+			HostFunctions: map[string]HostFunction{"add": add},
+			HostModule:    "wapc",
+		})
+		if !errors.Is(err, ErrInvalidHostFunction) {
+			t.Errorf("Expected invalid host function error, got: %s", err)
+		}
+	})
+
+	t.Run("Nil host function", func(t *testing.T) {
+		_, err := New(ServerConfig{
+			Callback:      func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+			HostFunctions: map[string]HostFunction{"add": {}},
+		})
+		if !errors.Is(err, ErrInvalidHostFunction) {
+			t.Errorf("Expected invalid host function error, got: %s", err)
+		}
+	})
+
+	s, err := New(ServerConfig{
+		Callback:      func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+		HostFunctions: map[string]HostFunction{"add": add},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	t.Run("Host functions exported to runtime", func(t *testing.T) {
+		r, err := s.newRuntime(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected error creating runtime - %s", err)
+		}
+		defer r.Close(context.Background())
+
+		def, ok := r.Module(DefaultHostModule).ExportedFunctionDefinitions()["add"]
+		if !ok {
+			t.Fatalf("Host function not exported by runtime")
+		}
+		if len(def.ParamTypes()) != 2 || len(def.ResultTypes()) != 1 {
+			t.Errorf("Unexpected host function signature: %v -> %v", def.ParamTypes(), def.ResultTypes())
+		}
+	})
+
+	t.Run("Load and run module with host functions", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{
+			Name:     "AModule",
+			PoolSize: 1,
+			Filepath: "../testdata/hello-go/hello.wasm",
+		})
+		if err != nil {
+			t.Fatalf("Failed to load module - %s", err)
+		}
+
+		m, err := s.Module("AModule")
+		if err != nil {
+			t.Fatalf("Cannot find module - %s", err)
+		}
+
+		_, err = m.Run("example", []byte("hello"))
+		if err != nil {
+			t.Errorf("Unexpected error running module - %s", err)
+		}
+	})
+}
+
+func TestCompilationCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := wazero.NewCompilationCacheWithDir(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error creating compilation cache - %s", err)
+	}
+	defer cache.Close(context.Background())
+
+	// Load the module with two Servers sharing the cache, as with a process restart
+	for i := 0; i < 2; i++ {
+		s, err := New(ServerConfig{
+			Callback:         func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+			CompilationCache: cache,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create WASM Server - %s", err)
+		}
+
+		err = s.LoadModule(ModuleConfig{Name: "AModule", PoolSize: 1, Filepath: "../testdata/hello-go/hello.wasm"})
+		if err != nil {
+			t.Fatalf("Failed to load module - %s", err)
+		}
+
+		m, err := s.Module("AModule")
+		if err != nil {
+			t.Fatalf("Cannot find module - %s", err)
+		}
+		if _, err := m.Run("example", []byte("hello")); err != nil {
+			t.Errorf("Unexpected error running module - %s", err)
+		}
+		s.Close()
+	}
+
+	// Compiled modules are only cached by the compiler, which is not available on every platform
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		return
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error reading cache directory - %s", err)
+	}
+	if len(entries) == 0 {
+		t.Errorf("Expected compiled modules within the cache directory")
+	}
+}
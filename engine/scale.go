@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrInvalidPoolSize is returned when a module pool cannot be resized to the requested size.
+	ErrInvalidPoolSize = errors.New("invalid pool size")
+)
+
+// ScaleDown gradually reduces the module pool to the target number of instances, closing excess instances
+// evenly over the provided duration rather than all at once. This smooths memory reclamation for large pools
+// and avoids a latency blip caused by closing many instances at the same time.
+//
+// ScaleDown returns immediately; instances are closed in the background as they become idle. Calling
+// ScaleDown while a previous ScaleDown is in progress replaces the previous target. If the pool is already
+// at or below the target, ScaleDown does nothing.
+//
+// The target must be at least one. Closed instances are not re-created.
+func (m *Module) ScaleDown(target uint64, over time.Duration) error {
+	if target < 1 {
+		return fmt.Errorf("%w: target must be greater than zero", ErrInvalidPoolSize)
+	}
+
+	m.scale.Lock()
+	defer m.scale.Unlock()
+
+	// Stop any in-progress scale down
+	if m.scaleCancel != nil {
+		m.scaleCancel()
+		m.scaleCancel = nil
+	}
+
+	current := m.poolSize.Load()
+	if current <= target {
+		return nil
+	}
+
+	// Spread instance closures evenly over the duration
+	interval := over / time.Duration(current-target)
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.scaleCancel = cancel
+	go m.scaleDown(ctx, target, interval)
+
+	return nil
+}
+
+// scaleDown closes one idle instance per interval until the pool reaches the target size or the context is
+// canceled.
+func (m *Module) scaleDown(ctx context.Context, target uint64, interval time.Duration) {
+	for m.poolSize.Load() > target {
+		if interval > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		m.retire()
+	}
+}
+
+// retire takes an idle instance from the pool and closes it, reducing the pool size by one.
+//
+// The instance is taken before acquiring the broadcast lock, so InvokeAll is never held up while retire waits
+// for an idle instance. If InvokeAll is running, the instance is returned to it and retire tries again on the
+// next interval.
+func (m *Module) retire() {
+	i, err := m.pool.Get(DefaultPoolTimeout * time.Second)
+	if err != nil {
+		// All instances are busy, retry on the next interval
+		return
+	}
+
+	// Prevent InvokeAll from waiting on the instance being retired
+	if !m.broadcast.TryLock() {
+		if err := m.pool.Return(i); err != nil {
+			_ = i.Close(m.ctx)
+		}
+		return
+	}
+	defer m.broadcast.Unlock()
+
+	_ = i.Close(m.ctx)
+	m.poolSize.Add(^uint64(0))
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestModuleScaleDown(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 4,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Invalid target", func(t *testing.T) {
+		err := m.ScaleDown(0, time.Millisecond)
+		if !errors.Is(err, ErrInvalidPoolSize) {
+			t.Errorf("Expected invalid pool size error, got: %s", err)
+		}
+	})
+
+	t.Run("Target above pool size", func(t *testing.T) {
+		err := m.ScaleDown(10, time.Millisecond)
+		if err != nil {
+			t.Fatalf("Unexpected error scaling down - %s", err)
+		}
+		if m.poolSize.Load() != 4 {
+			t.Errorf("Unexpected pool size: %d, expected: 4", m.poolSize.Load())
+		}
+	})
+
+	t.Run("Scale down", func(t *testing.T) {
+		err := m.ScaleDown(2, 20*time.Millisecond)
+		if err != nil {
+			t.Fatalf("Unexpected error scaling down - %s", err)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for m.poolSize.Load() > 2 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if m.poolSize.Load() != 2 {
+			t.Fatalf("Unexpected pool size: %d, expected: 2", m.poolSize.Load())
+		}
+
+		if _, err := m.Run("example", []byte("hello")); err != nil {
+			t.Errorf("Unexpected error running module after scale down - %s", err)
+		}
+
+		errs := m.InvokeAll("example", []byte("hello"))
+		if len(errs) != 2 {
+			t.Fatalf("Unexpected number of InvokeAll results: %d, expected: 2", len(errs))
+		}
+		for _, err := range errs {
+			if err != nil {
+				t.Errorf("Unexpected error invoking all instances - %s", err)
+			}
+		}
+	})
+	t.Run("Retire during InvokeAll", func(t *testing.T) {
+		// Hold the broadcast lock as a running InvokeAll would
+		m.broadcast.Lock()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			m.retire()
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected retire to return without waiting on InvokeAll")
+		}
+		m.broadcast.Unlock()
+
+		if m.poolSize.Load() != 2 {
+			t.Errorf("Unexpected pool size: %d, expected: 2", m.poolSize.Load())
+		}
+		if _, err := m.Run("example", []byte("hello")); err != nil {
+			t.Errorf("Unexpected error running module after retire - %s", err)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"errors"
+	"sync"
+
+	wapc "github.com/wapc/wapc-go"
+)
+
+var (
+	// ErrSessionClosed is returned when a Session is used after it has been closed.
+	ErrSessionClosed = errors.New("session closed")
+)
+
+// Session holds a single module instance taken from the module pool, allowing a sequence of related calls to
+// be made on the same instance. This enables workflows that rely on in-instance guest state across calls.
+//
+// The instance is held, and unavailable to other callers, until the Session is closed. Calls made on a
+// Session are serialized.
+type Session struct {
+	sync.Mutex
+
+	// module is the module the instance belongs to.
+	module *Module
+
+	// instance is the module instance held by the session.
+	instance wapc.Instance
+
+	// closed is set once the session has been closed.
+	closed bool
+}
+
+// Session takes an instance from the module pool and returns a Session holding it. Callers must Close the
+// Session to return the instance to the pool.
+//
+// If no instance becomes available within the pool timeout, ErrPoolTimeout is returned.
+func (m *Module) Session() (*Session, error) {
+	i, err := m.acquire(m.ctx, m.pool, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{module: m, instance: i}, nil
+}
+
+// Call calls the user-provided function with the user-provided payload on the instance held by the Session.
+// Calls are subject to the same function filtering, compression, and stats as Run.
+//
+// If a call is aborted, the instance is replaced within the pool and the Session is closed.
+func (s *Session) Call(function string, payload []byte) ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.closed {
+		return nil, ErrSessionClosed
+	}
+
+	function, payload, err := s.module.prepare(function, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	r, aborted, err := s.module.invoke(s.module.invokeContext(s.module.ctx), s.instance, function, payload)
+	if aborted {
+		// The instance was closed by the runtime, end the session and replace the instance
+		s.closed = true
+		s.module.release(s.module.pool, s.instance, true)
+	}
+	if err != nil {
+		return r, err
+	}
+
+	// Decompress the response using the negotiated codec
+	return decompress(s.module.codec, r, s.module.maxDecompressedSize)
+}
+
+// Close returns the instance held by the Session to the module pool. Closing a Session more than once has no
+// effect.
+func (s *Session) Close() {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+
+	s.module.release(s.module.pool, s.instance, false)
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestModuleSession(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	session, err := m.Session()
+	if err != nil {
+		t.Fatalf("Unexpected error creating session - %s", err)
+	}
+
+	t.Run("Multiple calls", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			rsp, err := session.Call("example", []byte("hello"))
+			if err != nil {
+				t.Fatalf("Unexpected error calling function - %s", err)
+			}
+			if string(rsp) != "Hello World!" {
+				t.Errorf("Unexpected response: %s", rsp)
+			}
+		}
+		if _, err := session.Call("ThisBetterFail", []byte("hello")); !errors.Is(err, ErrFunctionNotFound) {
+			t.Errorf("Expected function not found error, got: %s", err)
+		}
+	})
+
+	t.Run("Instance held", func(t *testing.T) {
+		if m.inUse.Load() != 1 {
+			t.Errorf("Unexpected in use instances: %d, expected: 1", m.inUse.Load())
+		}
+	})
+
+	t.Run("Closed session", func(t *testing.T) {
+		session.Close()
+		session.Close()
+
+		if _, err := session.Call("example", []byte("hello")); !errors.Is(err, ErrSessionClosed) {
+			t.Errorf("Expected session closed error, got: %s", err)
+		}
+
+		// The instance should be returned to the pool
+		done := make(chan error, 1)
+		go func() {
+			_, err := m.Run("example", []byte("hello"))
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Unexpected error running module after session closed - %s", err)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("Expected instance to be returned to the pool")
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Workiva/go-datastructures/queue"
+)
+
+var (
+	// ErrSingletonReentrant is returned when a host callback triggered by a singleton module invokes the same
+	// module. The module's only instance is held by the invocation that triggered the callback, so the
+	// reentrant invocation could never proceed.
+	ErrSingletonReentrant = errors.New("reentrant invocation of singleton module")
+)
+
+// takeTurn waits for the turn to use the instance of a singleton module, queueing behind any invocation
+// currently using it. It returns immediately for modules that are not singletons.
+//
+// The wait is bounded by the pool timeout, or the context deadline if sooner, and either is reported as
+// ErrPoolTimeout. This prevents a reentrant invocation that cannot be detected, such as a host callback
+// calling Run, from waiting forever on the turn held by the invocation that triggered it. If the context is
+// canceled while waiting, the context error is returned.
+func (m *Module) takeTurn(ctx context.Context) error {
+	if m.turn == nil {
+		return nil
+	}
+
+	timer := time.NewTimer(DefaultPoolTimeout * time.Second)
+	defer timer.Stop()
+
+	select {
+	case m.turn <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return m.poolTimeout(m.poolError(queue.ErrTimeout))
+	case <-ctx.Done():
+		return contextPoolError(ctx)
+	}
+}
+
+// checkReentrant returns ErrSingletonReentrant if the module is a singleton and the provided context belongs
+// to a host callback triggered by this module.
+func (m *Module) checkReentrant(ctx context.Context) error {
+	if m.turn != nil && m.reentrant(ctx) {
+		return fmt.Errorf("%w: %s", ErrSingletonReentrant, m.Name)
+	}
+	return nil
+}
+
+// giveTurn releases the turn to use the instance of a singleton module, allowing the next queued
+// invocation to proceed.
+func (m *Module) giveTurn() {
+	if m.turn != nil {
+		<-m.turn
+	}
+}
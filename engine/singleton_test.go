@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSingletonModule(t *testing.T) {
+	var m *Module
+	var active, maxActive atomic.Int32
+	var reentrant atomic.Bool
+	var reentrantErr error
+	s, err := New(ServerConfig{
+		Callback: func(ctx context.Context, _, _, _ string, _ []byte) ([]byte, error) {
+			if reentrant.Load() {
+				// Call back into the singleton module that triggered the callback
+				_, reentrantErr = m.RunWithContext(ctx, "example", []byte("hello"))
+				return []byte(""), nil
+			}
+
+			n := active.Add(1)
+			defer active.Add(-1)
+			for {
+				m := maxActive.Load()
+				if n <= m || maxActive.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			return []byte(""), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	t.Run("Invalid config", func(t *testing.T) {
+		for _, cfg := range []ModuleConfig{
+			{Name: "Invalid", Filepath: "../testdata/hello-go/hello.wasm", Singleton: true, PoolSize: 2},
+			{Name: "Invalid", Filepath: "../testdata/hello-go/hello.wasm", Singleton: true, ReentrantPoolSize: 1},
+		} {
+			if err := s.LoadModule(cfg); !errors.Is(err, ErrInvalidModuleConfig) {
+				t.Errorf("Expected invalid module config error, got: %s", err)
+			}
+		}
+	})
+
+	var instances sync.Map
+	err = s.LoadModule(ModuleConfig{
+		Name:      "AModule",
+		Filepath:  "../testdata/hello-go/hello.wasm",
+		Singleton: true,
+		RunStatsFunc: func(stats RunStats) {
+			instances.Store(stats.Instance, struct{}{})
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err = s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Single instance", func(t *testing.T) {
+		if m.poolSize.Load() != 1 {
+			t.Errorf("Unexpected pool size: %d, expected: 1", m.poolSize.Load())
+		}
+	})
+
+	t.Run("Serialized invocations", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := m.Run("example", []byte("hello")); err != nil {
+					t.Errorf("Unexpected error running module - %s", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if maxActive.Load() != 1 {
+			t.Errorf("Unexpected concurrent invocations: %d, expected: 1", maxActive.Load())
+		}
+
+		var n int
+		instances.Range(func(any, any) bool {
+			n++
+			return true
+		})
+		if n != 1 {
+			t.Errorf("Unexpected number of instances used: %d, expected: 1", n)
+		}
+	})
+
+	t.Run("Queued invocation deadline", func(t *testing.T) {
+		session, err := m.Session()
+		if err != nil {
+			t.Fatalf("Unexpected error creating session - %s", err)
+		}
+		defer session.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err = m.RunWithContext(ctx, "example", []byte("hello"))
+		if !errors.Is(err, ErrPoolTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected pool timeout and deadline exceeded errors, got: %s", err)
+		}
+	})
+
+	t.Run("InvokeAll", func(t *testing.T) {
+		errs := m.InvokeAll("example", []byte("hello"))
+		if len(errs) != 1 || errs[0] != nil {
+			t.Errorf("Unexpected InvokeAll errors: %v", errs)
+		}
+	})
+	t.Run("Reentrant invocation", func(t *testing.T) {
+		reentrant.Store(true)
+		defer reentrant.Store(false)
+
+		_, err := m.RunWithContext(context.Background(), "example", []byte("hello"))
+		if err != nil {
+			t.Fatalf("Unexpected error running module - %s", err)
+		}
+		if !errors.Is(reentrantErr, ErrSingletonReentrant) {
+			t.Errorf("Expected singleton reentrant error, got: %s", reentrantErr)
+		}
+	})
+}
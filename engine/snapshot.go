@@ -0,0 +1,241 @@
+package engine
+
+import (
+	"sort"
+	"time"
+)
+
+// ServerSnapshot is a point-in-time view of the Server state, including every loaded module and module
+// group. It is intended for debugging and operational tooling, such as a /debug endpoint.
+type ServerSnapshot struct {
+	// Time is the time the snapshot was taken.
+	Time time.Time
+
+	// MaxModules is the maximum number of modules the Server will load, zero means unlimited.
+	MaxModules int
+
+	// HostModule is the module name guests import HostFunctions from.
+	HostModule string
+
+	// HostFunctions is the sorted list of typed host function names exported to guests.
+	HostFunctions []string
+
+	// Modules is the list of loaded modules, sorted by name.
+	Modules []ModuleSnapshot
+
+	// Groups is the list of module groups, sorted by name.
+	Groups []GroupSnapshot
+}
+
+// ModuleSnapshot is a point-in-time view of a loaded module.
+type ModuleSnapshot struct {
+	// Name is the name of the module.
+	Name string
+
+	// Config is the serializable subset of the ModuleConfig the module was loaded with.
+	Config ModuleConfigSnapshot
+
+	// PoolSize is the number of instances within the module pool.
+	PoolSize uint64
+
+	// InUse is the number of instances currently taken from the module pool.
+	InUse uint64
+
+	// Codec is the compression codec negotiated with the guest.
+	Codec CompressionCodec
+
+	// Exports is the sorted list of function names exported by the WebAssembly module.
+	Exports []string
+
+	// Invocations is the number of guest function invocations made since the module was loaded.
+	Invocations uint64
+
+	// Failures is the number of guest function invocations that returned an error since the module
+	// was loaded.
+	Failures uint64
+
+	// InstantiationFailures is the number of module instances that failed to instantiate since the module
+	// was loaded, such as when replacing aborted instances under memory pressure.
+	InstantiationFailures uint64
+}
+
+// ModuleConfigSnapshot is the serializable subset of a ModuleConfig, as reported within a ModuleSnapshot.
+// Function fields, writers, and the module Source are omitted so that snapshots can be encoded, such as to
+// JSON for a /debug endpoint.
+type ModuleConfigSnapshot struct {
+	// Name is the name of the module.
+	Name string
+
+	// Filepath is the path the module was loaded from, empty if the module was loaded from Source.
+	Filepath string
+
+	// SHA256 is the expected digest of the module binary, if provided.
+	SHA256 string
+
+	// PoolSize is the configured size of the module pool.
+	PoolSize int
+
+	// ReentrantPoolSize is the configured size of the reentrancy pool.
+	ReentrantPoolSize int
+
+	// MaxConcurrentRuns is the configured limit of concurrent runs.
+	MaxConcurrentRuns int
+
+	// Compression is the configured compression codec.
+	Compression CompressionCodec
+
+	// MaxDecompressedSize is the configured maximum size of a decompressed guest response.
+	MaxDecompressedSize int64
+
+	// CompileTimeout is the configured maximum compilation duration.
+	CompileTimeout time.Duration
+
+	// RetryAfter is the configured minimum retry-after hint.
+	RetryAfter time.Duration
+
+	// LogPrefix reports whether guest output is prefixed with the module name.
+	LogPrefix bool
+
+	// ReturnStdout reports whether guest standard output is returned as the response.
+	ReturnStdout bool
+
+	// Singleton reports whether the module is loaded with a single shared instance.
+	Singleton bool
+
+	// BlockOnConcurrencyLimit reports whether calls beyond MaxConcurrentRuns wait rather than fail.
+	BlockOnConcurrencyLimit bool
+}
+
+// PoolStats reports the usage of a module's instance pool, helping to decide whether PoolSize is undersized.
+type PoolStats struct {
+	// Capacity is the number of instances within the module pool, including any reentrancy pool.
+	Capacity uint64
+
+	// InUse is the number of instances currently taken from the pool.
+	InUse uint64
+
+	// Idle is the number of instances available within the pool.
+	Idle uint64
+
+	// Invocations is the number of guest function invocations made since the module was loaded.
+	Invocations uint64
+}
+
+// GroupSnapshot is a point-in-time view of a module group.
+type GroupSnapshot struct {
+	// Name is the name of the group.
+	Name string
+
+	// Weights is the weight of each member module, keyed by module name.
+	Weights map[string]int
+}
+
+// Snapshot returns a point-in-time view of the Server state. The snapshot is assembled under the Server
+// read lock, so it reflects a consistent set of loaded modules and groups; per-module counters are read
+// without stopping in-flight invocations.
+func (s *Server) Snapshot() ServerSnapshot {
+	s.RLock()
+	defer s.RUnlock()
+
+	snap := ServerSnapshot{
+		Time:          time.Now(),
+		MaxModules:    s.maxModules,
+		HostModule:    s.hostModule,
+		HostFunctions: make([]string, 0, len(s.hostFunctions)),
+		Modules:       make([]ModuleSnapshot, 0, len(s.modules)),
+		Groups:        make([]GroupSnapshot, 0, len(s.groups)),
+	}
+
+	for name := range s.hostFunctions {
+		snap.HostFunctions = append(snap.HostFunctions, name)
+	}
+	sort.Strings(snap.HostFunctions)
+
+	for _, m := range s.modules {
+		snap.Modules = append(snap.Modules, m.snapshot())
+	}
+	sort.Slice(snap.Modules, func(i, j int) bool {
+		return snap.Modules[i].Name < snap.Modules[j].Name
+	})
+
+	for name, g := range s.groups {
+		snap.Groups = append(snap.Groups, g.snapshot(name))
+	}
+	sort.Slice(snap.Groups, func(i, j int) bool {
+		return snap.Groups[i].Name < snap.Groups[j].Name
+	})
+
+	return snap
+}
+
+// Stats returns the current usage of the module's instance pool. Counters are read individually without
+// stopping in-flight invocations, so they may be momentarily inconsistent with each other.
+func (m *Module) Stats() PoolStats {
+	stats := PoolStats{
+		Capacity:    m.poolSize.Load() + uint64(m.config.ReentrantPoolSize),
+		InUse:       m.inUse.Load(),
+		Invocations: m.invocations.Load(),
+	}
+	if stats.Capacity > stats.InUse {
+		stats.Idle = stats.Capacity - stats.InUse
+	}
+	return stats
+}
+
+// snapshot returns a point-in-time view of the module.
+func (m *Module) snapshot() ModuleSnapshot {
+	snap := ModuleSnapshot{
+		Name:                  m.Name,
+		Config:                m.config.snapshot(),
+		PoolSize:              m.poolSize.Load(),
+		InUse:                 m.inUse.Load(),
+		Codec:                 m.codec,
+		Exports:               make([]string, 0, len(m.exports)),
+		Invocations:           m.invocations.Load(),
+		Failures:              m.failures.Load(),
+		InstantiationFailures: m.instantiationFailures.Load(),
+	}
+
+	for name := range m.exports {
+		snap.Exports = append(snap.Exports, name)
+	}
+	sort.Strings(snap.Exports)
+
+	return snap
+}
+
+// snapshot returns the serializable subset of the module configuration.
+func (cfg ModuleConfig) snapshot() ModuleConfigSnapshot {
+	return ModuleConfigSnapshot{
+		Name:                    cfg.Name,
+		Filepath:                cfg.Filepath,
+		SHA256:                  cfg.SHA256,
+		PoolSize:                cfg.PoolSize,
+		ReentrantPoolSize:       cfg.ReentrantPoolSize,
+		MaxConcurrentRuns:       cfg.MaxConcurrentRuns,
+		Compression:             cfg.Compression,
+		MaxDecompressedSize:     cfg.MaxDecompressedSize,
+		CompileTimeout:          cfg.CompileTimeout,
+		RetryAfter:              cfg.RetryAfter,
+		LogPrefix:               cfg.LogPrefix,
+		ReturnStdout:            cfg.ReturnStdout,
+		Singleton:               cfg.Singleton,
+		BlockOnConcurrencyLimit: cfg.BlockOnConcurrencyLimit,
+	}
+}
+
+// snapshot returns a point-in-time view of the group.
+func (g *group) snapshot(name string) GroupSnapshot {
+	g.Lock()
+	defer g.Unlock()
+
+	snap := GroupSnapshot{
+		Name:    name,
+		Weights: make(map[string]int, len(g.members)),
+	}
+	for _, m := range g.members {
+		snap.Weights[m.name] = m.weight
+	}
+
+	return snap
+}
@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestServerSnapshot(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback:   func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+		MaxModules: 5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	t.Run("Empty Server", func(t *testing.T) {
+		snap := s.Snapshot()
+		if snap.MaxModules != 5 {
+			t.Errorf("Unexpected MaxModules: %d, expected: 5", snap.MaxModules)
+		}
+		if len(snap.Modules) != 0 || len(snap.Groups) != 0 {
+			t.Errorf("Expected no modules or groups, got: %+v", snap)
+		}
+	})
+
+	for _, name := range []string{"stable", "canary"} {
+		err = s.LoadModule(ModuleConfig{
+			Name:     name,
+			PoolSize: 2,
+			Filepath: "../testdata/hello-go/hello.wasm",
+		})
+		if err != nil {
+			t.Fatalf("Failed to load module - %s", err)
+		}
+	}
+
+	err = s.AddToGroup("hello", "stable", 9)
+	if err != nil {
+		t.Fatalf("Unexpected error adding module to group - %s", err)
+	}
+
+	m, err := s.Module("stable")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+	if _, err := m.Run("example", []byte("hello")); err != nil {
+		t.Fatalf("Unexpected error running module - %s", err)
+	}
+	if _, err := m.Run("ThisBetterFail", []byte("hello")); err == nil {
+		t.Fatalf("Expected error running unknown function")
+	}
+
+	t.Run("Loaded Server", func(t *testing.T) {
+		snap := s.Snapshot()
+		if len(snap.Modules) != 2 {
+			t.Fatalf("Unexpected number of modules: %d, expected: 2", len(snap.Modules))
+		}
+
+		// Modules are sorted by name
+		if snap.Modules[0].Name != "canary" || snap.Modules[1].Name != "stable" {
+			t.Errorf("Unexpected module order: %s, %s", snap.Modules[0].Name, snap.Modules[1].Name)
+		}
+
+		stable := snap.Modules[1]
+		if stable.PoolSize != 2 || stable.Config.PoolSize != 2 {
+			t.Errorf("Unexpected pool size: %d, expected: 2", stable.PoolSize)
+		}
+		if stable.InUse != 0 {
+			t.Errorf("Unexpected in use instances: %d, expected: 0", stable.InUse)
+		}
+		if stable.Invocations != 2 || stable.Failures != 1 {
+			t.Errorf("Unexpected invocations: %d, failures: %d, expected: 2, 1", stable.Invocations, stable.Failures)
+		}
+		if len(stable.Exports) == 0 {
+			t.Errorf("Expected module exports")
+		}
+
+		if len(snap.Groups) != 1 || snap.Groups[0].Weights["stable"] != 9 {
+			t.Errorf("Unexpected groups: %+v", snap.Groups)
+		}
+	})
+}
+
+func TestServerSnapshotJSON(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	source, err := os.ReadFile("../testdata/hello-go/hello.wasm")
+	if err != nil {
+		t.Fatalf("Unable to read module - %s", err)
+	}
+
+	err = s.LoadModule(ModuleConfig{
+		Name:           "AModule",
+		Source:         source,
+		Stdout:         io.Discard,
+		Callback:       func(context.Context, string, string, string, []byte) ([]byte, error) { return nil, nil },
+		Logger:         func(string) {},
+		RunStatsFunc:   func(RunStats) {},
+		FunctionFilter: func(name string) (string, error) { return name, nil },
+		CompileTimeout: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	b, err := json.Marshal(s.Snapshot())
+	if err != nil {
+		t.Fatalf("Unexpected error encoding snapshot - %s", err)
+	}
+
+	var snap ServerSnapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		t.Fatalf("Unexpected error decoding snapshot - %s", err)
+	}
+	if len(snap.Modules) != 1 || snap.Modules[0].Config.Name != "AModule" ||
+		snap.Modules[0].Config.CompileTimeout != time.Minute {
+		t.Errorf("Unexpected decoded snapshot: %+v", snap)
+	}
+}
+
+func TestModuleStats(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:              "AModule",
+		PoolSize:          2,
+		ReentrantPoolSize: 1,
+		Filepath:          "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	if _, err := m.Run("example", []byte("hello")); err != nil {
+		t.Fatalf("Unexpected error running module - %s", err)
+	}
+
+	session, err := m.Session()
+	if err != nil {
+		t.Fatalf("Unexpected error creating session - %s", err)
+	}
+
+	t.Run("In use", func(t *testing.T) {
+		stats := m.Stats()
+		expected := PoolStats{Capacity: 3, InUse: 1, Idle: 2, Invocations: 1}
+		if stats != expected {
+			t.Errorf("Unexpected stats: %+v, expected: %+v", stats, expected)
+		}
+	})
+
+	t.Run("Idle", func(t *testing.T) {
+		session.Close()
+		stats := m.Stats()
+		expected := PoolStats{Capacity: 3, InUse: 0, Idle: 3, Invocations: 1}
+		if stats != expected {
+			t.Errorf("Unexpected stats: %+v, expected: %+v", stats, expected)
+		}
+	})
+}
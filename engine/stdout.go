@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	wazeroruntime "github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+const (
+	// wasiErrnoSuccess is the WASI errno returned when a call succeeds.
+	wasiErrnoSuccess = 0
+
+	// wasiErrnoBadf is the WASI errno returned for an invalid file descriptor.
+	wasiErrnoBadf = 8
+
+	// wasiErrnoFault is the WASI errno returned when a memory access is out of range.
+	wasiErrnoFault = 21
+)
+
+// stdoutCaptureKey is the context key used to store the buffer capturing an invocation's standard output.
+type stdoutCaptureKey struct{}
+
+// withStdoutCapture returns a context which captures guest standard output written during an invocation
+// into buf.
+func withStdoutCapture(ctx context.Context, buf *bytes.Buffer) context.Context {
+	return context.WithValue(ctx, stdoutCaptureKey{}, buf)
+}
+
+// newStdoutRuntime returns a function creating runtimes able to capture the standard output of individual
+// invocations. Uncaptured output is written to the provided writers.
+func (s *Server) newStdoutRuntime(stdout, stderr io.Writer) func(context.Context) (wazeroruntime.Runtime, error) {
+	return func(ctx context.Context) (wazeroruntime.Runtime, error) {
+		return s.newRuntimeWithWASI(ctx, stdoutExporter{stdout: stdout, stderr: stderr})
+	}
+}
+
+// stdoutExporter exports the WASI host functions, replacing fd_write with an implementation able to
+// capture the standard output of individual invocations.
+//
+// Guest output is otherwise written to a writer shared by every instance of a module, making it impossible
+// to attribute output to a specific invocation. As fd_write is called with the invocation context, output
+// is attributed using the capture buffer stored within the context.
+type stdoutExporter struct {
+	// stdout is the writer standard output is written to when not captured.
+	stdout io.Writer
+
+	// stderr is the writer standard error is written to.
+	stderr io.Writer
+}
+
+// ExportFunctions implements wasi_snapshot_preview1.FunctionExporter.
+func (e stdoutExporter) ExportFunctions(b wazeroruntime.HostModuleBuilder) {
+	wasi_snapshot_preview1.NewFunctionExporter().ExportFunctions(b)
+
+	b.NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(e.fdWrite),
+			[]api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32},
+			[]api.ValueType{api.ValueTypeI32}).
+		WithParameterNames("fd", "iovs", "iovs_len", "result.nwritten").
+		Export("fd_write")
+}
+
+// fdWrite implements the WASI fd_write function for standard output and standard error. Guests have no
+// other writable file descriptors.
+func (e stdoutExporter) fdWrite(ctx context.Context, mod api.Module, stack []uint64) {
+	fd := api.DecodeU32(stack[0])
+	iovs := api.DecodeU32(stack[1])
+	iovsLen := api.DecodeU32(stack[2])
+	resultNwritten := api.DecodeU32(stack[3])
+
+	var w io.Writer
+	switch fd {
+	case 1:
+		w = e.stdout
+		if buf, ok := ctx.Value(stdoutCaptureKey{}).(*bytes.Buffer); ok {
+			w = buf
+		}
+	case 2:
+		w = e.stderr
+	default:
+		stack[0] = wasiErrnoBadf
+		return
+	}
+
+	// Gather the data referenced by each iovec
+	mem := mod.Memory()
+	var data []byte
+	for i := uint32(0); i < iovsLen; i++ {
+		offset, ok := mem.ReadUint32Le(iovs + i*8)
+		if !ok {
+			stack[0] = wasiErrnoFault
+			return
+		}
+		l, ok := mem.ReadUint32Le(iovs + i*8 + 4)
+		if !ok {
+			stack[0] = wasiErrnoFault
+			return
+		}
+		b, ok := mem.Read(offset, l)
+		if !ok {
+			stack[0] = wasiErrnoFault
+			return
+		}
+		data = append(data, b...)
+	}
+
+	if w != nil {
+		_, _ = w.Write(data)
+	}
+
+	if !mem.WriteUint32Le(resultNwritten, uint32(len(data))) {
+		stack[0] = wasiErrnoFault
+		return
+	}
+	stack[0] = wasiErrnoSuccess
+}
@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+type ReturnStdoutTestCase struct {
+	Name         string
+	ReturnStdout bool
+	Function     string
+	Payload      string
+	Response     string
+	Stdout       string
+	Err          error
+}
+
+func TestReturnStdout(t *testing.T) {
+	tt := []ReturnStdoutTestCase{
+		{
+			Name:         "Captured",
+			ReturnStdout: true,
+			Function:     "stdout",
+			Payload:      "from stdout",
+			Response:     "from stdout",
+			Stdout:       "",
+		},
+		{
+			Name:         "No output",
+			ReturnStdout: true,
+			Function:     "example",
+			Payload:      "hello",
+			Response:     "",
+			Stdout:       "",
+		},
+		{
+			Name:         "Guest error",
+			ReturnStdout: true,
+			Function:     "ThisBetterFail",
+			Payload:      "hello",
+			Err:          ErrFunctionNotFound,
+		},
+		{
+			Name:         "Disabled",
+			ReturnStdout: false,
+			Function:     "stdout",
+			Payload:      "from stdout",
+			Response:     "Hello World!",
+			Stdout:       "from stdout",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			s, err := New(ServerConfig{
+				Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+			})
+			if err != nil {
+				t.Fatalf("Failed to create WASM Server - %s", err)
+			}
+			defer s.Close()
+
+			var stdout bytes.Buffer
+			err = s.LoadModule(ModuleConfig{
+				Name:         "AModule",
+				PoolSize:     1,
+				Filepath:     "../testdata/hello-go/hello.wasm",
+				Stdout:       &stdout,
+				ReturnStdout: tc.ReturnStdout,
+			})
+			if err != nil {
+				t.Fatalf("Failed to load module - %s", err)
+			}
+
+			m, err := s.Module("AModule")
+			if err != nil {
+				t.Fatalf("Cannot find module - %s", err)
+			}
+
+			rsp, err := m.Run(tc.Function, []byte(tc.Payload))
+			if !errors.Is(err, tc.Err) {
+				t.Fatalf("Unexpected error running module - %s, expected: %s", err, tc.Err)
+			}
+			if string(rsp) != tc.Response {
+				t.Errorf("Unexpected response: %q, expected: %q", rsp, tc.Response)
+			}
+			if stdout.String() != tc.Stdout {
+				t.Errorf("Unexpected stdout: %q, expected: %q", stdout.String(), tc.Stdout)
+			}
+		})
+	}
+}
+
+func TestReturnStdoutConcurrent(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:         "AModule",
+		PoolSize:     5,
+		Filepath:     "../testdata/hello-go/hello.wasm",
+		ReturnStdout: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			payload := fmt.Sprintf("invocation %d", i)
+			rsp, err := m.Run("stdout", []byte(payload))
+			if err != nil {
+				t.Errorf("Unexpected error running module - %s", err)
+				return
+			}
+			if string(rsp) != payload {
+				t.Errorf("Unexpected response: %q, expected: %q", rsp, payload)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestReturnStdoutWithCompression(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:         "AModule",
+		Filepath:     "../testdata/hello-go/hello.wasm",
+		Compression:  CompressionGzip,
+		ReturnStdout: true,
+	})
+	if !errors.Is(err, ErrInvalidModuleConfig) {
+		t.Errorf("Expected invalid module config error, got: %s", err)
+	}
+}
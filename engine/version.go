@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+const (
+	// ABIVersionUnknown is returned by Module.ABIVersion when the guest's waPC protocol version cannot be
+	// detected.
+	ABIVersionUnknown = ""
+
+	// ABIVersion1 is the waPC protocol version of guests exporting the __guest_call function.
+	ABIVersion1 = "wapc-v1"
+)
+
+const (
+	// toolkitModule is the module path of this package, used to find its version within the build info.
+	toolkitModule = "github.com/tarmac-project/wapc-toolkit/engine"
+
+	// wapcModule is the module path of the waPC host library.
+	wapcModule = "github.com/wapc/wapc-go"
+
+	// wazeroModule is the module path of the WebAssembly runtime.
+	wazeroModule = "github.com/tetratelabs/wazero"
+)
+
+// VersionInfo describes the versions of the toolkit and the libraries it is built against. Versions that
+// cannot be determined from the binary's build information are left empty.
+type VersionInfo struct {
+	// Toolkit is the version of the engine package, or "(devel)" when built from a local checkout.
+	Toolkit string
+
+	// WAPC is the version of the waPC host library.
+	WAPC string
+
+	// Wazero is the version of the WebAssembly runtime.
+	Wazero string
+
+	// Go is the version of the Go toolchain the binary was built with.
+	Go string
+}
+
+// Version returns the versions of the toolkit, the waPC host library, and the WebAssembly runtime the
+// binary was built against, which helps diagnose host and guest protocol mismatches.
+func Version() VersionInfo {
+	v := VersionInfo{Go: runtime.Version()}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+
+	modules := append([]*debug.Module{&info.Main}, info.Deps...)
+	for _, mod := range modules {
+		version := mod.Version
+		if mod.Replace != nil {
+			version = mod.Replace.Version
+		}
+
+		switch mod.Path {
+		case toolkitModule:
+			v.Toolkit = version
+		case wapcModule:
+			v.WAPC = version
+		case wazeroModule:
+			v.Wazero = version
+		}
+	}
+
+	return v
+}
+
+// ABIVersion returns the waPC protocol version targeted by the guest, detected from the module's
+// WebAssembly exports, or ABIVersionUnknown if the guest does not export the waPC entry point.
+func (m *Module) ABIVersion() string {
+	if _, ok := m.exports["__guest_call"]; ok {
+		return ABIVersion1
+	}
+	return ABIVersionUnknown
+}
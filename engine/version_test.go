@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"context"
+	"runtime"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	v := Version()
+
+	if v.Go != runtime.Version() {
+		t.Errorf("Unexpected Go version: %s, expected: %s", v.Go, runtime.Version())
+	}
+
+	if v.WAPC != "v0.7.0" {
+		t.Errorf("Unexpected waPC version: %s", v.WAPC)
+	}
+
+	if v.Wazero != "v1.7.3" {
+		t.Errorf("Unexpected wazero version: %s", v.Wazero)
+	}
+}
+
+func TestModuleABIVersion(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	if v := m.ABIVersion(); v != ABIVersion1 {
+		t.Errorf("Unexpected ABI version: %q, expected: %q", v, ABIVersion1)
+	}
+}
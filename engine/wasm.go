@@ -55,11 +55,19 @@ package engine
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	wazeroruntime "github.com/tetratelabs/wazero"
 	wapc "github.com/wapc/wapc-go"
 	"github.com/wapc/wapc-go/engines/wazero"
 )
@@ -68,10 +76,39 @@ var (
 	// ErrModuleNotFound is returned when a module is not found.
 	ErrModuleNotFound = errors.New("module not found")
 
-	// ErrCallbackNil is returned when the callback function is nil.
+	// ErrCallbackNil was returned when the callback function is nil.
+	//
+	// Deprecated: A nil callback is now allowed, and host calls return ErrNoCallbackRegistered.
 	ErrCallbackNil = errors.New("callback cannot be nil")
+
+	// ErrNoCallbackRegistered is returned to guests performing a host call when the Server was created
+	// without a Callback or Router.
+	ErrNoCallbackRegistered = errors.New("no callback registered")
+
+	// ErrInvalidServerConfig is returned when a ServerConfig is invalid.
+	ErrInvalidServerConfig = errors.New("invalid server config")
+
+	// ErrModuleLimitReached is returned when loading a module would exceed the Server's MaxModules.
+	ErrModuleLimitReached = errors.New("module limit reached")
+
+	// ErrModuleTooLarge is returned when a module file exceeds the Server's MaxModuleBytes.
+	ErrModuleTooLarge = errors.New("module too large")
+
+	// ErrChecksumMismatch is returned when the WebAssembly module binary does not match the ModuleConfig
+	// SHA256 checksum.
+	ErrChecksumMismatch = errors.New("module checksum mismatch")
+
+	// ErrCompileTimeout is returned when compiling a module exceeds the module's CompileTimeout.
+	ErrCompileTimeout = errors.New("module compilation timed out")
 )
 
+// CallbackRouter routes waPC guest HostCalls to host functionality, such as the Router of the callbacks
+// package. It is defined here so that the engine does not depend on the callbacks package.
+type CallbackRouter interface {
+	// Callback is called with the namespace, capability, operation, and input of each HostCall.
+	Callback(ctx context.Context, namespace, capability, operation string, input []byte) ([]byte, error)
+}
+
 // ServerConfig is used to configure the initial Server.
 type ServerConfig struct {
 
@@ -80,8 +117,87 @@ type ServerConfig struct {
 	// allows a host to expose functionality to a guest via the waPC protocol.
 	//
 	// The callback function is registered via the waPC runtime engine and is called with parameters
-	// specified by the guest.
+	// specified by the guest. The provided context carries the Invocation that triggered the callback, which
+	// can be read with InvocationFromContext. If neither Callback nor Router is provided, host calls return
+	// ErrNoCallbackRegistered, which suits hosts that expose no capabilities to guests.
 	Callback func(context.Context, string, string, string, []byte) ([]byte, error)
+
+	// Router, as an alternative to Callback, routes waPC guest HostCalls via its Callback method. A
+	// *callbacks.Router from the callbacks package satisfies CallbackRouter, allowing it to be provided
+	// directly. Callback and Router cannot both be provided.
+	Router CallbackRouter
+
+	// MaxModules is the maximum number of modules the Server will load. Once the limit is reached,
+	// LoadModule will return ErrModuleLimitReached for any new module. Reloading an already loaded
+	// module does not count against the limit.
+	//
+	// This is a safety guard for hosts that load user-provided modules, ensuring a single tenant
+	// cannot exhaust host resources by loading thousands of modules.
+	//
+	// If MaxModules is zero, the number of modules is unlimited.
+	MaxModules int
+
+	// MaxModuleBytes is the maximum size, in bytes, of a module file the Server will load. LoadModule
+	// checks the file size before reading and compiling the module, returning ErrModuleTooLarge for
+	// larger files. This prevents pathologically large guests from consuming compile time and memory.
+	//
+	// If MaxModuleBytes is zero, the module size is unlimited.
+	MaxModuleBytes int
+
+	// HostFunctions is a registry of typed host functions, keyed by function name, that guests can
+	// import directly from the HostModule namespace. This complements the waPC host call, which routes
+	// serialized payloads via the Callback function.
+	HostFunctions map[string]HostFunction
+
+	// HostModule is the module name guests import HostFunctions from. The names "wapc", "env", and
+	// "wasi_snapshot_preview1" are reserved.
+	//
+	// If HostModule is not provided, DefaultHostModule will be used.
+	HostModule string
+
+	// Events is an optional channel the Server sends lifecycle events to, such as modules being loaded and
+	// unloaded, invocation errors, and pool timeouts. This enables event-driven supervisors to react to
+	// engine state changes without polling.
+	//
+	// Events are sent without blocking; if the channel is full, the event is dropped and counted, see
+	// EventsDropped. The channel must not be closed while the Server is in use.
+	Events chan<- ServerEvent
+
+	// MetricsFunc is an optional user-defined function called after each Run and RunWithContext call on any
+	// module, with ModuleMetrics describing the call. This allows per-module, per-function metrics to be
+	// exported, such as to Prometheus or OpenTelemetry, without wrapping every call site.
+	//
+	// MetricsFunc is called synchronously before the call returns, so it should not block.
+	MetricsFunc func(ModuleMetrics)
+
+	// Engine is the waPC engine used to compile and instantiate modules, allowing a backend other than
+	// wazero, such as one of the other engines provided by wapc-go, to be selected. If Engine is not
+	// provided, a wazero engine is used.
+	//
+	// Several features extend the wazero runtime and so require the default engine: HostFunctions and
+	// CompilationCache cannot be used with Engine, and modules cannot use ReturnStdout, Env, or Args. Whether
+	// RunWithContext aborts an invocation when its context is done depends on the engine.
+	Engine wapc.Engine
+
+	// CompilationCache caches compiled modules, so that loading a module that was compiled before, including
+	// by another Server or process, reuses the compiled artifacts rather than compiling it again. This
+	// reduces the cold-start time of hosts loading many or large modules.
+	//
+	// To persist compiled modules across process restarts, create the cache from a directory with
+	// wazero.NewCompilationCacheWithDir, for example:
+	//
+	//	cache, err := wazero.NewCompilationCacheWithDir("/var/cache/wasm")
+	//
+	// The cache may be shared between Servers. The Server does not close the cache; close it once every
+	// Server using it is closed. If CompilationCache is not provided, modules are compiled on every load.
+	CompilationCache wazeroruntime.CompilationCache
+
+	// OnClose is an optional function called by Close after all modules have been torn down. This gives
+	// hosts a single place to run shutdown logic tied to the engine lifecycle, such as flushing metrics or
+	// closing external connections used by modules.
+	//
+	// OnClose is called once, even if Close is called multiple times.
+	OnClose func()
 }
 
 // Server provides the ability to load and execute waPC guest modules.
@@ -93,33 +209,235 @@ type Server struct {
 
 	// modules is a map for storing and fetching modules that have already been loaded.
 	modules map[string]*Module
+
+	// groups is a map of module groups used to split invocations across modules by weight.
+	groups map[string]*group
+
+	// maxModules is the maximum number of modules the Server will load, zero means unlimited.
+	maxModules int
+
+	// maxModuleBytes is the maximum size of a module file the Server will load, zero means unlimited.
+	maxModuleBytes int64
+
+	// loading is a map of in-progress module loads, used to coordinate concurrent EnsureAndInvoke calls.
+	loading map[string]*loadCall
+
+	// hostFunctions is the registry of typed host functions exported to guests.
+	hostFunctions map[string]HostFunction
+
+	// hostModule is the module name guests import hostFunctions from.
+	hostModule string
+
+	// events is the user-provided channel lifecycle events are sent to.
+	events chan<- ServerEvent
+
+	// eventsDropped counts the number of events dropped because the events channel was full.
+	eventsDropped atomic.Uint64
+
+	// onClose is the user-provided function called once modules are torn down by Close.
+	onClose func()
+
+	// metricsFunc is the user-provided function called with the metrics of each module call.
+	metricsFunc func(ModuleMetrics)
+
+	// engine is the user-provided waPC engine, or nil to use wazero.
+	engine wapc.Engine
+
+	// compilationCache is the user-provided cache of compiled modules, or nil.
+	compilationCache wazeroruntime.CompilationCache
+
+	// closeOnce ensures the server is torn down, and onClose is called, once.
+	closeOnce sync.Once
+}
+
+// loadCall is an in-progress module load shared by concurrent callers.
+type loadCall struct {
+	// wg is used by waiting callers to block until the load completes.
+	wg sync.WaitGroup
+
+	// err is the result of the load, it is safe to read once wg is done.
+	err error
+}
+
+// Validate validates the server configuration. It returns an error if the configuration values are
+// invalid or missing any required fields.
+func (cfg ServerConfig) Validate() error {
+	// Verify Callback
+	if cfg.Callback != nil && cfg.Router != nil {
+		return fmt.Errorf("%w: Callback and Router cannot both be provided", ErrInvalidServerConfig)
+	}
+
+	// Verify MaxModules
+	if cfg.MaxModules < 0 {
+		return fmt.Errorf("%w: MaxModules cannot be negative", ErrInvalidServerConfig)
+	}
+
+	// Verify MaxModuleBytes
+	if cfg.MaxModuleBytes < 0 {
+		return fmt.Errorf("%w: MaxModuleBytes cannot be negative", ErrInvalidServerConfig)
+	}
+
+	// Verify Engine
+	if cfg.Engine != nil && (len(cfg.HostFunctions) > 0 || cfg.CompilationCache != nil) {
+		return fmt.Errorf(
+			"%w: HostFunctions and CompilationCache cannot be used with Engine",
+			ErrInvalidServerConfig,
+		)
+	}
+
+	// Verify HostFunctions
+	hostModule := DefaultHostModule
+	if cfg.HostModule != "" {
+		hostModule = cfg.HostModule
+	}
+
+	return validateHostFunctions(hostModule, cfg.HostFunctions)
+}
+
+// noCallback is the Server callback used when no Callback or Router is provided.
+func noCallback(context.Context, string, string, string, []byte) ([]byte, error) {
+	return nil, ErrNoCallbackRegistered
 }
 
 // New will create a new waPC Engine Server. The Server is a simplified interface for applications to
 // load waPC guests.
 //
+// The provided configuration is validated with ServerConfig.Validate; if it is invalid, no Server is
+// returned and the error describes the problem.
+//
 // Once the Server is created, users can load waPC guest modules, allowing them to execute exported functions.
 func New(cfg ServerConfig) (*Server, error) {
-	s := &Server{}
-	s.modules = make(map[string]*Module)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		modules:          make(map[string]*Module),
+		groups:           make(map[string]*group),
+		loading:          make(map[string]*loadCall),
+		callback:         cfg.Callback,
+		maxModules:       cfg.MaxModules,
+		maxModuleBytes:   int64(cfg.MaxModuleBytes),
+		hostModule:       DefaultHostModule,
+		hostFunctions:    make(map[string]HostFunction, len(cfg.HostFunctions)),
+		events:           cfg.Events,
+		onClose:          cfg.OnClose,
+		engine:           cfg.Engine,
+		metricsFunc:      cfg.MetricsFunc,
+		compilationCache: cfg.CompilationCache,
+	}
+
+	if cfg.HostModule != "" {
+		s.hostModule = cfg.HostModule
+	}
+	if cfg.Router != nil {
+		s.callback = cfg.Router.Callback
+	}
+	if s.callback == nil {
+		s.callback = noCallback
+	}
 
-	if cfg.Callback == nil {
-		return s, ErrCallbackNil
+	for name, fn := range cfg.HostFunctions {
+		s.hostFunctions[name] = fn
 	}
 
-	s.callback = cfg.Callback
 	return s, nil
 }
 
-// Close will shut down the server and clean up any loaded modules, including the module pools.
+// Close will shut down the server and clean up any loaded modules, including the module pools. Once modules
+// are torn down, any OnClose function defined is called.
+//
+// Close is idempotent; calls after the first do nothing.
 func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		s.closeModules()
+
+		if s.onClose != nil {
+			s.onClose()
+		}
+	})
+}
+
+// closeModules cleans up all loaded modules, including the module pools.
+func (s *Server) closeModules() {
 	s.RLock()
 	defer s.RUnlock()
 	for _, m := range s.modules {
-		defer m.cancel()
-		defer m.module.Close(m.ctx)
-		defer m.pool.Close(m.ctx)
+		m.close()
+		s.emit(ServerEvent{Type: EventModuleUnloaded, Module: m.Name})
+	}
+}
+
+// UnloadModule closes the named module and its pools and removes it from the Server, allowing long-running
+// hosts to remove modules at runtime. The module is also removed from any groups it is a member of.
+//
+// Invocations in progress are aborted, and later calls via the Module return errors. If the module is not
+// found, ErrModuleNotFound will be returned.
+func (s *Server) UnloadModule(name string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	m, ok := s.modules[name]
+	if !ok {
+		return ErrModuleNotFound
+	}
+	delete(s.modules, name)
+
+	for group := range s.groups {
+		s.removeFromGroup(group, name)
+	}
+
+	m.close()
+	s.emit(ServerEvent{Type: EventModuleUnloaded, Module: m.Name})
+
+	return nil
+}
+
+// ReloadModule replaces the named module with the WebAssembly Module specified by the provided ModuleConfig,
+// hot-swapping the implementation without changing the module name. The ModuleConfig Name is replaced by name.
+// If the named module is not loaded, ErrModuleNotFound is returned.
+//
+// The new module and its pool are created before the swap, so a module that fails to load leaves the old
+// module in place. Once swapped, calls fetching the module from the Server use the new module, while
+// invocations already running on, or waiting for an instance of, the old module are allowed to complete.
+// ReloadModule returns after the old module has drained and been closed. Draining waits at most
+// DefaultDrainTimeout, so that a Session or Buffer that is never closed cannot block ReloadModule; the old
+// module is closed regardless once the timeout elapses. Callers holding a reference to the old Module, rather
+// than fetching it from the Server for each call, will find it closed.
+func (s *Server) ReloadModule(name string, cfg ModuleConfig) error {
+	cfg.Name = name
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	s.RLock()
+	_, ok := s.modules[name]
+	s.RUnlock()
+	if !ok {
+		return ErrModuleNotFound
+	}
+
+	m, err := s.newModule(cfg)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	old, ok := s.modules[name]
+	if !ok {
+		// The module was unloaded concurrently
+		s.Unlock()
+		m.close()
+		return ErrModuleNotFound
 	}
+	s.modules[name] = m
+	s.Unlock()
+
+	old.drain(DefaultDrainTimeout)
+	old.close()
+	s.emit(ServerEvent{Type: EventModuleReloaded, Module: name})
+
+	return nil
 }
 
 // LoadModule will fetch the WebAssembly Module specified by the user-provided ModuleConfig and initialize it via
@@ -127,54 +445,431 @@ func (s *Server) Close() {
 //
 // Once a Module is loaded, users can fetch the Module from the Server and call the exported functions.
 func (s *Server) LoadModule(cfg ModuleConfig) error {
-	if cfg.Name == "" || cfg.Filepath == "" {
-		return fmt.Errorf("%w: key and file cannot be empty", ErrInvalidModuleConfig)
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	// Check module limit before doing any expensive work
+	s.RLock()
+	limited := s.limitReached(cfg.Name)
+	s.RUnlock()
+	if limited {
+		return ErrModuleLimitReached
+	}
+
+	m, err := s.newModule(cfg)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	// Re-check module limit as other modules may have loaded concurrently
+	if s.limitReached(m.Name) {
+		m.close()
+		return ErrModuleLimitReached
+	}
+
+	s.modules[m.Name] = m
+	s.emit(ServerEvent{Type: EventModuleLoaded, Module: m.Name})
+
+	return nil
+}
+
+// newModule compiles the WebAssembly Module specified by the module configuration and creates its instance
+// pools. The returned module is not added to the Server.
+func (s *Server) newModule(cfg ModuleConfig) (_ *Module, err error) {
+	if s.engine != nil && (cfg.ReturnStdout || len(cfg.Env) > 0 || len(cfg.Args) > 0) {
+		return nil, fmt.Errorf("%w: ReturnStdout, Env, and Args require the default engine", ErrInvalidModuleConfig)
 	}
 
 	// Create Module
 	m := &Module{
-		Name: cfg.Name,
+		Name:           cfg.Name,
+		config:         cfg,
+		runStatsFunc:   cfg.RunStatsFunc,
+		metricsFunc:    s.metricsFunc,
+		functionFilter: cfg.FunctionFilter,
+		emit:           s.emit,
 	}
 
 	// Create context
 	m.ctx, m.cancel = context.WithCancel(context.Background())
 
+	// Release anything already created if the module fails to load
+	defer func() {
+		if err == nil {
+			return
+		}
+		if m.reentrantPool != nil {
+			m.reentrantPool.Close(m.ctx)
+		}
+		if m.pool != nil {
+			m.pool.Close(m.ctx)
+		}
+		if m.module != nil {
+			m.module.Close(m.ctx)
+		}
+		m.cancel()
+	}()
+
 	// Set Pool Size
-	m.poolSize = uint64(DefaultPoolSize)
+	poolSize := uint64(DefaultPoolSize)
 	if cfg.PoolSize > 0 {
-		m.poolSize = uint64(cfg.PoolSize)
+		poolSize = uint64(cfg.PoolSize)
+	}
+	if cfg.Singleton {
+		poolSize = 1
+		m.turn = make(chan struct{}, 1)
+	}
+	m.poolSize.Store(poolSize)
+	if cfg.MaxConcurrentRuns > 0 {
+		m.runs = make(chan struct{}, cfg.MaxConcurrentRuns)
 	}
 
 	// Read the WASM module file
-	guest, err := os.ReadFile(cfg.Filepath)
+	guest, err := s.moduleSource(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	source := cfg.Filepath
+	if source == "" {
+		source = "source"
+	}
+
+	// Parse the functions exported by the guest
+	exports, err := parseExports(guest)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse exports of wasm %s - %w", source, err)
+	}
+	m.exports = make(map[string]struct{}, len(exports))
+	for _, name := range exports {
+		m.exports[name] = struct{}{}
+	}
+
+	// Negotiate payload compression with the guest
+	m.codec, err = negotiateCodec(cfg.Compression, m.exports)
 	if err != nil {
-		return fmt.Errorf("unable to read wasm module file - %w", err)
+		return nil, fmt.Errorf("unable to negotiate compression for wasm %s - %w", source, err)
+	}
+	m.maxDecompressedSize = cfg.MaxDecompressedSize
+	if m.maxDecompressedSize == 0 {
+		m.maxDecompressedSize = DefaultMaxDecompressedSize
 	}
 
 	// Initiate waPC Engine
-	engine := wazero.Engine()
+	mc := moduleConfig(cfg)
+	engine := s.engine
+	if engine == nil {
+		engine = wazero.EngineWithRuntime(s.newRuntime)
+	}
+	if cfg.ReturnStdout {
+		engine = wazero.EngineWithRuntime(s.newStdoutRuntime(mc.Stdout, mc.Stderr))
+	}
 
 	// Create a new Module from file contents
-	m.module, err = engine.New(m.ctx, s.callback, guest, &wapc.ModuleConfig{
-		Logger: wapc.PrintlnLogger,
-		Stdout: os.Stdout,
-		Stderr: os.Stderr,
-	})
+	callback := s.callback
+	if cfg.Callback != nil {
+		callback = cfg.Callback
+	}
+	m.module, err = s.compile(m.ctx, engine, callback, guest, mc, cfg.CompileTimeout)
 	if err != nil {
-		return fmt.Errorf("unable to load module with wasm file %s - %w", cfg.Filepath, err)
+		return nil, fmt.Errorf("unable to load module with wasm %s - %w", source, err)
 	}
+	configureWASI(m.module, cfg)
 
 	// Create pool for module
-	m.pool, err = wapc.NewPool(m.ctx, m.module, m.poolSize)
+	m.pool, err = wapc.NewPool(m.ctx, m.module, poolSize)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to create module pool for wasm %s - %w - %w",
+			source, ErrInstantiationFailed, err,
+		)
+	}
+
+	// Create reentrancy pool for module
+	if cfg.ReentrantPoolSize > 0 {
+		m.reentrantPool, err = wapc.NewPool(m.ctx, m.module, uint64(cfg.ReentrantPoolSize))
+		if err != nil {
+			return nil, fmt.Errorf(
+				"unable to create reentrancy pool for wasm %s - %w - %w",
+				source, ErrInstantiationFailed, err,
+			)
+		}
+	}
+
+	return m, nil
+}
+
+// LoadModuleFromReader reads the WebAssembly Module from the provided reader and initializes it via the Server
+// under the provided name, in the same way as LoadModule. This suits modules embedded with go:embed, via
+// embed.FS.Open, or fetched over the network, such as via an http.Response Body, without writing them to the
+// file system.
+//
+// The module is read fully into memory, enforcing the Server's MaxModuleBytes. The provided ModuleConfig
+// must not set Filepath or Source; its Name is replaced by name.
+func (s *Server) LoadModuleFromReader(name string, r io.Reader, cfg ModuleConfig) error {
+	if cfg.Filepath != "" || cfg.Source != nil {
+		return fmt.Errorf("%w: Filepath and Source cannot be provided with a reader", ErrInvalidModuleConfig)
+	}
+
+	var guest []byte
+	var err error
+	if s.maxModuleBytes <= 0 {
+		guest, err = io.ReadAll(r)
+	} else {
+		guest, err = io.ReadAll(io.LimitReader(r, s.maxModuleBytes+1))
+	}
 	if err != nil {
-		return fmt.Errorf("unable to create module pool for wasm file %s - %w", cfg.Filepath, err)
+		return fmt.Errorf("unable to read wasm module - %w", err)
+	}
+	if s.maxModuleBytes > 0 && int64(len(guest)) > s.maxModuleBytes {
+		return fmt.Errorf("%w: reader exceeds %d bytes", ErrModuleTooLarge, s.maxModuleBytes)
+	}
+
+	cfg.Name = name
+	cfg.Source = guest
+	return s.LoadModule(cfg)
+}
+
+// compile creates a waPC module from the guest, compiling it, with host calls handled by the provided
+// callback. If the timeout is greater than zero and compilation exceeds it, ErrCompileTimeout is returned;
+// the compiled module is closed once compilation completes in the background.
+func (s *Server) compile(
+	ctx context.Context,
+	engine wapc.Engine,
+	callback wapc.HostCallHandler,
+	guest []byte,
+	mc *wapc.ModuleConfig,
+	timeout time.Duration,
+) (wapc.Module, error) {
+	if timeout == 0 {
+		return engine.New(ctx, callback, guest, mc)
+	}
+
+	type compiled struct {
+		module wapc.Module
+		err    error
+	}
+	done := make(chan compiled, 1)
+	go func() {
+		m, err := engine.New(ctx, callback, guest, mc)
+		done <- compiled{module: m, err: err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case c := <-done:
+		return c.module, c.err
+	case <-timer.C:
+		// Clean up the module once compilation completes
+		go func() {
+			if c := <-done; c.err == nil {
+				c.module.Close(ctx)
+			}
+		}()
+		return nil, fmt.Errorf("%w: exceeded %s", ErrCompileTimeout, timeout)
+	}
+}
+
+// moduleSource returns the WASM module binary provided by the ModuleConfig, either as Source or read from
+// Filepath, enforcing the Server's MaxModuleBytes.
+func (s *Server) moduleSource(cfg ModuleConfig) ([]byte, error) {
+	guest := cfg.Source
+	if guest == nil {
+		var err error
+		guest, err = s.readModule(cfg.Filepath)
+		if err != nil {
+			return nil, err
+		}
+	} else if s.maxModuleBytes > 0 && int64(len(guest)) > s.maxModuleBytes {
+		return nil, fmt.Errorf("%w: source exceeds %d bytes", ErrModuleTooLarge, s.maxModuleBytes)
 	}
 
+	// Verify the module binary matches the expected checksum
+	if cfg.SHA256 != "" {
+		sum := sha256.Sum256(guest)
+		if actual := hex.EncodeToString(sum[:]); !strings.EqualFold(actual, cfg.SHA256) {
+			return nil, fmt.Errorf("%w: expected sha256 %s, got %s", ErrChecksumMismatch, cfg.SHA256, actual)
+		}
+	}
+
+	return guest, nil
+}
+
+// readModule reads the WASM module file, enforcing the Server's MaxModuleBytes.
+func (s *Server) readModule(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read wasm module file - %w", err)
+	}
+	defer f.Close()
+
+	if s.maxModuleBytes <= 0 {
+		guest, err := io.ReadAll(f)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read wasm module file - %w", err)
+		}
+		return guest, nil
+	}
+
+	// Check the file size before reading
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read wasm module file - %w", err)
+	}
+	if info.Size() > s.maxModuleBytes {
+		return nil, fmt.Errorf("%w: %s exceeds %d bytes", ErrModuleTooLarge, path, s.maxModuleBytes)
+	}
+
+	// Limit the read in case the file grows while reading
+	guest, err := io.ReadAll(io.LimitReader(f, s.maxModuleBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read wasm module file - %w", err)
+	}
+	if int64(len(guest)) > s.maxModuleBytes {
+		return nil, fmt.Errorf("%w: %s exceeds %d bytes", ErrModuleTooLarge, path, s.maxModuleBytes)
+	}
+
+	return guest, nil
+}
+
+// limitReached returns true if loading the named module would exceed the Server's module limit. The caller
+// must hold the Server lock.
+func (s *Server) limitReached(name string) bool {
+	if s.maxModules <= 0 {
+		return false
+	}
+	if _, ok := s.modules[name]; ok {
+		return false
+	}
+	return len(s.modules) >= s.maxModules
+}
+
+// EnsureAndInvoke will load the module specified by the user-provided ModuleConfig if it is not already
+// loaded, then call the user-provided function with the user-provided payload.
+//
+// Concurrent calls for the same module name are coordinated so that the module is loaded exactly once;
+// callers arriving while a load is in progress wait for it to complete and share its result. If the module
+// is already loaded, the ModuleConfig is ignored and the loaded module is used.
+//
+// This smooths the cold-start path for on-demand modules, such as serverless-style invocation.
+func (s *Server) EnsureAndInvoke(
+	ctx context.Context,
+	cfg ModuleConfig,
+	function string,
+	payload []byte,
+) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.ensureModule(cfg); err != nil {
+		return nil, err
+	}
+
+	m, err := s.Module(cfg.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.RunWithContext(ctx, function, payload)
+}
+
+// ensureModule loads the module if it is not already loaded, sharing a single load between concurrent callers.
+func (s *Server) ensureModule(cfg ModuleConfig) error {
 	s.Lock()
-	defer s.Unlock()
-	s.modules[m.Name] = m
+	if _, ok := s.modules[cfg.Name]; ok {
+		s.Unlock()
+		return nil
+	}
 
-	return nil
+	// Wait for an in-progress load
+	if c, ok := s.loading[cfg.Name]; ok {
+		s.Unlock()
+		c.wg.Wait()
+		return c.err
+	}
+
+	c := &loadCall{}
+	c.wg.Add(1)
+	s.loading[cfg.Name] = c
+	s.Unlock()
+
+	c.err = s.LoadModule(cfg)
+
+	s.Lock()
+	delete(s.loading, cfg.Name)
+	s.Unlock()
+	c.wg.Done()
+
+	return c.err
+}
+
+// moduleConfig returns the waPC module configuration for the user-provided ModuleConfig.
+func moduleConfig(cfg ModuleConfig) *wapc.ModuleConfig {
+	mc := &wapc.ModuleConfig{
+		Logger: wapc.PrintlnLogger,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+
+	if cfg.Stdout != nil {
+		mc.Stdout = cfg.Stdout
+	}
+	if cfg.Stderr != nil {
+		mc.Stderr = cfg.Stderr
+	}
+	if cfg.Logger != nil {
+		mc.Logger = cfg.Logger
+	}
+
+	// Prefix guest output with the module name
+	if cfg.LogPrefix {
+		prefix := "[" + cfg.Name + "] "
+		mc.Stdout = newPrefixWriter(mc.Stdout, prefix)
+		mc.Stderr = newPrefixWriter(mc.Stderr, prefix)
+		logger := mc.Logger
+		mc.Logger = func(msg string) {
+			logger(prefix + msg)
+		}
+	}
+
+	return mc
+}
+
+// configureWASI provides the environment variables and command-line arguments of the module configuration to
+// each instance of the module. The waPC module configuration does not support these, so the wazero module
+// configuration is extended directly. Environment variables are set in sorted order.
+func configureWASI(module wapc.Module, cfg ModuleConfig) {
+	if len(cfg.Env) == 0 && len(cfg.Args) == 0 {
+		return
+	}
+
+	m, ok := module.(*wazero.Module)
+	if !ok {
+		return
+	}
+
+	keys := make([]string, 0, len(cfg.Env))
+	for k := range cfg.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	m.WithConfig(func(c wazeroruntime.ModuleConfig) wazeroruntime.ModuleConfig {
+		for _, k := range keys {
+			c = c.WithEnv(k, cfg.Env[k])
+		}
+		if len(cfg.Args) > 0 {
+			c = c.WithArgs(cfg.Args...)
+		}
+		return c
+	})
 }
 
 // Module will return the specified Module.
@@ -188,3 +883,17 @@ func (s *Server) Module(key string) (*Module, error) {
 	}
 	return &Module{}, ErrModuleNotFound
 }
+
+// Modules returns the sorted names of the modules loaded by the Server. If no modules are loaded, an empty
+// slice is returned. For details of each module, such as its pool size and configuration, see Snapshot.
+func (s *Server) Modules() []string {
+	s.RLock()
+	defer s.RUnlock()
+
+	names := make([]string, 0, len(s.modules))
+	for name := range s.modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
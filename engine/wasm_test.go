@@ -1,18 +1,125 @@
 package engine
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/wapc/wapc-go"
+	"github.com/wapc/wapc-go/engines/wazero"
 )
 
 func TestWASMServerCreation(t *testing.T) {
-	_, err := New(ServerConfig{})
-	if err == nil {
-		t.Errorf("New Server creation should have errored with no Callback")
+	t.Run("No Callback", func(t *testing.T) {
+		s, err := New(ServerConfig{})
+		if err != nil {
+			t.Fatalf("Unexpected error creating Server with no Callback - %s", err)
+		}
+		defer s.Close()
+
+		r, err := s.callback(context.Background(), "namespace", "module", "function", []byte(""))
+		if !errors.Is(err, ErrNoCallbackRegistered) || r != nil {
+			t.Errorf("Expected no callback registered error, got: %v", err)
+		}
+
+		err = s.LoadModule(ModuleConfig{Name: "AModule", PoolSize: 1, Filepath: "../testdata/hello-go/hello.wasm"})
+		if err != nil {
+			t.Fatalf("Failed to load module - %s", err)
+		}
+		m, err := s.Module("AModule")
+		if err != nil {
+			t.Fatalf("Cannot find module - %s", err)
+		}
+		if _, err := m.Run("example", []byte("hello")); err == nil {
+			t.Errorf("Expected error when guest performs a host call without a Callback")
+		}
+	})
+
+	t.Run("Negative MaxModules", func(t *testing.T) {
+		s, err := New(ServerConfig{
+			Callback:   func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+			MaxModules: -1,
+		})
+		if !errors.Is(err, ErrInvalidServerConfig) {
+			t.Errorf("Expected invalid server config error, got: %s", err)
+		}
+		if s != nil {
+			t.Errorf("Expected no Server to be returned with an invalid config")
+		}
+	})
+	t.Run("Negative MaxModuleBytes", func(t *testing.T) {
+		_, err := New(ServerConfig{
+			Callback:       func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+			MaxModuleBytes: -1,
+		})
+		if !errors.Is(err, ErrInvalidServerConfig) {
+			t.Errorf("Expected invalid server config error, got: %s", err)
+		}
+	})
+	t.Run("Callback and Router", func(t *testing.T) {
+		_, err := New(ServerConfig{
+			Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+			Router:   &testRouter{},
+		})
+		if !errors.Is(err, ErrInvalidServerConfig) {
+			t.Errorf("Expected invalid server config error, got: %s", err)
+		}
+	})
+}
+
+// testRouter is a CallbackRouter recording the HostCalls it routes.
+type testRouter struct {
+	sync.Mutex
+	calls []string
+}
+
+func (r *testRouter) Callback(_ context.Context, namespace, capability, operation string, _ []byte) ([]byte, error) {
+	r.Lock()
+	defer r.Unlock()
+	r.calls = append(r.calls, namespace+":"+capability+":"+operation)
+	return []byte(""), nil
+}
+
+func TestWASMRouter(t *testing.T) {
+	router := &testRouter{}
+	s, err := New(ServerConfig{Router: router})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{Name: "AModule", PoolSize: 1, Filepath: "../testdata/hello-go/hello.wasm"})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	if _, err := m.Run("example", []byte("hello")); err != nil {
+		t.Fatalf("Unexpected error running module - %s", err)
+	}
+
+	router.Lock()
+	defer router.Unlock()
+	if len(router.calls) != 1 || router.calls[0] != "namespace:module:function" {
+		t.Errorf("Unexpected routed calls: %v", router.calls)
 	}
 }
 
+var ErrTestCallback = errors.New("test callback error")
+
 type ModuleCase struct {
 	ModuleConf ModuleConfig
 	Pass       bool
@@ -35,7 +142,7 @@ func TestWASMModuleCreation(t *testing.T) {
 		Name: "Happy Path",
 		Pass: true,
 		ModuleConf: ModuleConfig{
-			Name:     "A Module",
+			Name:     "AModule",
 			PoolSize: 99,
 			Filepath: "../testdata/hello-go/hello.wasm",
 		},
@@ -51,12 +158,25 @@ func TestWASMModuleCreation(t *testing.T) {
 		},
 	})
 
+	// Invalid Names
+	for _, name := range []string{"A Module", "../AModule", "-AModule", "AModule\n", "AModule/v1"} {
+		mc = append(mc, ModuleCase{
+			Name: "Invalid Name " + strconv.Quote(name),
+			Pass: false,
+			ModuleConf: ModuleConfig{
+				Name:     name,
+				PoolSize: 99,
+				Filepath: "../testdata/hello-go/hello.wasm",
+			},
+		})
+	}
+
 	// No Pool Size
 	mc = append(mc, ModuleCase{
 		Name: "No Pool Size",
 		Pass: true,
 		ModuleConf: ModuleConfig{
-			Name:     "A Module",
+			Name:     "AModule",
 			Filepath: "../testdata/hello-go/hello.wasm",
 		},
 	})
@@ -66,18 +186,29 @@ func TestWASMModuleCreation(t *testing.T) {
 		Name: "Negative Pool Size",
 		Pass: true,
 		ModuleConf: ModuleConfig{
-			Name:     "A Module",
+			Name:     "AModule",
 			PoolSize: -1,
 			Filepath: "../testdata/hello-go/hello.wasm",
 		},
 	})
 
+	// Negative MaxDecompressedSize
+	mc = append(mc, ModuleCase{
+		Name: "Negative MaxDecompressedSize",
+		Pass: false,
+		ModuleConf: ModuleConfig{
+			Name:                "AModule",
+			Filepath:            "../testdata/hello-go/hello.wasm",
+			MaxDecompressedSize: -1,
+		},
+	})
+
 	// No File
 	mc = append(mc, ModuleCase{
 		Name: "No File",
 		Pass: false,
 		ModuleConf: ModuleConfig{
-			Name:     "A Module",
+			Name:     "AModule",
 			PoolSize: 99,
 		},
 	})
@@ -87,7 +218,7 @@ func TestWASMModuleCreation(t *testing.T) {
 		Name: "Bad File Location",
 		Pass: false,
 		ModuleConf: ModuleConfig{
-			Name:     "A Module",
+			Name:     "AModule",
 			PoolSize: 99,
 			Filepath: "/doesntexist/testdata/something.wasm",
 		},
@@ -97,6 +228,9 @@ func TestWASMModuleCreation(t *testing.T) {
 	for _, m := range mc {
 		t.Run("Module Creation Test Case - "+m.Name, func(t *testing.T) {
 			err := s.LoadModule(m.ModuleConf)
+			if strings.HasPrefix(m.Name, "Invalid Name") && !errors.Is(err, ErrInvalidModuleName) {
+				t.Errorf("Expected invalid module name error, got: %s", err)
+			}
 			if !m.Pass && err == nil {
 				t.Errorf("Case should have failed, but it passed")
 			}
@@ -156,3 +290,1146 @@ func TestWASMExecution(t *testing.T) {
 		return
 	}
 }
+
+func TestWASMModuleLimit(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback:   func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+		MaxModules: 2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	for _, name := range []string{"first", "second"} {
+		err = s.LoadModule(ModuleConfig{
+			Name:     name,
+			PoolSize: 1,
+			Filepath: "../testdata/hello-go/hello.wasm",
+		})
+		if err != nil {
+			t.Fatalf("Failed to load module - %s", err)
+		}
+	}
+
+	t.Run("Load module beyond limit", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{
+			Name:     "third",
+			PoolSize: 1,
+			Filepath: "../testdata/hello-go/hello.wasm",
+		})
+		if !errors.Is(err, ErrModuleLimitReached) {
+			t.Errorf("Expected module limit error, got: %s", err)
+		}
+	})
+
+	t.Run("Reload module at limit", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{
+			Name:     "first",
+			PoolSize: 1,
+			Filepath: "../testdata/hello-go/hello.wasm",
+		})
+		if err != nil {
+			t.Errorf("Unexpected error reloading module at limit - %s", err)
+		}
+	})
+}
+
+func TestWASMEnsureAndInvoke(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	cfg := ModuleConfig{
+		Name:     "on-demand",
+		PoolSize: 5,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	}
+
+	t.Run("Concurrent first calls", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := s.EnsureAndInvoke(context.Background(), cfg, "example", []byte("hello"))
+				if err != nil {
+					t.Errorf("Unexpected error invoking module - %s", err)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	m, err := s.Module("on-demand")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Subsequent call uses loaded module", func(t *testing.T) {
+		_, err := s.EnsureAndInvoke(context.Background(), cfg, "example", []byte("hello"))
+		if err != nil {
+			t.Fatalf("Unexpected error invoking module - %s", err)
+		}
+
+		m2, err := s.Module("on-demand")
+		if err != nil {
+			t.Fatalf("Cannot find module - %s", err)
+		}
+		if m != m2 {
+			t.Errorf("Module was reloaded on subsequent call")
+		}
+	})
+
+	t.Run("Invalid module config", func(t *testing.T) {
+		_, err := s.EnsureAndInvoke(context.Background(), ModuleConfig{Name: "bad"}, "example", []byte("hello"))
+		if !errors.Is(err, ErrInvalidModuleConfig) {
+			t.Errorf("Expected invalid module config error, got: %s", err)
+		}
+	})
+}
+
+func TestWASMInvokeAll(t *testing.T) {
+	callbackCh := make(chan struct{}, 10)
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) {
+			callbackCh <- struct{}{}
+			return []byte(""), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 3,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Invoke all instances", func(t *testing.T) {
+		errs := m.InvokeAll("example", []byte("hello"))
+		if len(errs) != 3 {
+			t.Fatalf("Unexpected number of results: %d, expected: 3", len(errs))
+		}
+		for _, err := range errs {
+			if err != nil {
+				t.Errorf("Unexpected error invoking instance - %s", err)
+			}
+		}
+		if len(callbackCh) != 3 {
+			t.Errorf("Unexpected number of callbacks: %d, expected: 3", len(callbackCh))
+		}
+	})
+
+	t.Run("Invoke all instances with unknown function", func(t *testing.T) {
+		for _, err := range m.InvokeAll("ThisBetterFail", []byte("hello")) {
+			if !errors.Is(err, ErrFunctionNotFound) {
+				t.Errorf("Expected function not found error, got: %s", err)
+			}
+		}
+	})
+
+	t.Run("Run after invoke all", func(t *testing.T) {
+		_, err := m.Run("example", []byte("hello"))
+		if err != nil {
+			t.Errorf("Unexpected error running module after invoke all - %s", err)
+		}
+	})
+}
+
+func TestWASMFunctionNotFound(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) {
+			return []byte(""), ErrTestCallback
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Unknown function", func(t *testing.T) {
+		_, err := m.Run("ThisBetterFail", []byte("hello"))
+		if !errors.Is(err, ErrFunctionNotFound) {
+			t.Errorf("Expected function not found error, got: %s", err)
+		}
+	})
+
+	t.Run("Failing function", func(t *testing.T) {
+		_, err := m.Run("example", []byte("hello"))
+		if err == nil {
+			t.Fatalf("Expected error from failing function")
+		}
+		if errors.Is(err, ErrFunctionNotFound) {
+			t.Errorf("Unexpected function not found error for failing function: %s", err)
+		}
+	})
+}
+
+func TestWASMFunctionFilter(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+		FunctionFilter: func(name string) (string, error) {
+			switch name {
+			case "greet":
+				return "example", nil
+			case "blocked":
+				return "", ErrTestCallback
+			}
+			return name, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Rewritten function", func(t *testing.T) {
+		rsp, err := m.Run("greet", []byte("hello"))
+		if err != nil {
+			t.Fatalf("Unexpected error running module - %s", err)
+		}
+		if string(rsp) != "Hello World!" {
+			t.Errorf("Unexpected response: %s", rsp)
+		}
+	})
+
+	t.Run("Allowed function", func(t *testing.T) {
+		if _, err := m.Run("example", []byte("hello")); err != nil {
+			t.Errorf("Unexpected error running module - %s", err)
+		}
+	})
+
+	t.Run("Rejected function", func(t *testing.T) {
+		_, err := m.Run("blocked", []byte("hello"))
+		if !errors.Is(err, ErrFunctionNotAllowed) || !errors.Is(err, ErrTestCallback) {
+			t.Errorf("Expected function not allowed error, got: %s", err)
+		}
+	})
+
+	t.Run("Rejected function on all instances", func(t *testing.T) {
+		for _, err := range m.InvokeAll("blocked", []byte("hello")) {
+			if !errors.Is(err, ErrFunctionNotAllowed) {
+				t.Errorf("Expected function not allowed error, got: %s", err)
+			}
+		}
+	})
+}
+
+func TestWASMModuleSource(t *testing.T) {
+	guest, err := os.ReadFile("../testdata/hello-go/hello.wasm")
+	if err != nil {
+		t.Fatalf("Failed to read wasm file - %s", err)
+	}
+
+	s, err := New(ServerConfig{
+		Callback:       func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+		MaxModuleBytes: 1 << 20,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	t.Run("Filepath and Source", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{
+			Name:     "AModule",
+			Filepath: "../testdata/hello-go/hello.wasm",
+			Source:   guest,
+		})
+		if !errors.Is(err, ErrInvalidModuleConfig) {
+			t.Errorf("Expected invalid module config error, got: %s", err)
+		}
+	})
+
+	t.Run("Neither Filepath nor Source", func(t *testing.T) {
+		if err := s.LoadModule(ModuleConfig{Name: "AModule"}); !errors.Is(err, ErrInvalidModuleConfig) {
+			t.Errorf("Expected invalid module config error, got: %s", err)
+		}
+	})
+
+	t.Run("Source too large", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{Name: "AModule", Source: make([]byte, 2<<20)})
+		if !errors.Is(err, ErrModuleTooLarge) {
+			t.Errorf("Expected module too large error, got: %s", err)
+		}
+	})
+
+	t.Run("Invalid Source", func(t *testing.T) {
+		if err := s.LoadModule(ModuleConfig{Name: "AModule", Source: []byte("not wasm")}); err == nil {
+			t.Errorf("Expected error loading invalid source")
+		}
+	})
+
+	t.Run("Source", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{Name: "AModule", PoolSize: 1, Source: guest})
+		if err != nil {
+			t.Fatalf("Unexpected error loading module - %s", err)
+		}
+
+		m, err := s.Module("AModule")
+		if err != nil {
+			t.Fatalf("Cannot find module - %s", err)
+		}
+		rsp, err := m.Run("example", []byte("hello"))
+		if err != nil || string(rsp) != "Hello World!" {
+			t.Errorf("Unexpected result running module: %s, %v", rsp, err)
+		}
+	})
+}
+
+func TestWASMLoadModuleFromReader(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback:       func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+		MaxModuleBytes: 1 << 20,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	t.Run("Filepath provided", func(t *testing.T) {
+		err := s.LoadModuleFromReader("AModule", bytes.NewReader(nil), ModuleConfig{
+			Filepath: "../testdata/hello-go/hello.wasm",
+		})
+		if !errors.Is(err, ErrInvalidModuleConfig) {
+			t.Errorf("Expected invalid module config error, got: %s", err)
+		}
+	})
+
+	t.Run("Reader too large", func(t *testing.T) {
+		err := s.LoadModuleFromReader("AModule", bytes.NewReader(make([]byte, 2<<20)), ModuleConfig{})
+		if !errors.Is(err, ErrModuleTooLarge) {
+			t.Errorf("Expected module too large error, got: %s", err)
+		}
+	})
+
+	t.Run("Reader", func(t *testing.T) {
+		f, err := os.Open("../testdata/hello-go/hello.wasm")
+		if err != nil {
+			t.Fatalf("Failed to open wasm file - %s", err)
+		}
+		defer f.Close()
+
+		if err := s.LoadModuleFromReader("AModule", f, ModuleConfig{PoolSize: 1}); err != nil {
+			t.Fatalf("Unexpected error loading module - %s", err)
+		}
+
+		m, err := s.Module("AModule")
+		if err != nil {
+			t.Fatalf("Cannot find module - %s", err)
+		}
+		rsp, err := m.Run("example", []byte("hello"))
+		if err != nil || string(rsp) != "Hello World!" {
+			t.Errorf("Unexpected result running module: %s, %v", rsp, err)
+		}
+	})
+}
+
+func TestWASMMaxModuleBytes(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback:       func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+		MaxModuleBytes: 16,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	t.Run("Module too large", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{
+			Name:     "AModule",
+			Filepath: "../testdata/hello-go/hello.wasm",
+		})
+		if !errors.Is(err, ErrModuleTooLarge) {
+			t.Errorf("Expected module too large error, got: %s", err)
+		}
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{
+			Name:     "AModule",
+			Filepath: "/doesntexist/hello.wasm",
+		})
+		if err == nil || errors.Is(err, ErrModuleTooLarge) {
+			t.Errorf("Expected file read error, got: %s", err)
+		}
+	})
+
+	t.Run("Module within limit", func(t *testing.T) {
+		s.maxModuleBytes = 1 << 20
+		err := s.LoadModule(ModuleConfig{
+			Name:     "AModule",
+			PoolSize: 1,
+			Filepath: "../testdata/hello-go/hello.wasm",
+		})
+		if err != nil {
+			t.Errorf("Unexpected error loading module - %s", err)
+		}
+	})
+}
+
+func TestWASMCompileTimeout(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	t.Run("Negative timeout", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{
+			Name:           "AModule",
+			Filepath:       "../testdata/hello-go/hello.wasm",
+			CompileTimeout: -time.Second,
+		})
+		if !errors.Is(err, ErrInvalidModuleConfig) {
+			t.Errorf("Expected invalid module config error, got: %s", err)
+		}
+	})
+
+	t.Run("Timeout exceeded", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{
+			Name:           "AModule",
+			Filepath:       "../testdata/hello-go/hello.wasm",
+			CompileTimeout: time.Nanosecond,
+		})
+		if !errors.Is(err, ErrCompileTimeout) {
+			t.Errorf("Expected compile timeout error, got: %s", err)
+		}
+		if _, err := s.Module("AModule"); !errors.Is(err, ErrModuleNotFound) {
+			t.Errorf("Expected module not to be loaded, got: %s", err)
+		}
+	})
+
+	t.Run("Within timeout", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{
+			Name:           "AModule",
+			PoolSize:       1,
+			Filepath:       "../testdata/hello-go/hello.wasm",
+			CompileTimeout: time.Minute,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error loading module - %s", err)
+		}
+	})
+}
+
+func TestWASMOnClose(t *testing.T) {
+	var calls int
+	var moduleClosed bool
+	var s *Server
+	events := make(chan ServerEvent, 10)
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+		Events:   events,
+		OnClose: func() {
+			calls++
+			m, err := s.Module("AModule")
+			if err != nil {
+				t.Errorf("Cannot find module - %s", err)
+				return
+			}
+			moduleClosed = m.ctx.Err() != nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	<-events
+
+	s.Close()
+	s.Close()
+
+	if calls != 1 {
+		t.Errorf("Unexpected OnClose calls: %d, expected: 1", calls)
+	}
+	if len(events) != 1 {
+		t.Errorf("Unexpected events after closing twice: %d, expected: 1", len(events))
+	}
+	if !moduleClosed {
+		t.Errorf("Expected modules to be torn down before OnClose")
+	}
+}
+
+func TestWASMInstantiationFailure(t *testing.T) {
+	events := make(chan ServerEvent, 10)
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+		Events:   events,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+	<-events
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	// Take the only instance, then prevent it from being replaced once it is aborted
+	i, err := m.acquire(m.ctx, m.pool, "example")
+	if err != nil {
+		t.Fatalf("Unexpected error fetching instance - %s", err)
+	}
+	_ = m.module.Close(m.ctx)
+	m.release(m.pool, i, true)
+
+	t.Run("Replacement failure", func(t *testing.T) {
+		ev := <-events
+		if ev.Type != EventInstantiationFailed || !errors.Is(ev.Err, ErrInstantiationFailed) {
+			t.Errorf("Unexpected event: %+v", ev)
+		}
+
+		snap := m.snapshot()
+		if snap.InstantiationFailures != 1 || snap.PoolSize != 0 {
+			t.Errorf("Unexpected snapshot: %+v", snap)
+		}
+	})
+
+	t.Run("Empty pool", func(t *testing.T) {
+		_, err := m.Run("example", []byte("hello"))
+		if !errors.Is(err, ErrInstantiationFailed) || errors.Is(err, ErrPoolTimeout) {
+			t.Errorf("Expected instantiation failed error, got: %s", err)
+		}
+
+		ev := <-events
+		if ev.Type != EventInstantiationFailed || ev.Function != "example" {
+			t.Errorf("Unexpected event: %+v", ev)
+		}
+	})
+}
+
+func TestWASMUnloadModule(t *testing.T) {
+	events := make(chan ServerEvent, 10)
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+		Events:   events,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	for _, name := range []string{"AModule", "BModule"} {
+		err = s.LoadModule(ModuleConfig{Name: name, PoolSize: 1, Filepath: "../testdata/hello-go/hello.wasm"})
+		if err != nil {
+			t.Fatalf("Failed to load module - %s", err)
+		}
+		<-events
+		if err := s.AddToGroup("AGroup", name, 1); err != nil {
+			t.Fatalf("Failed to add module to group - %s", err)
+		}
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Not loaded", func(t *testing.T) {
+		if err := s.UnloadModule("NotLoaded"); !errors.Is(err, ErrModuleNotFound) {
+			t.Errorf("Expected module not found error, got: %s", err)
+		}
+	})
+
+	t.Run("Unloaded", func(t *testing.T) {
+		if err := s.UnloadModule("AModule"); err != nil {
+			t.Fatalf("Unexpected error unloading module - %s", err)
+		}
+
+		ev := <-events
+		if ev.Type != EventModuleUnloaded || ev.Module != "AModule" {
+			t.Errorf("Unexpected event: %+v", ev)
+		}
+		if _, err := s.Module("AModule"); !errors.Is(err, ErrModuleNotFound) {
+			t.Errorf("Expected module not found error, got: %s", err)
+		}
+		if _, err := m.Run("example", []byte("hello")); err == nil {
+			t.Errorf("Expected error running unloaded module")
+		}
+		if err := s.UnloadModule("AModule"); !errors.Is(err, ErrModuleNotFound) {
+			t.Errorf("Expected module not found error, got: %s", err)
+		}
+	})
+
+	t.Run("Removed from groups", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			if _, err := s.InvokeGroup(context.Background(), "AGroup", "example", []byte("hello")); err != nil {
+				t.Errorf("Unexpected error invoking group - %s", err)
+			}
+		}
+	})
+
+	t.Run("Reload", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{Name: "AModule", PoolSize: 1, Filepath: "../testdata/hello-go/hello.wasm"})
+		if err != nil {
+			t.Fatalf("Failed to load module - %s", err)
+		}
+		m, err := s.Module("AModule")
+		if err != nil {
+			t.Fatalf("Cannot find module - %s", err)
+		}
+		if _, err := m.Run("example", []byte("hello")); err != nil {
+			t.Errorf("Unexpected error running reloaded module - %s", err)
+		}
+	})
+}
+
+func TestWASMModules(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	t.Run("Empty", func(t *testing.T) {
+		names := s.Modules()
+		if names == nil || len(names) != 0 {
+			t.Errorf("Unexpected modules: %v, expected empty slice", names)
+		}
+	})
+
+	for _, name := range []string{"BModule", "AModule"} {
+		err = s.LoadModule(ModuleConfig{Name: name, PoolSize: 1, Filepath: "../testdata/hello-go/hello.wasm"})
+		if err != nil {
+			t.Fatalf("Failed to load module - %s", err)
+		}
+	}
+
+	t.Run("Sorted", func(t *testing.T) {
+		names := s.Modules()
+		if len(names) != 2 || names[0] != "AModule" || names[1] != "BModule" {
+			t.Errorf("Unexpected modules: %v", names)
+		}
+	})
+
+	t.Run("Unloaded", func(t *testing.T) {
+		if err := s.UnloadModule("AModule"); err != nil {
+			t.Fatalf("Unexpected error unloading module - %s", err)
+		}
+		names := s.Modules()
+		if len(names) != 1 || names[0] != "BModule" {
+			t.Errorf("Unexpected modules: %v", names)
+		}
+	})
+}
+
+func TestWASMEnvArgs(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	t.Run("Invalid config", func(t *testing.T) {
+		cfgs := []ModuleConfig{
+			{Name: "Invalid", Filepath: "../testdata/hello-go/hello.wasm", Env: map[string]string{"": "value"}},
+			{Name: "Invalid", Filepath: "../testdata/hello-go/hello.wasm", Env: map[string]string{"A=B": "value"}},
+			{Name: "Invalid", Filepath: "../testdata/hello-go/hello.wasm", Env: map[string]string{"KEY": "a\x00b"}},
+			{Name: "Invalid", Filepath: "../testdata/hello-go/hello.wasm", Args: []string{"a\x00b"}},
+		}
+		for _, cfg := range cfgs {
+			if err := s.LoadModule(cfg); !errors.Is(err, ErrInvalidModuleConfig) {
+				t.Errorf("Expected invalid module config error, got: %s", err)
+			}
+		}
+	})
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+		Env:      map[string]string{"GREETING": "hello", "TARGET": "world"},
+		Args:     []string{"hello", "--verbose"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	m, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Env", func(t *testing.T) {
+		r, err := m.Run("env", []byte(""))
+		if err != nil {
+			t.Fatalf("Unexpected error running module - %s", err)
+		}
+		if string(r) != "GREETING=hello\x00TARGET=world" {
+			t.Errorf("Unexpected environment: %q", r)
+		}
+	})
+
+	t.Run("Args", func(t *testing.T) {
+		r, err := m.Run("args", []byte(""))
+		if err != nil {
+			t.Fatalf("Unexpected error running module - %s", err)
+		}
+		if string(r) != "hello\x00--verbose" {
+			t.Errorf("Unexpected args: %q", r)
+		}
+	})
+}
+
+type WASMLoggerTestCase struct {
+	Name      string
+	LogPrefix bool
+	Expected  string
+}
+
+func TestWASMLogger(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	tt := []WASMLoggerTestCase{
+		{Name: "Logger", Expected: "hello"},
+		{Name: "Prefixed", LogPrefix: true, Expected: "[Prefixed] hello"},
+	}
+
+	for _, c := range tt {
+		t.Run(c.Name, func(t *testing.T) {
+			var messages []string
+			err := s.LoadModule(ModuleConfig{
+				Name:      c.Name,
+				PoolSize:  1,
+				Filepath:  "../testdata/hello-go/hello.wasm",
+				LogPrefix: c.LogPrefix,
+				Logger: func(msg string) {
+					messages = append(messages, msg)
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to load module - %s", err)
+			}
+
+			m, err := s.Module(c.Name)
+			if err != nil {
+				t.Fatalf("Cannot find module - %s", err)
+			}
+
+			if _, err := m.Run("log", []byte("hello")); err != nil {
+				t.Fatalf("Unexpected error running module - %s", err)
+			}
+
+			if len(messages) != 1 || messages[0] != c.Expected {
+				t.Errorf("Unexpected log messages: %q", messages)
+			}
+		})
+	}
+}
+
+func TestWASMReloadModule(t *testing.T) {
+	events := make(chan ServerEvent, 10)
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+		Events:   events,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{
+		Name:     "AModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+		Env:      map[string]string{"VERSION": "v1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+	<-events
+
+	old, err := s.Module("AModule")
+	if err != nil {
+		t.Fatalf("Cannot find module - %s", err)
+	}
+
+	t.Run("Not loaded", func(t *testing.T) {
+		cfg := ModuleConfig{Filepath: "../testdata/hello-go/hello.wasm"}
+		if err := s.ReloadModule("NotLoaded", cfg); !errors.Is(err, ErrModuleNotFound) {
+			t.Errorf("Expected module not found error, got: %s", err)
+		}
+	})
+
+	t.Run("Invalid module", func(t *testing.T) {
+		err := s.ReloadModule("AModule", ModuleConfig{Filepath: "/doesntexist/notreal.wasm"})
+		if err == nil {
+			t.Fatalf("Expected error reloading module with invalid file")
+		}
+		if m, _ := s.Module("AModule"); m != old {
+			t.Errorf("Expected old module to remain loaded")
+		}
+	})
+
+	t.Run("Reloaded", func(t *testing.T) {
+		// Hold an instance of the old module to simulate an in-flight invocation
+		session, err := old.Session()
+		if err != nil {
+			t.Fatalf("Unexpected error creating session - %s", err)
+		}
+
+		// Queue an invocation waiting for the instance held by the session
+		waiting := make(chan error, 1)
+		go func() {
+			_, err := old.Run("example", []byte("hello"))
+			waiting <- err
+		}()
+		for old.active.Load() < 2 {
+			time.Sleep(time.Millisecond)
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ReloadModule("AModule", ModuleConfig{
+				PoolSize: 1,
+				Filepath: "../testdata/hello-go/hello.wasm",
+				Env:      map[string]string{"VERSION": "v2"},
+			})
+		}()
+
+		// Wait for the new module to be swapped in
+		var m *Module
+		for m == nil || m == old {
+			m, _ = s.Module("AModule")
+			time.Sleep(time.Millisecond)
+		}
+
+		r, err := m.Run("env", []byte(""))
+		if err != nil || string(r) != "VERSION=v2" {
+			t.Errorf("Unexpected response from new module: %q - %v", r, err)
+		}
+
+		select {
+		case err := <-done:
+			t.Fatalf("ReloadModule returned before the old module drained - %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		// The in-flight invocation completes on the old module
+		if _, err := session.Call("example", []byte("hello")); err != nil {
+			t.Errorf("Unexpected error calling old module - %s", err)
+		}
+		session.Close()
+
+		// The waiting invocation is served by the old module before it is closed
+		if err := <-waiting; err != nil {
+			t.Errorf("Unexpected error from waiting invocation on old module - %s", err)
+		}
+
+		if err := <-done; err != nil {
+			t.Fatalf("Unexpected error reloading module - %s", err)
+		}
+
+		ev := <-events
+		if ev.Type != EventModuleReloaded || ev.Module != "AModule" {
+			t.Errorf("Unexpected event: %+v", ev)
+		}
+		if _, err := old.Run("example", []byte("hello")); err == nil {
+			t.Errorf("Expected error running old module")
+		}
+	})
+
+	t.Run("Drain timeout", func(t *testing.T) {
+		m, err := s.Module("AModule")
+		if err != nil {
+			t.Fatalf("Cannot find module - %s", err)
+		}
+
+		// A session that is never closed does not block draining forever
+		session, err := m.Session()
+		if err != nil {
+			t.Fatalf("Unexpected error creating session - %s", err)
+		}
+		defer session.Close()
+
+		if m.drain(20 * time.Millisecond) {
+			t.Errorf("Expected drain to time out while a session is held")
+		}
+	})
+}
+
+func TestWASMModuleChecksum(t *testing.T) {
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	guest, err := os.ReadFile("../testdata/hello-go/hello.wasm")
+	if err != nil {
+		t.Fatalf("Unable to read wasm module - %s", err)
+	}
+	sum := sha256.Sum256(guest)
+	checksum := hex.EncodeToString(sum[:])
+
+	t.Run("Match", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{
+			Name:     "AModule",
+			PoolSize: 1,
+			Filepath: "../testdata/hello-go/hello.wasm",
+			SHA256:   strings.ToUpper(checksum),
+		})
+		if err != nil {
+			t.Errorf("Unexpected error loading module - %s", err)
+		}
+	})
+
+	t.Run("Source match", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{Name: "BModule", PoolSize: 1, Source: guest, SHA256: checksum})
+		if err != nil {
+			t.Errorf("Unexpected error loading module - %s", err)
+		}
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		tampered := append([]byte{}, guest...)
+		tampered = append(tampered, 0)
+		err := s.LoadModule(ModuleConfig{Name: "CModule", PoolSize: 1, Source: tampered, SHA256: checksum})
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("Expected checksum mismatch error, got: %s", err)
+		}
+		if _, err := s.Module("CModule"); !errors.Is(err, ErrModuleNotFound) {
+			t.Errorf("Expected module not found error, got: %s", err)
+		}
+	})
+
+	t.Run("Invalid checksum", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{Name: "DModule", PoolSize: 1, Source: guest, SHA256: "abc"})
+		if !errors.Is(err, ErrInvalidModuleConfig) {
+			t.Errorf("Expected invalid module config error, got: %s", err)
+		}
+	})
+}
+
+func TestWASMEngine(t *testing.T) {
+	callback := func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil }
+
+	t.Run("Invalid config", func(t *testing.T) {
+		_, err := New(ServerConfig{
+			Callback:      callback,
+			Engine:        wazero.Engine(),
+			HostFunctions: map[string]HostFunction{"add": {}},
+		})
+		if !errors.Is(err, ErrInvalidServerConfig) {
+			t.Errorf("Expected invalid server config error, got: %s", err)
+		}
+	})
+
+	s, err := New(ServerConfig{Callback: callback, Engine: wazero.Engine()})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	t.Run("Unsupported module config", func(t *testing.T) {
+		cfgs := []ModuleConfig{
+			{Name: "AModule", Filepath: "../testdata/hello-go/hello.wasm", ReturnStdout: true},
+			{Name: "AModule", Filepath: "../testdata/hello-go/hello.wasm", Env: map[string]string{"KEY": "value"}},
+			{Name: "AModule", Filepath: "../testdata/hello-go/hello.wasm", Args: []string{"hello"}},
+		}
+		for _, cfg := range cfgs {
+			if err := s.LoadModule(cfg); !errors.Is(err, ErrInvalidModuleConfig) {
+				t.Errorf("Expected invalid module config error, got: %s", err)
+			}
+		}
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		err := s.LoadModule(ModuleConfig{Name: "AModule", PoolSize: 1, Filepath: "../testdata/hello-go/hello.wasm"})
+		if err != nil {
+			t.Fatalf("Failed to load module - %s", err)
+		}
+
+		m, err := s.Module("AModule")
+		if err != nil {
+			t.Fatalf("Cannot find module - %s", err)
+		}
+
+		r, err := m.Run("example", []byte("hello"))
+		if err != nil || string(r) != "Hello World!" {
+			t.Errorf("Unexpected response: %q - %v", r, err)
+		}
+	})
+}
+
+// failingEngine wraps an engine, returning modules whose instances fail to instantiate.
+type failingEngine struct {
+	wapc.Engine
+
+	// closed is set once a module created by the engine is closed.
+	closed atomic.Bool
+}
+
+func (e *failingEngine) New(
+	ctx context.Context,
+	host wapc.HostCallHandler,
+	guest []byte,
+	config *wapc.ModuleConfig,
+) (wapc.Module, error) {
+	m, err := e.Engine.New(ctx, host, guest, config)
+	if err != nil {
+		return nil, err
+	}
+	return &failingModule{Module: m, engine: e}, nil
+}
+
+// failingModule is a module whose instances fail to instantiate.
+type failingModule struct {
+	wapc.Module
+	engine *failingEngine
+}
+
+func (m *failingModule) Instantiate(context.Context) (wapc.Instance, error) {
+	return nil, ErrTestCallback
+}
+
+func (m *failingModule) Close(ctx context.Context) error {
+	m.engine.closed.Store(true)
+	return m.Module.Close(ctx)
+}
+
+func TestWASMModuleCleanup(t *testing.T) {
+	engine := &failingEngine{Engine: wazero.Engine()}
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) { return []byte(""), nil },
+		Engine:   engine,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{Name: "AModule", PoolSize: 1, Filepath: "../testdata/hello-go/hello.wasm"})
+	if !errors.Is(err, ErrInstantiationFailed) {
+		t.Fatalf("Expected instantiation failed error, got: %s", err)
+	}
+	if !engine.closed.Load() {
+		t.Errorf("Expected compiled module to be closed after pool creation failed")
+	}
+}
+
+func TestWASMModuleCallback(t *testing.T) {
+	var serverCalls, moduleCalls atomic.Int64
+	s, err := New(ServerConfig{
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) {
+			serverCalls.Add(1)
+			return []byte(""), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create WASM Server - %s", err)
+	}
+	defer s.Close()
+
+	err = s.LoadModule(ModuleConfig{Name: "AModule", PoolSize: 1, Filepath: "../testdata/hello-go/hello.wasm"})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+	err = s.LoadModule(ModuleConfig{
+		Name:     "BModule",
+		PoolSize: 1,
+		Filepath: "../testdata/hello-go/hello.wasm",
+		Callback: func(context.Context, string, string, string, []byte) ([]byte, error) {
+			moduleCalls.Add(1)
+			return []byte(""), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to load module - %s", err)
+	}
+
+	for _, name := range []string{"AModule", "BModule", "BModule"} {
+		m, err := s.Module(name)
+		if err != nil {
+			t.Fatalf("Cannot find module - %s", err)
+		}
+		if _, err := m.Run("example", []byte("hello")); err != nil {
+			t.Fatalf("Unexpected error running module - %s", err)
+		}
+	}
+
+	if n := serverCalls.Load(); n != 1 {
+		t.Errorf("Unexpected number of server callback calls: %d", n)
+	}
+	if n := moduleCalls.Load(); n != 2 {
+		t.Errorf("Unexpected number of module callback calls: %d", n)
+	}
+}
@@ -5,6 +5,8 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	wapc "github.com/wapc/wapc-guest-tinygo"
 )
@@ -14,6 +16,10 @@ func main() {
 	// multiple functions can be registered at once.
 	wapc.RegisterFunctions(wapc.Functions{
 		"example": Example,
+		"stdout":  Stdout,
+		"env":     Env,
+		"args":    Args,
+		"log":     Log,
 	})
 }
 
@@ -26,3 +32,25 @@ func Example(payload []byte) ([]byte, error) {
 	}
 	return []byte("Hello World!"), nil
 }
+
+// Stdout writes the payload to standard output, adhering to the wapc signature.
+func Stdout(payload []byte) ([]byte, error) {
+	fmt.Print(string(payload))
+	return []byte("Hello World!"), nil
+}
+
+// Env returns the guest's environment variables, separated by NUL characters.
+func Env(payload []byte) ([]byte, error) {
+	return []byte(strings.Join(os.Environ(), "\x00")), nil
+}
+
+// Args returns the guest's command-line arguments, separated by NUL characters.
+func Args(payload []byte) ([]byte, error) {
+	return []byte(strings.Join(os.Args, "\x00")), nil
+}
+
+// Log writes the payload to the host logger, adhering to the wapc signature.
+func Log(payload []byte) ([]byte, error) {
+	wapc.ConsoleLog(string(payload))
+	return []byte("Hello World!"), nil
+}